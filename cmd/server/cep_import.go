@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"showcase-datastar-go/internal/services"
+)
+
+// runCEPImport implements the "cep-import" subcommand, compiling a CSV of
+// CEP ranges and streets into the binary database loaded through CEP_DB:
+//
+//	showcase cep-import [-o data/ceps.db] [-check] <file.csv|->
+func runCEPImport(args []string) error {
+	fs := flag.NewFlagSet("cep-import", flag.ContinueOnError)
+	output := fs.String("o", cepDatabaseFile(), "compiled database to write")
+	check := fs.Bool("check", false, "validate the CSV without writing the database")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: cep-import [flags] <file.csv|->")
+	}
+
+	var r io.Reader = os.Stdin
+	if input := fs.Arg(0); input != "-" {
+		file, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	db, err := services.CompileCEPDatabase(r)
+	if err != nil {
+		return err
+	}
+
+	stats := db.Stats()
+	fmt.Printf("%d state ranges, %d city ranges, %d streets (%d distinct names)\n",
+		stats.States, stats.Cities, stats.Streets, stats.Names)
+
+	if *check {
+		fmt.Println("check only: database not written")
+		return nil
+	}
+
+	if err := services.WriteCEPDatabaseFile(*output, db); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(*output)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ database written to %s (%d bytes)\n", *output, info.Size())
+	return nil
+}
+
+func cepDatabaseFile() string {
+	if path := os.Getenv("CEP_DB"); path != "" {
+		return path
+	}
+	return defaultCEPDatabase
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"showcase-datastar-go/internal/auth"
+)
+
+// runHashPassword implements the "hash-password" subcommand, which prints
+// the argon2id hash of a password read from stdin, for ADMIN_USERS_FILE:
+//
+//	echo -n 'senha' | showcase hash-password
+func runHashPassword(args []string) error {
+	fs := flag.NewFlagSet("hash-password", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// The password comes from stdin so it stays out of the shell history
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return errors.New("usage: echo -n <password> | hash-password")
+	}
+
+	fmt.Println(auth.HashPassword(password))
+	return nil
+}
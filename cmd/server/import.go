@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"showcase-datastar-go/internal/services"
+)
+
+// runImport implements the "import" subcommand:
+//
+//	showcase import [-catalog data/catalog.json] [-format csv] [-dry-run] [-merge] <file|->
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	catalogPath := fs.String("catalog", catalogFile(), "catalog JSON file to update")
+	formatName := fs.String("format", "", "input format: csv, json or ndjson (default: from extension)")
+	dryRun := fs.Bool("dry-run", false, "validate only and print the per-row report")
+	merge := fs.Bool("merge", false, "upsert into the current catalog instead of replacing it")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: import [flags] <file|->")
+	}
+
+	input := fs.Arg(0)
+
+	format, err := importFormat(input, *formatName)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if input != "-" {
+		file, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	searchService := services.NewSearchService()
+	if err := searchService.LoadCatalogFile(*catalogPath); err != nil {
+		return err
+	}
+
+	report, err := searchService.ImportCatalog(r, services.ImportOptions{
+		Format: format,
+		DryRun: *dryRun,
+		Merge:  *merge,
+	})
+	if report != nil {
+		printImportReport(os.Stdout, report, *catalogPath)
+	}
+	return err
+}
+
+func importFormat(input, name string) (services.ImportFormat, error) {
+	if name != "" {
+		return services.ParseImportFormat(name)
+	}
+	if input == "-" {
+		return "", errors.New("-format is required when reading from stdin")
+	}
+	return services.DetectImportFormat(input, "")
+}
+
+func printImportReport(w io.Writer, report *services.ImportReport, catalogPath string) {
+	for _, row := range report.Rows {
+		status := "ok"
+		if !row.Valid {
+			status = "ERROR"
+		}
+		fmt.Fprintf(w, "row %-5d %-24s %s", row.Row, row.ID, status)
+		if len(row.Errors) > 0 {
+			fmt.Fprintf(w, ": %s", strings.Join(row.Errors, "; "))
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "\n%d rows, %d valid, %d invalid (format %s)\n",
+		report.TotalRows, report.ValidRows, report.InvalidRows, report.Format)
+
+	switch {
+	case report.DryRun:
+		fmt.Fprintf(w, "dry run: catalog would have %d items\n", report.CatalogSize)
+	case report.Applied:
+		fmt.Fprintf(w, "✅ catalog written to %s (%d items)\n", catalogPath, report.CatalogSize)
+	default:
+		fmt.Fprintln(w, "❌ import rejected, catalog left unchanged")
+	}
+}
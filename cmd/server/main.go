@@ -1,47 +1,252 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"showcase-datastar-go/internal/auth"
+	"showcase-datastar-go/internal/csrf"
 	"showcase-datastar-go/internal/handlers"
+	"showcase-datastar-go/internal/mailer"
 	"showcase-datastar-go/internal/services"
+	"showcase-datastar-go/internal/signing"
+	"showcase-datastar-go/internal/templates/pages"
 
 	"github.com/gin-gonic/gin"
 )
 
+// defaultCatalogFile is used by the import subcommand when CATALOG_FILE is unset
+const defaultCatalogFile = "data/catalog.json"
+
+// defaultPublicURL is where links in emails point when PUBLIC_URL is unset
+const defaultPublicURL = "http://localhost:8080"
+
+// Email defaults, overridable through MAIL_FROM, MAIL_OUTBOX and SMTP_PORT
+const (
+	defaultMailFrom   = "CEAR Showcase <nao-responda@localhost>"
+	defaultMailOutbox = "data/outbox"
+	defaultSMTPPort   = 587
+)
+
+// defaultAttachmentsDir keeps contact form attachments, see ATTACHMENTS_DIR
+const defaultAttachmentsDir = "data/attachments"
+
+// defaultAuditLog keeps the admin login trail, see ADMIN_AUDIT_LOG
+const defaultAuditLog = "data/admin-audit.log"
+
+// defaultCEPDatabase is where cep-import writes when CEP_DB is unset
+const defaultCEPDatabase = "data/ceps.db"
+
+// CEP lookup defaults, overridable through CEP_API_TIMEOUT and CEP_API_RETRIES
+const (
+	defaultCEPTimeout = 3 * time.Second
+	defaultCEPRetries = 2
+
+	cepCacheTTL         = 24 * time.Hour
+	cepNegativeCacheTTL = 5 * time.Minute
+	cepCacheEntries     = 10000
+)
+
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImport(os.Args[2:]); err != nil {
+			log.Fatal("Erro na importação: ", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "hash-password" {
+		if err := runHashPassword(os.Args[2:]); err != nil {
+			log.Fatal("Erro ao gerar hash: ", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "cep-import" {
+		if err := runCEPImport(os.Args[2:]); err != nil {
+			log.Fatal("Erro na importação de CEPs: ", err)
+		}
+		return
+	}
+
 	// Initialize services
 	searchService := services.NewSearchService()
+	if path := os.Getenv("CATALOG_FILE"); path != "" {
+		if err := searchService.LoadCatalogFile(path); err != nil {
+			log.Fatal("Erro ao carregar catálogo:", err)
+		}
+	}
+	if path := os.Getenv("INDEX_SNAPSHOT"); path != "" {
+		status, err := searchService.UseIndexSnapshot(path)
+		if err != nil {
+			log.Fatal("Erro no snapshot do índice:", err)
+		}
+		log.Printf("Índice de busca: snapshot %s (%s)", status, path)
+	}
+	searchHistoryService := services.NewSearchHistoryService(services.DefaultRecentSearches)
+	siteSearchService, err := services.NewSiteSearchService(sitePages(searchService))
+	if err != nil {
+		log.Fatal("Erro ao indexar páginas:", err)
+	}
 	dashboardService := services.NewDashboardService()
-	formsService := services.NewFormsService()
+	var cepDatabase *services.CEPDatabase
+	if path := os.Getenv("CEP_DB"); path != "" {
+		cepDatabase, err = services.LoadCEPDatabase(path)
+		if err != nil {
+			log.Fatal("Erro ao carregar base de CEPs:", err)
+		}
+	}
+	cepProvider, err := newCEPProvider(cepDatabase)
+	if err != nil {
+		log.Fatal("Erro ao configurar consulta de CEP:", err)
+	}
+	var cepLocator services.CEPLocator
+	if cepDatabase != nil {
+		cepLocator = cepDatabase
+	}
+	formsService := services.NewFormsService(cepProvider, cepLocator)
+	if err := configureFormsStore(formsService); err != nil {
+		log.Fatal("Erro ao abrir armazenamento dos formulários:", err)
+	}
+	if err := configureAttachments(formsService); err != nil {
+		log.Fatal("Erro ao configurar anexos:", err)
+	}
+	if err := configureEmailChecks(formsService); err != nil {
+		log.Fatal("Erro ao configurar validação de email:", err)
+	}
+	mailQueue, outbox, err := newMailer()
+	if err != nil {
+		log.Fatal("Erro ao configurar envio de emails:", err)
+	}
+	notifier := &services.MailNotifier{Mailer: mailQueue, From: envOr("MAIL_FROM", defaultMailFrom)}
+	formsService.UseContactMailer(notifier)
+	if err := configureNewsletter(formsService, notifier); err != nil {
+		log.Fatal("Erro ao configurar newsletter:", err)
+	}
+	formSigner, err := newFormSigner()
+	if err != nil {
+		log.Fatal("Erro ao configurar proteção dos formulários:", err)
+	}
+	spamGuard := services.NewSpamGuard(formSigner, services.SpamOptions{})
+	powGuard, err := newPowGuard(formSigner)
+	if err != nil {
+		log.Fatal("Erro ao configurar prova de trabalho:", err)
+	}
+	dashboardService.UseSpamGuard(spamGuard)
+	authManager, err := newAuthManager()
+	if err != nil {
+		log.Fatal("Erro ao configurar área administrativa:", err)
+	}
 
 	// Initialize handlers
 	homeHandler := handlers.NewHomeHandler()
-	searchHandler := handlers.NewSearchHandler(searchService)
+	searchHandler := handlers.NewSearchHandler(searchService, searchHistoryService, siteSearchService)
 	dashboardHandler := handlers.NewDashboardHandler(dashboardService)
-	formsHandler := handlers.NewFormsHandler(formsService)
+	formsHandler := handlers.NewFormsHandler(formsService, spamGuard, powGuard)
 	componentsHandler := handlers.NewComponentsHandler()
+	catalogHandler := handlers.NewCatalogHandler(searchService)
+	adminHandler := handlers.NewAdminHandler(authManager)
+	var inboxHandler *handlers.InboxHandler
+	if outbox != nil {
+		enabled, err := devInboxEnabled()
+		if err != nil {
+			log.Fatal("DEV_INBOX inválido:", err)
+		}
+		if enabled {
+			inboxHandler = handlers.NewInboxHandler(outbox)
+			log.Println("📬 Caixa de saída para admins em http://localhost:8080/admin/dev/inbox")
+		}
+	}
 
 	// Setup Gin
 	r := gin.Default()
+	// Client IPs feed the spam rate limits: only trust X-Forwarded-For from
+	// known proxies
+	if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatal("TRUSTED_PROXIES inválido:", err)
+	}
 
 	// Middleware
-	r.Use(setupCORS())
+	r.Use(setupCORS(corsOrigins()))
 	r.Use(setupHeaders())
+	// One-click unsubscribe comes from mail clients; its signed link is the proof
+	r.Use(csrf.Middleware(formSigner, "/newsletter/unsubscribe"))
 
 	// Static files
 	r.Static("/static", "./web/static")
 
 	// Routes
-	setupRoutes(r, searchHandler, dashboardHandler, formsHandler, homeHandler, componentsHandler)
+	setupRoutes(r, searchHandler, dashboardHandler, formsHandler, homeHandler, componentsHandler, catalogHandler, inboxHandler, adminHandler, authManager)
 
 	// Start server
 	log.Println("🚀 CEAR Showcase Go rodando em http://localhost:8080")
-	if err := r.Run(":8080"); err != nil {
+	if err := serve(":8080", r, mailQueue); err != nil {
 		log.Fatal("Erro ao iniciar servidor:", err)
 	}
 }
 
+// Shutdown limits: open requests get shutdownTimeout to finish (streams
+// are cut afterwards), then queued emails get mailDrainTimeout to go out
+const (
+	shutdownTimeout  = 10 * time.Second
+	mailDrainTimeout = 30 * time.Second
+)
+
+// serve runs the server until SIGINT or SIGTERM, then shuts it down and
+// drains the email queue, so restarts don't lose queued mail
+func serve(addr string, handler http.Handler, mailQueue *mailer.Queue) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: addr, Handler: handler}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+	log.Println("⏹️  Encerrando servidor...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Live streams (dashboard, progress) never finish on their own
+		srv.Close()
+	}
+
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), mailDrainTimeout)
+	defer cancelDrain()
+	if err := mailQueue.Close(drainCtx); err != nil {
+		log.Printf("📧 Emails ainda na fila ao encerrar: %v", err)
+	}
+	return nil
+}
+
+// sitePages lists the showcase pages covered by the site search, with the
+// same titles their layouts use
+func sitePages(searchService *services.SearchService) []services.SitePage {
+	return []services.SitePage{
+		{Path: "/search", Title: "Active Search", Component: pages.SearchContent(nil, searchService.Categories(), nil)},
+		{Path: "/dashboard", Title: "Dashboard Real-time", Component: pages.DashboardContent()},
+		{Path: "/forms", Title: "Forms Reativos", Component: pages.FormsContent(pages.FormGuard{})},
+		{Path: "/components", Title: "Components Gallery", Component: pages.ComponentsContent()},
+	}
+}
+
 func setupRoutes(
 	r *gin.Engine,
 	searchHandler *handlers.SearchHandler,
@@ -49,6 +254,10 @@ func setupRoutes(
 	formsHandler *handlers.FormsHandler,
 	homeHandler *handlers.HomeHandler,
 	componentsHandler *handlers.ComponentsHandler,
+	catalogHandler *handlers.CatalogHandler,
+	inboxHandler *handlers.InboxHandler,
+	adminHandler *handlers.AdminHandler,
+	authManager *auth.Manager,
 ) {
 	// Home route
 	r.GET("/", homeHandler.HomePage)
@@ -58,6 +267,11 @@ func setupRoutes(
 	r.GET("/search/results", searchHandler.SearchResults)
 	r.GET("/search/suggestions", searchHandler.GetSuggestions)
 	r.GET("/search/live", searchHandler.LiveSearch)
+	r.GET("/search/categories", searchHandler.GetCategories)
+	r.GET("/search/tags", searchHandler.GetTags)
+	r.GET("/search/tags/cloud", searchHandler.TagCloud)
+	r.POST("/search/click", searchHandler.RecordClick)
+	r.DELETE("/search/history", searchHandler.ClearHistory)
 
 	// Dashboard routes
 	r.GET("/dashboard", dashboardHandler.DashboardPage)
@@ -67,18 +281,61 @@ func setupRoutes(
 
 	// Forms routes
 	r.GET("/forms", formsHandler.FormsPage)
+	r.GET("/forms/pow-challenge", formsHandler.PowChallenge)
 	r.POST("/forms/validate-email", formsHandler.ValidateEmail)
 	r.POST("/forms/validate-field", formsHandler.ValidateField)
 	r.POST("/forms/validate-cpf", formsHandler.ValidateCPF)
+	r.POST("/forms/validate-cnpj", formsHandler.ValidateCNPJ)
 	r.POST("/forms/mask-phone", formsHandler.MaskPhone)
+	r.POST("/forms/validate-phone", formsHandler.ValidatePhone)
 	r.POST("/forms/validate-cep", formsHandler.ValidateCEP)
+	r.POST("/forms/validate-address-field", formsHandler.ValidateAddressField)
+	r.POST("/forms/address-submit", formsHandler.SubmitAddress)
 	r.POST("/forms/count-chars", formsHandler.CountChars)
 	r.POST("/forms/submit-newsletter", formsHandler.SubmitNewsletter)
 	r.POST("/forms/contact-submit", formsHandler.SubmitContact)
+	r.GET("/forms/contact-upload-progress", formsHandler.ContactUploadProgress)
+
+	// Newsletter double opt-in links
+	r.GET("/newsletter/confirm", formsHandler.ConfirmPage)
+	r.POST("/newsletter/confirm", formsHandler.ConfirmNewsletter)
+	r.GET("/newsletter/unsubscribe", formsHandler.UnsubscribePage)
+	r.POST("/newsletter/unsubscribe", formsHandler.UnsubscribeNewsletter)
+
+	// Admin routes: viewers read, editors change data, admins see the
+	// login trail
+	admin := r.Group("/admin", authManager.Middleware())
+	admin.GET("/login", adminHandler.LoginPage)
+	admin.POST("/login", adminHandler.Login)
+	admin.POST("/logout", adminHandler.Logout)
 
-	// Admin routes (optional - for testing)
-	r.GET("/admin/newsletter-subscribers", formsHandler.GetNewsletterSubscribers)
-	r.GET("/admin/contact-messages", formsHandler.GetContactMessages)
+	viewer := admin.Group("", auth.Require(auth.RoleViewer))
+	viewer.GET("", adminHandler.AdminPage)
+	viewer.GET("/me", adminHandler.Me)
+	viewer.GET("/newsletter-subscribers", formsHandler.GetNewsletterSubscribers)
+	viewer.GET("/newsletter-subscribers/table", formsHandler.SubscribersTable)
+	viewer.GET("/newsletter-subscribers/export", formsHandler.ExportSubscribers)
+	viewer.GET("/contact-messages", formsHandler.GetContactMessages)
+	viewer.GET("/contact-messages/table", formsHandler.ContactMessagesTable)
+	viewer.GET("/contact-messages/export", formsHandler.ExportContactMessages)
+	viewer.GET("/contact-messages/search", formsHandler.SearchContactMessages)
+	viewer.GET("/contact-messages/:id/attachments/:attachment", formsHandler.DownloadAttachment)
+
+	editor := admin.Group("", auth.Require(auth.RoleEditor))
+	editor.POST("/catalog/import", catalogHandler.ImportCatalog)
+	editor.POST("/contact-messages/read", formsHandler.MarkContactMessagesRead)
+	editor.POST("/contact-messages/delete", formsHandler.DeleteContactMessages)
+	editor.POST("/newsletter-subscribers/delete", formsHandler.DeleteSubscribers)
+
+	admins := admin.Group("", auth.Require(auth.RoleAdmin))
+	admins.GET("/audit", adminHandler.AuditTrail)
+
+	// Development inbox: the captured emails hold confirmation tokens, so
+	// it's opt-in (DEV_INBOX) and for admins only
+	if inboxHandler != nil {
+		admins.GET("/dev/inbox", inboxHandler.InboxPage)
+		admins.GET("/dev/inbox/:id", inboxHandler.MessagePage)
+	}
 
 	// Components routes
 	r.GET("/components", componentsHandler.ComponentsPage)
@@ -87,13 +344,25 @@ func setupRoutes(
 	r.GET("/components/tokens", componentsHandler.GetDesignTokens)
 }
 
-func setupCORS() gin.HandlerFunc {
+// setupCORS lets the listed origins call the API from the browser. Other
+// origins get no CORS headers, so the browser keeps them same-origin;
+// credentials are never allowed cross-origin.
+func setupCORS(origins []string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		allowed[origin] = true
+	}
+
 	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Vary", "Origin")
+		origin := c.GetHeader("Origin")
+		if allowed[origin] {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", "Content-Type, "+csrf.HeaderName)
+		}
 
-		if c.Request.Method == "OPTIONS" {
+		if c.Request.Method == "OPTIONS" && origin != "" {
 			c.AbortWithStatus(204)
 			return
 		}
@@ -102,6 +371,63 @@ func setupCORS() gin.HandlerFunc {
 	}
 }
 
+// newPowGuard sets the proof-of-work difficulty in zero bits: POW_DIFFICULTY
+// for a quiet network (-1 turns the work off) and POW_MAX_DIFFICULTY for
+// one sending many submissions
+func newPowGuard(signer *signing.Signer) (*services.PowGuard, error) {
+	var options services.PowOptions
+	if value := os.Getenv("POW_DIFFICULTY"); value != "" {
+		difficulty, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		options.Difficulty = difficulty
+	}
+	if value := os.Getenv("POW_MAX_DIFFICULTY"); value != "" {
+		difficulty, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		options.MaxDifficulty = difficulty
+	}
+	return services.NewPowGuard(signer, options), nil
+}
+
+// trustedProxies reads the comma-separated TRUSTED_PROXIES (IPs or CIDRs);
+// none by default, so the client IP is the peer address
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// corsOrigins reads the comma-separated CORS_ORIGINS, such as
+// https://app.exemplo.com,https://admin.exemplo.com
+func corsOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("CORS_ORIGINS"), ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// newFormSigner signs CSRF tokens and form render times with CSRF_SECRET
+// (at least 16 bytes). Without it the key is random and open pages need a
+// reload after a restart.
+func newFormSigner() (*signing.Signer, error) {
+	secret := os.Getenv("CSRF_SECRET")
+	if secret == "" {
+		return signing.NewRandom(), nil
+	}
+	return signing.New([]byte(secret))
+}
+
 func setupHeaders() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Headers necessários para Datastar
@@ -110,3 +436,237 @@ func setupHeaders() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// catalogFile returns the configured catalog path for subcommands
+func catalogFile() string {
+	if path := os.Getenv("CATALOG_FILE"); path != "" {
+		return path
+	}
+	return defaultCatalogFile
+}
+
+// newCEPProvider chains the compiled CEP database (CEP_DB), the offline
+// dataset (CEP_DATASET) and a ViaCEP-style API (CEP_API_URL, e.g.
+// https://viacep.com.br/ws), in that order, behind a cache. The built-in
+// mock CEPs answer only when none of them is configured.
+func newCEPProvider(cepDatabase *services.CEPDatabase) (services.CEPProvider, error) {
+	var chain services.ChainCEPProvider
+
+	if cepDatabase != nil {
+		chain = append(chain, cepDatabase)
+	}
+
+	if path := os.Getenv("CEP_DATASET"); path != "" {
+		dataset, err := services.LoadCEPDataset(path)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, dataset)
+	}
+
+	if baseURL := os.Getenv("CEP_API_URL"); baseURL != "" {
+		timeout := defaultCEPTimeout
+		if value := os.Getenv("CEP_API_TIMEOUT"); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, err
+			}
+			timeout = parsed
+		}
+
+		retries := defaultCEPRetries
+		if value := os.Getenv("CEP_API_RETRIES"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, err
+			}
+			retries = parsed
+		}
+
+		chain = append(chain, services.NewHTTPCEPProvider(baseURL, timeout, retries))
+	}
+
+	if len(chain) == 0 {
+		log.Println("⚠️  Nenhuma fonte de CEP configurada: usando os CEPs de demonstração")
+		chain = append(chain, services.NewMockCEPProvider())
+	}
+
+	return services.NewCachedCEPProvider(chain, cepCacheTTL, cepNegativeCacheTTL, cepCacheEntries), nil
+}
+
+// configureFormsStore keeps subscribers and contact messages in the log file
+// named by FORMS_STORE, so they survive restarts; without it they live in
+// memory only
+func configureFormsStore(formsService *services.FormsService) error {
+	path := os.Getenv("FORMS_STORE")
+	if path == "" {
+		log.Println("⚠️  FORMS_STORE não definido: inscrições e mensagens ficam só em memória")
+		return nil
+	}
+
+	repository, err := services.OpenFileFormsRepository(path)
+	if err != nil {
+		return err
+	}
+	formsService.UseRepository(repository)
+	log.Printf("💾 Formulários salvos em %s", path)
+	return nil
+}
+
+// newAuthManager sets up the admin accounts: the users in ADMIN_USERS_FILE
+// (see auth.LoadUsers) plus, if ADMIN_PASSWORD is set, an admin named
+// ADMIN_USER (default admin). Logins are audited to ADMIN_AUDIT_LOG.
+func newAuthManager() (*auth.Manager, error) {
+	var users []auth.User
+	if path := os.Getenv("ADMIN_USERS_FILE"); path != "" {
+		loaded, err := auth.LoadUsers(path)
+		if err != nil {
+			return nil, err
+		}
+		users = loaded
+	}
+	if password := os.Getenv("ADMIN_PASSWORD"); password != "" {
+		users = append(users, auth.User{
+			Username:     envOr("ADMIN_USER", "admin"),
+			PasswordHash: auth.HashPassword(password),
+			Role:         auth.RoleAdmin,
+		})
+	}
+	if len(users) == 0 {
+		log.Println("⚠️  Nenhum usuário admin: defina ADMIN_PASSWORD ou ADMIN_USERS_FILE para acessar /admin")
+	}
+
+	auditPath := envOr("ADMIN_AUDIT_LOG", defaultAuditLog)
+	if err := os.MkdirAll(filepath.Dir(auditPath), 0o755); err != nil {
+		return nil, err
+	}
+	audit, err := auth.NewAuditLog(auditPath)
+	if err != nil {
+		return nil, err
+	}
+	return auth.NewManager(users, audit, auth.Options{})
+}
+
+// configureAttachments keeps the files attached to contact messages in the
+// directory named by ATTACHMENTS_DIR (default data/attachments)
+func configureAttachments(formsService *services.FormsService) error {
+	dir := envOr("ATTACHMENTS_DIR", defaultAttachmentsDir)
+	store, err := services.NewLocalBlobStore(dir)
+	if err != nil {
+		return err
+	}
+	formsService.UseBlobStore(store)
+	log.Printf("📎 Anexos salvos em %s", dir)
+	return nil
+}
+
+// configureEmailChecks turns on the DNS checks of email domains
+// (EMAIL_DNS_CHECK=true) and replaces the built-in disposable domain list
+// with a file, one domain per line (EMAIL_DISPOSABLE_FILE)
+func configureEmailChecks(formsService *services.FormsService) error {
+	if value := os.Getenv("EMAIL_DNS_CHECK"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		if enabled {
+			formsService.UseDomainResolver(net.DefaultResolver)
+		}
+	}
+
+	if path := os.Getenv("EMAIL_DISPOSABLE_FILE"); path != "" {
+		domains, err := services.LoadDisposableDomains(path)
+		if err != nil {
+			return err
+		}
+		formsService.UseDisposableDomains(domains)
+	}
+	return nil
+}
+
+// configureNewsletter sets the key newsletter links are signed with
+// (NEWSLETTER_SECRET, at least 16 bytes) and the public address they point
+// to (PUBLIC_URL). Without a secret, links stop working on restart.
+func configureNewsletter(formsService *services.FormsService, mailer services.NewsletterMailer) error {
+	if secret := os.Getenv("NEWSLETTER_SECRET"); secret != "" {
+		signer, err := signing.New([]byte(secret))
+		if err != nil {
+			return err
+		}
+		formsService.UseTokenSigner(signer)
+	} else {
+		log.Println("⚠️  NEWSLETTER_SECRET não definido: links da newsletter valem só até reiniciar")
+	}
+
+	publicURL := defaultPublicURL
+	if value := os.Getenv("PUBLIC_URL"); value != "" {
+		publicURL = value
+	}
+	formsService.UseNewsletterMailer(mailer, publicURL)
+	return nil
+}
+
+// newMailer sends through SMTP when SMTP_HOST is set (SMTP_PORT, SMTP_TLS
+// starttls|tls|none, SMTP_USERNAME, SMTP_PASSWORD); otherwise emails are
+// captured in the MAIL_OUTBOX directory, which is returned for the dev inbox.
+// Either way delivery is queued, with MAIL_ATTEMPTS tries per email.
+func newMailer() (*mailer.Queue, *mailer.Outbox, error) {
+	var next mailer.Mailer
+	var outbox *mailer.Outbox
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port := defaultSMTPPort
+		if value := os.Getenv("SMTP_PORT"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, nil, err
+			}
+			port = parsed
+		}
+		mode := mailer.TLSMode(envOr("SMTP_TLS", string(mailer.TLSStartTLS)))
+		switch mode {
+		case mailer.TLSStartTLS, mailer.TLSImplicit, mailer.TLSNone:
+		default:
+			return nil, nil, fmt.Errorf("SMTP_TLS must be starttls, tls or none, not %q", mode)
+		}
+		next = mailer.NewSMTPMailer(host, port, mode, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+		log.Printf("📧 Emails via SMTP %s:%d (%s)", host, port, mode)
+	} else {
+		var err error
+		dir := envOr("MAIL_OUTBOX", defaultMailOutbox)
+		outbox, err = mailer.NewOutbox(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		next = outbox
+		log.Printf("📧 Emails capturados na caixa de saída em %s", dir)
+	}
+
+	var options mailer.QueueOptions
+	if value := os.Getenv("MAIL_ATTEMPTS"); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, nil, err
+		}
+		options.Attempts = attempts
+	}
+	return mailer.NewQueue(next, options), outbox, nil
+}
+
+// devInboxEnabled tells whether DEV_INBOX turns on the development inbox,
+// which shows the outbox to admins
+func devInboxEnabled() (bool, error) {
+	value := os.Getenv("DEV_INBOX")
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// envOr returns the environment variable key, or fallback when it's unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// Audit events
+const (
+	EventLogin  = "login"
+	EventLogout = "logout"
+)
+
+// Reasons a login was refused
+const (
+	ReasonUnknownUser = "unknown_user"
+	ReasonBadPassword = "bad_password"
+	ReasonLocked      = "locked"
+	ReasonBusy        = "busy"
+)
+
+// auditMemory is how many entries are kept for Recent
+const auditMemory = 500
+
+// AuditEntry is a login attempt or a logout
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	Username  string    `json:"username"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"userAgent,omitempty"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// AuditLog keeps the login trail: the latest entries in memory and, when
+// opened with a path, every entry appended to that file as a JSON line
+type AuditLog struct {
+	mu      sync.Mutex
+	file    *os.File
+	entries []AuditEntry
+}
+
+// NewAuditLog opens the trail at path, reading back its latest entries;
+// an empty path keeps it in memory only
+func NewAuditLog(path string) (*AuditLog, error) {
+	audit := &AuditLog{}
+	if path == "" {
+		return audit, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry AuditEntry
+		// A line torn by a crash is skipped, the trail goes on
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			audit.remember(entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	audit.file = file
+	return audit, nil
+}
+
+// Record adds an entry to the trail
+func (a *AuditLog) Record(entry AuditEntry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.remember(entry)
+	if a.file == nil {
+		return nil
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return a.file.Sync()
+}
+
+// Recent returns up to limit entries, newest first; a negative limit returns
+// none
+func (a *AuditLog) Recent(limit int) []AuditEntry {
+	limit = max(limit, 0)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	limit = min(limit, len(a.entries))
+	recent := make([]AuditEntry, 0, limit)
+	for i := len(a.entries) - 1; i >= len(a.entries)-limit; i-- {
+		recent = append(recent, a.entries[i])
+	}
+	return recent
+}
+
+// Close closes the trail file, if any
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// remember keeps entry in memory, dropping the oldest past auditMemory;
+// callers must hold a.mu, or own a
+func (a *AuditLog) remember(entry AuditEntry) {
+	if len(a.entries) == auditMemory {
+		a.entries = append(a.entries[:0], a.entries[1:]...)
+	}
+	a.entries = append(a.entries, entry)
+}
@@ -0,0 +1,388 @@
+// Package auth guards the admin area: local users with argon2id or bcrypt
+// password hashes, sessions kept on the server and named by a random ID in
+// an HttpOnly cookie, and three roles, each including the one below:
+// viewer reads, editor changes data, admin also sees the login trail.
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidCredentials means the user doesn't exist or the password
+	// is wrong; callers can't tell which
+	ErrInvalidCredentials = errors.New("auth: invalid username or password")
+
+	// ErrLocked means too many failed logins for the user, from the IP or
+	// for the user from that IP
+	ErrLocked = errors.New("auth: too many failed logins")
+
+	// ErrBusy means too many passwords are being checked at once
+	ErrBusy = errors.New("auth: too many logins in progress")
+)
+
+// Role is what a user may do in the admin area
+type Role string
+
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleAdmin  Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// Valid tells whether r is a known role
+func (r Role) Valid() bool {
+	return roleRank[r] > 0
+}
+
+// Allows tells whether r includes the permissions of required
+func (r Role) Allows(required Role) bool {
+	return r.Valid() && roleRank[r] >= roleRank[required]
+}
+
+// User is an admin account, as kept in the users file
+type User struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"password"`
+	Role         Role   `json:"role"`
+}
+
+// LoadUsers reads a JSON list of users:
+//
+//	[{"username": "ana", "password": "$argon2id$v=19$...", "role": "editor"}]
+func LoadUsers(path string) ([]User, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var users []User
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return users, nil
+}
+
+// Session is a logged-in user
+type Session struct {
+	ID       string    `json:"-"`
+	Username string    `json:"username"`
+	Role     Role      `json:"role"`
+	Created  time.Time `json:"created"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// Options tune a Manager; zero values pick the defaults
+type Options struct {
+	// IdleTimeout ends sessions unused for that long (default 30m)
+	IdleTimeout time.Duration
+
+	// MaxAge ends sessions that old, used or not (default 12h)
+	MaxAge time.Duration
+
+	// MaxFailures failed logins for a user from one IP within
+	// LockoutWindow lock further attempts out (defaults 5 and 15m)
+	MaxFailures   int
+	LockoutWindow time.Duration
+
+	// MaxIPFailures failed logins from one IP, whatever the users, lock
+	// that IP out (default 20); MaxAccountFailures from any IPs lock the
+	// user out (default 10). Both count within LockoutWindow.
+	MaxIPFailures      int
+	MaxAccountFailures int
+
+	// MaxVerifications bounds the passwords checked at once, since each
+	// argon2id check takes 64 MiB; attempts past it get ErrBusy (default 4)
+	MaxVerifications int
+}
+
+// Manager checks logins and keeps the sessions. Sessions live in memory,
+// so a restart logs everyone out.
+type Manager struct {
+	users   map[string]User
+	audit   *AuditLog
+	options Options
+
+	// verifying holds a token per password check in progress
+	verifying chan struct{}
+
+	mu        sync.Mutex
+	sessions  map[string]*Session
+	failures  map[string][]time.Time
+	lastPrune time.Time
+}
+
+// failureBucket counts failed logins under key, locking out at limit
+type failureBucket struct {
+	key   string
+	limit int
+}
+
+// NewManager checks the users: unique names (ignoring case), known roles
+// and readable hashes
+func NewManager(users []User, audit *AuditLog, options Options) (*Manager, error) {
+	if options.IdleTimeout <= 0 {
+		options.IdleTimeout = 30 * time.Minute
+	}
+	if options.MaxAge <= 0 {
+		options.MaxAge = 12 * time.Hour
+	}
+	if options.MaxFailures <= 0 {
+		options.MaxFailures = 5
+	}
+	if options.LockoutWindow <= 0 {
+		options.LockoutWindow = 15 * time.Minute
+	}
+	if options.MaxIPFailures <= 0 {
+		options.MaxIPFailures = 20
+	}
+	if options.MaxAccountFailures <= 0 {
+		options.MaxAccountFailures = 10
+	}
+	if options.MaxVerifications <= 0 {
+		options.MaxVerifications = 4
+	}
+
+	byName := make(map[string]User, len(users))
+	for _, user := range users {
+		key := strings.ToLower(strings.TrimSpace(user.Username))
+		switch {
+		case key == "":
+			return nil, errors.New("auth: user without username")
+		case byName[key].Username != "":
+			return nil, fmt.Errorf("auth: user %q listed twice", user.Username)
+		case !user.Role.Valid():
+			return nil, fmt.Errorf("auth: user %q has unknown role %q", user.Username, user.Role)
+		}
+		if err := CheckHash(user.PasswordHash); err != nil {
+			return nil, fmt.Errorf("auth: user %q: %w", user.Username, err)
+		}
+		byName[key] = user
+	}
+
+	return &Manager{
+		users:     byName,
+		audit:     audit,
+		options:   options,
+		verifying: make(chan struct{}, options.MaxVerifications),
+		sessions:  map[string]*Session{},
+		failures:  map[string][]time.Time{},
+		lastPrune: time.Now(),
+	}, nil
+}
+
+// HasUsers tells whether anyone can log in
+func (m *Manager) HasUsers() bool {
+	return len(m.users) > 0
+}
+
+// Login checks the credentials and starts a session. Failures count against
+// the user from that IP, the IP and the user, and any of them can lock
+// further attempts out. Every attempt goes to the audit trail.
+func (m *Manager) Login(username, password, ip, userAgent string) (Session, error) {
+	username = strings.TrimSpace(username)
+	key := strings.ToLower(username)
+	buckets := []failureBucket{
+		{"pair:" + key + "|" + ip, m.options.MaxFailures},
+		{"ip:" + ip, m.options.MaxIPFailures},
+		{"user:" + key, m.options.MaxAccountFailures},
+	}
+	entry := AuditEntry{Event: EventLogin, Username: username, IP: ip, UserAgent: userAgent}
+
+	if m.locked(buckets) {
+		entry.Reason = ReasonLocked
+		m.record(entry)
+		return Session{}, ErrLocked
+	}
+
+	user, known := m.users[key]
+	hash := user.PasswordHash
+	if !known {
+		hash = dummyHash()
+	}
+	ok, err := m.verify(hash, password)
+	if errors.Is(err, ErrBusy) {
+		entry.Reason = ReasonBusy
+		m.record(entry)
+		return Session{}, ErrBusy
+	}
+	if err != nil {
+		log.Printf("Erro ao conferir senha de %s: %v", username, err)
+	}
+	if !known || !ok {
+		entry.Reason = ReasonBadPassword
+		if !known {
+			entry.Reason = ReasonUnknownUser
+		}
+		m.fail(buckets)
+		m.record(entry)
+		return Session{}, ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	session := &Session{
+		ID:       newSessionID(),
+		Username: user.Username,
+		Role:     user.Role,
+		Created:  now,
+		LastSeen: now,
+	}
+
+	m.mu.Lock()
+	m.prune(now)
+	// The IP keeps its count: one right password doesn't pay for the
+	// others it tried
+	delete(m.failures, buckets[0].key)
+	delete(m.failures, buckets[2].key)
+	m.sessions[session.ID] = session
+	m.mu.Unlock()
+
+	entry.Username = user.Username
+	entry.Success = true
+	m.record(entry)
+	return *session, nil
+}
+
+// Session returns the live session with that ID, marking it as used
+func (m *Manager) Session(id string) (Session, bool) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok {
+		return Session{}, false
+	}
+	if m.expired(session, now) {
+		delete(m.sessions, id)
+		return Session{}, false
+	}
+	session.LastSeen = now
+	return *session, true
+}
+
+// Logout ends the session with that ID
+func (m *Manager) Logout(id, ip, userAgent string) {
+	m.mu.Lock()
+	session, ok := m.sessions[id]
+	delete(m.sessions, id)
+	m.mu.Unlock()
+
+	if ok {
+		m.record(AuditEntry{
+			Event:     EventLogout,
+			Username:  session.Username,
+			IP:        ip,
+			UserAgent: userAgent,
+			Success:   true,
+		})
+	}
+}
+
+// AuditTrail returns up to limit audit entries, newest first
+func (m *Manager) AuditTrail(limit int) []AuditEntry {
+	return m.audit.Recent(limit)
+}
+
+func (m *Manager) record(entry AuditEntry) {
+	if err := m.audit.Record(entry); err != nil {
+		log.Printf("Erro ao registrar auditoria de %s: %v", entry.Username, err)
+	}
+}
+
+// verify checks a password unless MaxVerifications checks are already
+// running, in which case it returns ErrBusy right away
+func (m *Manager) verify(hash, password string) (bool, error) {
+	select {
+	case m.verifying <- struct{}{}:
+	default:
+		return false, ErrBusy
+	}
+	defer func() { <-m.verifying }()
+
+	return VerifyPassword(hash, password)
+}
+
+// locked tells whether any of the buckets has used up its failed attempts
+func (m *Manager) locked(buckets []failureBucket) bool {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, bucket := range buckets {
+		recent := 0
+		for _, at := range m.failures[bucket.key] {
+			if now.Sub(at) < m.options.LockoutWindow {
+				recent++
+			}
+		}
+		if recent >= bucket.limit {
+			return true
+		}
+	}
+	return false
+}
+
+// fail counts a failed login in every bucket, keeping the latest limit
+func (m *Manager) fail(buckets []failureBucket) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.prune(now)
+	for _, bucket := range buckets {
+		failures := append(m.failures[bucket.key], now)
+		if len(failures) > bucket.limit {
+			failures = failures[len(failures)-bucket.limit:]
+		}
+		m.failures[bucket.key] = failures
+	}
+}
+
+func (m *Manager) expired(session *Session, now time.Time) bool {
+	return now.Sub(session.LastSeen) > m.options.IdleTimeout || now.Sub(session.Created) > m.options.MaxAge
+}
+
+// prune drops expired sessions and old failures; callers must hold m.mu
+func (m *Manager) prune(now time.Time) {
+	if now.Sub(m.lastPrune) < time.Minute {
+		return
+	}
+	m.lastPrune = now
+
+	for id, session := range m.sessions {
+		if m.expired(session, now) {
+			delete(m.sessions, id)
+		}
+	}
+	for key, failures := range m.failures {
+		if now.Sub(failures[len(failures)-1]) >= m.options.LockoutWindow {
+			delete(m.failures, key)
+		}
+	}
+}
+
+func newSessionID() string {
+	id := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(id)
+}
@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testPassword = "senha-certa"
+
+// testManager has users ana, bia and caio with testPassword, hashed with
+// the cheapest bcrypt so tests stay fast
+func testManager(t *testing.T, options Options) *Manager {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit, err := NewAuditLog("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager([]User{
+		{Username: "ana", PasswordHash: string(hash), Role: RoleAdmin},
+		{Username: "bia", PasswordHash: string(hash), Role: RoleEditor},
+		{Username: "caio", PasswordHash: string(hash), Role: RoleViewer},
+	}, audit, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func login(t *testing.T, m *Manager, username, password, ip string, want error) {
+	t.Helper()
+	if _, err := m.Login(username, password, ip, "test"); !errors.Is(err, want) {
+		t.Fatalf("Login(%s, from %s) = %v, want %v", username, ip, err, want)
+	}
+}
+
+func TestLoginLocksOutUserFromIP(t *testing.T) {
+	m := testManager(t, Options{MaxFailures: 3})
+
+	for range 3 {
+		login(t, m, "ana", "errada", "10.0.0.1", ErrInvalidCredentials)
+	}
+	// Locked even with the right password, and audited as such
+	login(t, m, "ana", testPassword, "10.0.0.1", ErrLocked)
+	if reason := m.AuditTrail(1)[0].Reason; reason != ReasonLocked {
+		t.Errorf("audit reason = %q, want %q", reason, ReasonLocked)
+	}
+
+	// Other IPs and other users from the same IP aren't
+	login(t, m, "ana", testPassword, "10.0.0.2", nil)
+	login(t, m, "bia", testPassword, "10.0.0.1", nil)
+}
+
+func TestLoginLocksOutIPSprayingUsers(t *testing.T) {
+	m := testManager(t, Options{MaxIPFailures: 3})
+
+	// One password tried across users never trips the per-user buckets
+	for _, username := range []string{"ana", "bia", "caio"} {
+		login(t, m, username, "senha123", "10.0.0.1", ErrInvalidCredentials)
+	}
+	login(t, m, "ana", testPassword, "10.0.0.1", ErrLocked)
+	login(t, m, "ana", testPassword, "10.0.0.2", nil)
+}
+
+func TestLoginLocksOutAccountAcrossIPs(t *testing.T) {
+	m := testManager(t, Options{MaxAccountFailures: 3})
+
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		login(t, m, "Ana", "errada", ip, ErrInvalidCredentials)
+	}
+	login(t, m, "ana", testPassword, "10.0.0.4", ErrLocked)
+	login(t, m, "bia", testPassword, "10.0.0.4", nil)
+}
+
+func TestLoginLockoutExpires(t *testing.T) {
+	m := testManager(t, Options{MaxFailures: 2, LockoutWindow: 50 * time.Millisecond})
+
+	login(t, m, "ana", "errada", "10.0.0.1", ErrInvalidCredentials)
+	login(t, m, "ana", "errada", "10.0.0.1", ErrInvalidCredentials)
+	login(t, m, "ana", testPassword, "10.0.0.1", ErrLocked)
+
+	time.Sleep(60 * time.Millisecond)
+	login(t, m, "ana", testPassword, "10.0.0.1", nil)
+}
+
+func TestLoginSuccessClearsUserFailures(t *testing.T) {
+	m := testManager(t, Options{MaxFailures: 3, MaxIPFailures: 100})
+
+	login(t, m, "ana", "errada", "10.0.0.1", ErrInvalidCredentials)
+	login(t, m, "ana", "errada", "10.0.0.1", ErrInvalidCredentials)
+	login(t, m, "ana", testPassword, "10.0.0.1", nil)
+	login(t, m, "ana", "errada", "10.0.0.1", ErrInvalidCredentials)
+	login(t, m, "ana", "errada", "10.0.0.1", ErrInvalidCredentials)
+	login(t, m, "ana", testPassword, "10.0.0.1", nil)
+}
+
+func TestLoginBusy(t *testing.T) {
+	m := testManager(t, Options{MaxVerifications: 2})
+
+	// Two checks in progress take every slot
+	m.verifying <- struct{}{}
+	m.verifying <- struct{}{}
+	login(t, m, "ana", testPassword, "10.0.0.1", ErrBusy)
+	if reason := m.AuditTrail(1)[0].Reason; reason != ReasonBusy {
+		t.Errorf("audit reason = %q, want %q", reason, ReasonBusy)
+	}
+
+	<-m.verifying
+	login(t, m, "ana", testPassword, "10.0.0.1", nil)
+	if len(m.verifying) != 1 {
+		t.Errorf("%d slots taken after the login, want the slot given back", len(m.verifying))
+	}
+}
+
+func TestSessionExpires(t *testing.T) {
+	m := testManager(t, Options{IdleTimeout: 50 * time.Millisecond, MaxAge: time.Hour})
+
+	session, err := m.Login("ana", testPassword, "10.0.0.1", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Session(session.ID); !ok {
+		t.Fatal("new session not found")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := m.Session(session.ID); ok {
+		t.Error("session still live past the idle timeout")
+	}
+
+	m = testManager(t, Options{IdleTimeout: time.Hour, MaxAge: 50 * time.Millisecond})
+	session, _ = m.Login("ana", testPassword, "10.0.0.1", "test")
+	for range 3 {
+		time.Sleep(20 * time.Millisecond)
+		m.Session(session.ID)
+	}
+	if _, ok := m.Session(session.ID); ok {
+		t.Error("session still live past its maximum age, though in use")
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role, required Role
+		want           bool
+	}{
+		{RoleAdmin, RoleViewer, true},
+		{RoleAdmin, RoleAdmin, true},
+		{RoleEditor, RoleViewer, true},
+		{RoleEditor, RoleEditor, true},
+		{RoleEditor, RoleAdmin, false},
+		{RoleViewer, RoleEditor, false},
+		{Role("root"), RoleViewer, false},
+		{Role(""), RoleViewer, false},
+	}
+	for _, tt := range tests {
+		if got := tt.role.Allows(tt.required); got != tt.want {
+			t.Errorf("%q.Allows(%q) = %v, want %v", tt.role, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestRequire(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := testManager(t, Options{})
+
+	router := gin.New()
+	router.Use(m.Middleware())
+	router.GET("/admin/import", Require(RoleEditor), func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	sessionOf := func(username string) string {
+		session, err := m.Login(username, testPassword, "10.0.0.1", "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return session.ID
+	}
+
+	tests := []struct {
+		name     string
+		session  string
+		accept   string
+		status   int
+		location string
+	}{
+		{"no session, API", "", "application/json", http.StatusUnauthorized, ""},
+		{"no session, page", "", "text/html", http.StatusSeeOther, "/admin/login?next=%2Fadmin%2Fimport%3Fx%3D1"},
+		{"unknown session", "forjada", "application/json", http.StatusUnauthorized, ""},
+		{"viewer", sessionOf("caio"), "application/json", http.StatusForbidden, ""},
+		{"editor", sessionOf("bia"), "application/json", http.StatusOK, ""},
+		{"admin", sessionOf("ana"), "application/json", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/import?x=1", nil)
+			req.Header.Set("Accept", tt.accept)
+			if tt.session != "" {
+				req.AddCookie(&http.Cookie{Name: CookieName, Value: tt.session})
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if location := w.Header().Get("Location"); location != tt.location {
+				t.Errorf("Location = %q, want %q", location, tt.location)
+			}
+		})
+	}
+}
+
+func TestSafeNext(t *testing.T) {
+	tests := map[string]string{
+		"":                                 "/admin",
+		"/admin":                           "/admin",
+		"/admin/":                          "/admin/",
+		"/admin/contact-messages?page=2":   "/admin/contact-messages?page=2",
+		"/admin/login":                     "/admin",
+		"/admin/login?next=/admin/import":  "/admin",
+		"/administrator":                   "/admin",
+		"/forms":                           "/admin",
+		"https://evil.example/admin":       "/admin",
+		"//evil.example/admin":             "/admin",
+		"/\\evil.example/admin":            "/admin",
+		"javascript:alert(1)":              "/admin",
+		"/admin/../forms":                  "/admin",
+		"/admin/./login":                   "/admin",
+		"/admin/import#top":                "/admin/import",
+		"http:/admin":                      "/admin",
+		"/admin/search?q=caf%C3%A9&page=1": "/admin/search?q=caf%C3%A9&page=1",
+	}
+	for next, want := range tests {
+		if got := SafeNext(next); got != want {
+			t.Errorf("SafeNext(%q) = %q, want %q", next, got, want)
+		}
+	}
+}
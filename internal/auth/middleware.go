@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// CookieName holds the session ID
+	CookieName = "admin_session"
+
+	// LoginPath is where visitors without a session are sent
+	LoginPath = "/admin/login"
+
+	// cookiePath keeps the cookie to the admin area
+	cookiePath = "/admin"
+)
+
+type contextKey struct{}
+
+// CurrentSession returns the session of the current request, for handlers
+// and templates; false outside the middleware or when logged out
+func CurrentSession(ctx context.Context) (Session, bool) {
+	session, ok := ctx.Value(contextKey{}).(Session)
+	return session, ok
+}
+
+// Middleware loads the session named by the cookie, if it's still live.
+// It lets every request through; Require refuses them.
+func (m *Manager) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if id, err := c.Cookie(CookieName); err == nil {
+			if session, ok := m.Session(id); ok {
+				c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), contextKey{}, session))
+			}
+		}
+		c.Next()
+	}
+}
+
+// Require refuses requests without a session (401) or whose role doesn't
+// include role (403). Pages are redirected to the login instead of the 401.
+func Require(role Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, ok := CurrentSession(c.Request.Context())
+		if !ok {
+			if c.Request.Method == http.MethodGet && strings.Contains(c.GetHeader("Accept"), "text/html") {
+				c.Redirect(http.StatusSeeOther, LoginPath+"?next="+url.QueryEscape(c.Request.URL.RequestURI()))
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+		if !session.Role.Allows(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// SetCookie hands the session to the browser
+func (m *Manager) SetCookie(c *gin.Context, session Session) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(CookieName, session.ID, int(m.options.MaxAge.Seconds()), cookiePath, "", c.Request.TLS != nil, true)
+}
+
+// ClearCookie removes the session cookie
+func (m *Manager) ClearCookie(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(CookieName, "", -1, cookiePath, "", c.Request.TLS != nil, true)
+}
+
+// SafeNext is where to go after logging in: next if it's a path in the
+// admin area, the admin home otherwise, so the login can't be used to send
+// people to other sites
+func SafeNext(next string) string {
+	target, err := url.Parse(next)
+	if err != nil || target.Scheme != "" || target.Host != "" || strings.HasPrefix(next, "//") || strings.Contains(next, "\\") {
+		return cookiePath
+	}
+	// Dot segments could climb out of the admin area or back to the login
+	cleaned := path.Clean(target.Path)
+	if cleaned != cookiePath && !strings.HasPrefix(cleaned, cookiePath+"/") {
+		return cookiePath
+	}
+	if cleaned == LoginPath {
+		return cookiePath
+	}
+	return target.RequestURI()
+}
@@ -0,0 +1,173 @@
+// Package csrf protects state-changing requests with the double-submit
+// pattern. Every visitor gets a signed token in an HttpOnly cookie; pages
+// render the same token into the Datastar store, and unsafe requests must
+// send it back, in the X-CSRF-Token header or as the "csrf" key of their
+// JSON body (Datastar posts the whole store). A cross-site page can make the
+// browser send the cookie but can't read the token to echo it.
+package csrf
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"showcase-datastar-go/internal/signing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// CookieName holds the visitor's token
+	CookieName = "csrf_token"
+
+	// HeaderName is where API clients and uploads send the token
+	HeaderName = "X-CSRF-Token"
+
+	// FieldName is the key of the token in the Datastar store
+	FieldName = "csrf"
+
+	// TokenTTL is how long a token is accepted; tokens older than half of
+	// it are replaced on the next page load
+	TokenTTL = 24 * time.Hour
+)
+
+// maxBodyPeek bounds how much of a JSON body is read looking for the token;
+// bigger requests must use the header
+const maxBodyPeek = 1 << 20
+
+const purpose = "csrf"
+
+// Error messages shown by the page when a request is refused
+const (
+	MessageMissing = "Token de segurança ausente. Recarregue a página e tente novamente."
+	MessageInvalid = "Token de segurança inválido ou expirado. Recarregue a página e tente novamente."
+)
+
+type contextKey struct{}
+
+// Token returns the token of the current request, for templates; empty
+// outside the middleware
+func Token(ctx context.Context) string {
+	token, _ := ctx.Value(contextKey{}).(string)
+	return token
+}
+
+// Middleware issues tokens and refuses unsafe requests (POST, PUT, PATCH,
+// DELETE) without a matching one with 403. Paths in exempt skip the check;
+// they must be protected by other means, such as a signed link.
+func Middleware(signer *signing.Signer, exempt ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		skip[path] = true
+	}
+
+	return func(c *gin.Context) {
+		cookie, _ := c.Cookie(CookieName)
+		issued, valid := verify(signer, cookie)
+
+		if !safeMethod(c.Request.Method) && !skip[c.Request.URL.Path] {
+			submitted := submittedToken(c)
+			switch {
+			case submitted == "":
+				refuse(c, MessageMissing)
+				return
+			case !valid || subtle.ConstantTimeCompare([]byte(submitted), []byte(cookie)) != 1:
+				refuse(c, MessageInvalid)
+				return
+			}
+		}
+
+		token := cookie
+		if !valid || (safeMethod(c.Request.Method) && time.Since(issued) > TokenTTL/2) {
+			token = issue(signer)
+			c.SetSameSite(http.SameSiteLaxMode)
+			c.SetCookie(CookieName, token, int(TokenTTL.Seconds()), "/", "", c.Request.TLS != nil, true)
+		}
+
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), contextKey{}, token))
+		c.Next()
+	}
+}
+
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// issue signs a random nonce along with the time it was issued
+func issue(signer *signing.Signer) string {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+	subject := base64.RawURLEncoding.EncodeToString(nonce) + ":" + strconv.FormatInt(time.Now().Unix(), 10)
+	return signer.Sign(purpose, subject, TokenTTL)
+}
+
+// verify checks a cookie token and returns when it was issued
+func verify(signer *signing.Signer, token string) (time.Time, bool) {
+	if token == "" {
+		return time.Time{}, false
+	}
+	subject, err := signer.Verify(purpose, token)
+	if err != nil {
+		return time.Time{}, false
+	}
+	_, unix, ok := strings.Cut(subject, ":")
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+// submittedToken reads the token from the header or, for JSON requests,
+// from the body, which is put back for the handler
+func submittedToken(c *gin.Context) string {
+	if token := c.GetHeader(HeaderName); token != "" {
+		return token
+	}
+	if c.ContentType() != "application/json" || c.Request.Body == nil {
+		return ""
+	}
+
+	peeked, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBodyPeek+1))
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), c.Request.Body), c.Request.Body}
+	if err != nil || len(peeked) > maxBodyPeek {
+		return ""
+	}
+
+	var body struct {
+		Token string `json:"csrf"`
+	}
+	if json.Unmarshal(peeked, &body) != nil {
+		return ""
+	}
+	return body.Token
+}
+
+// refuse answers 403 with the message in the body and, for Datastar pages,
+// in the csrfError store key
+func refuse(c *gin.Context, message string) {
+	store, _ := json.Marshal(gin.H{"csrfError": message})
+	c.Header("Content-Type", "application/json")
+	c.Header("Datastar-Merge-Store", string(store))
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": message})
+}
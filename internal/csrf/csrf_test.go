@@ -0,0 +1,148 @@
+package csrf
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"showcase-datastar-go/internal/signing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testRouter echoes the request token on GET and the body on POST, so tests
+// can see the body reaches the handler intact
+func testRouter(signer *signing.Signer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(signer, "/newsletter/unsubscribe"))
+	r.GET("/", func(c *gin.Context) { c.String(http.StatusOK, Token(c.Request.Context())) })
+	echo := func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	}
+	r.POST("/forms/contact", echo)
+	r.POST("/newsletter/unsubscribe", echo)
+	return r
+}
+
+func serve(r *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func tokenCookie(w *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == CookieName {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// signedAt forges a genuine token issued at the given time
+func signedAt(signer *signing.Signer, issued time.Time, ttl time.Duration) string {
+	return signer.Sign(purpose, "nonce:"+strconv.FormatInt(issued.Unix(), 10), ttl)
+}
+
+func TestGetIssuesToken(t *testing.T) {
+	signer := signing.NewRandom()
+	r := testRouter(signer)
+
+	w := serve(r, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET status = %d", w.Code)
+	}
+	cookie := tokenCookie(w)
+	if cookie == nil {
+		t.Fatal("GET set no token cookie")
+	}
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.Path != "/" {
+		t.Errorf("cookie = %+v, want HttpOnly, SameSite=Lax, Path=/", cookie)
+	}
+	if w.Body.String() != cookie.Value {
+		t.Errorf("page token %q differs from the cookie %q", w.Body.String(), cookie.Value)
+	}
+
+	// A fresh token is kept; one past half its life is replaced
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	if w := serve(r, req); tokenCookie(w) != nil || w.Body.String() != cookie.Value {
+		t.Error("a fresh token was replaced")
+	}
+	old := signedAt(signer, time.Now().Add(-TokenTTL/2-time.Minute), time.Hour)
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: CookieName, Value: old})
+	if w := serve(r, req); tokenCookie(w) == nil || w.Body.String() == old {
+		t.Error("a token past half its life was kept")
+	}
+}
+
+func TestPostChecksToken(t *testing.T) {
+	signer := signing.NewRandom()
+	r := testRouter(signer)
+	token := tokenCookie(serve(r, httptest.NewRequest(http.MethodGet, "/", nil))).Value
+	other := tokenCookie(serve(r, httptest.NewRequest(http.MethodGet, "/", nil))).Value
+	// Pushing the expiry forward keeps the old signature, which no longer fits
+	parts := strings.Split(token, ".")
+	tampered := parts[0] + "." + strconv.FormatInt(time.Now().Add(365*24*time.Hour).Unix(), 10) + "." + parts[2]
+	expired := signedAt(signer, time.Now().Add(-2*time.Hour), -time.Hour)
+	foreign := tokenCookie(serve(testRouter(signing.NewRandom()), httptest.NewRequest(http.MethodGet, "/", nil))).Value
+
+	tests := []struct {
+		name        string
+		path        string
+		cookie      string
+		header      string
+		contentType string
+		body        string
+		status      int
+		message     string
+	}{
+		{"no token", "/forms/contact", token, "", "", "", http.StatusForbidden, MessageMissing},
+		{"no cookie", "/forms/contact", "", token, "", "", http.StatusForbidden, MessageInvalid},
+		{"header matches", "/forms/contact", token, token, "", "", http.StatusOK, ""},
+		{"header differs from cookie", "/forms/contact", token, other, "", "", http.StatusForbidden, MessageInvalid},
+		{"token in Datastar body", "/forms/contact", token, "", "application/json", `{"csrf":"` + token + `","email":"ana@example.com"}`, http.StatusOK, ""},
+		{"other token in body", "/forms/contact", token, "", "application/json", `{"csrf":"` + other + `"}`, http.StatusForbidden, MessageInvalid},
+		{"body token ignored unless JSON", "/forms/contact", token, "", "text/plain", `{"csrf":"` + token + `"}`, http.StatusForbidden, MessageMissing},
+		{"tampered expiry", "/forms/contact", tampered, tampered, "", "", http.StatusForbidden, MessageInvalid},
+		{"expired", "/forms/contact", expired, expired, "", "", http.StatusForbidden, MessageInvalid},
+		{"signed with another key", "/forms/contact", foreign, foreign, "", "", http.StatusForbidden, MessageInvalid},
+		{"exempt path", "/newsletter/unsubscribe", "", "", "", "token=assinado", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: CookieName, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				req.Header.Set(HeaderName, tt.header)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := serve(r, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.status == http.StatusOK {
+				if w.Body.String() != tt.body {
+					t.Errorf("handler read body %q, want %q", w.Body.String(), tt.body)
+				}
+				return
+			}
+			if !strings.Contains(w.Body.String(), tt.message) || !strings.Contains(w.Header().Get("Datastar-Merge-Store"), tt.message) {
+				t.Errorf("refusal = %s (store %s), want %q", w.Body.String(), w.Header().Get("Datastar-Merge-Store"), tt.message)
+			}
+		})
+	}
+}
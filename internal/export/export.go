@@ -0,0 +1,87 @@
+// Package export writes tables as CSV or XLSX files for download
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Formats of the exported files
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Table is a header and rows of text cells
+type Table struct {
+	// Sheet names the XLSX worksheet
+	Sheet  string
+	Header []string
+	Rows   [][]string
+}
+
+// ContentType is the MIME type of a format
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Write writes table in format, CSV unless it's FormatXLSX
+func Write(w io.Writer, format string, table Table) error {
+	if format == FormatXLSX {
+		return WriteXLSX(w, table)
+	}
+	return WriteCSV(w, table)
+}
+
+// WriteCSV writes table as CSV, with a byte order mark so spreadsheets read
+// it as UTF-8
+func WriteCSV(w io.Writer, table Table) error {
+	if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(table.Header); err != nil {
+		return err
+	}
+	for _, row := range table.Rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = escapeFormula(cell)
+		}
+		if err := writer.Write(cells); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// formulaStarts are the characters a spreadsheet may take as the start of a
+// formula, with their full-width and small forms, which some convert
+const formulaStarts = "=+-@" + "＝＋－＠" + "﹦﹢﹣﹫"
+
+// escapeFormula quotes cells a spreadsheet would run as a formula, since
+// they hold what visitors typed. Leading spaces and invisible characters are
+// skipped, as spreadsheets skip them too.
+func escapeFormula(cell string) string {
+	if cell == "" {
+		return cell
+	}
+	if strings.ContainsRune("\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	first, _ := utf8.DecodeRuneInString(strings.TrimLeftFunc(cell, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.Is(unicode.Cf, r)
+	}))
+	if strings.ContainsRune(formulaStarts, first) {
+		return "'" + cell
+	}
+	return cell
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"showcase-datastar-go/internal/auth"
+	"showcase-datastar-go/internal/templates/pages"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditPageSize is how many login attempts the admin home lists
+const auditPageSize = 50
+
+// AdminHandler serves the login and the admin home
+type AdminHandler struct {
+	auth *auth.Manager
+}
+
+func NewAdminHandler(manager *auth.Manager) *AdminHandler {
+	return &AdminHandler{
+		auth: manager,
+	}
+}
+
+// LoginPage renders the login form; logged-in users go straight in
+func (h *AdminHandler) LoginPage(c *gin.Context) {
+	next := auth.SafeNext(c.Query("next"))
+	if _, ok := auth.CurrentSession(c.Request.Context()); ok {
+		c.Redirect(http.StatusSeeOther, next)
+		return
+	}
+
+	c.Header("Content-Type", "text/html")
+	c.Header("Cache-Control", "no-store")
+	pages.AdminLogin(next, h.auth.HasUsers()).Render(c.Request.Context(), c.Writer)
+}
+
+// Login checks the credentials and sets the session cookie. The page gets
+// a redirect over SSE; API clients get the user as JSON.
+func (h *AdminHandler) Login(c *gin.Context) {
+	// The page posts its whole store, with the form under "login"
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Next     string `json:"next"`
+		Store    *struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Next     string `json:"next"`
+		} `json:"login"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if req.Store != nil {
+		req.Username, req.Password, req.Next = req.Store.Username, req.Store.Password, req.Store.Next
+	}
+
+	session, err := h.auth.Login(req.Username, req.Password, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		status, message := http.StatusUnauthorized, "Usuário ou senha inválidos"
+		switch {
+		case errors.Is(err, auth.ErrLocked):
+			status, message = http.StatusTooManyRequests, "Muitas tentativas. Aguarde alguns minutos e tente de novo."
+		case errors.Is(err, auth.ErrBusy):
+			status, message = http.StatusServiceUnavailable, "Muitos acessos ao mesmo tempo. Tente de novo em instantes."
+		}
+		storeUpdate := map[string]interface{}{
+			"login":      map[string]string{"password": ""},
+			"loginError": message,
+		}
+		if isDatastar(c) {
+			startDatastar(c)
+			sendSignal(c, storeUpdate)
+			return
+		}
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	h.auth.SetCookie(c, session)
+	next := auth.SafeNext(req.Next)
+	if isDatastar(c) {
+		startDatastar(c)
+		sendSignal(c, map[string]interface{}{
+			"login":      map[string]string{"password": ""},
+			"loginError": "",
+		})
+		sendRedirect(c, next)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"username": session.Username,
+		"role":     session.Role,
+		"redirect": next,
+	})
+}
+
+// Logout ends the session and clears the cookie
+func (h *AdminHandler) Logout(c *gin.Context) {
+	if id, err := c.Cookie(auth.CookieName); err == nil {
+		h.auth.Logout(id, c.ClientIP(), c.Request.UserAgent())
+	}
+	h.auth.ClearCookie(c)
+
+	if isDatastar(c) {
+		startDatastar(c)
+		sendRedirect(c, auth.LoginPath)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"redirect": auth.LoginPath})
+}
+
+// AdminPage is the admin home; admins also see the latest logins
+func (h *AdminHandler) AdminPage(c *gin.Context) {
+	session, _ := auth.CurrentSession(c.Request.Context())
+	var trail []auth.AuditEntry
+	if session.Role.Allows(auth.RoleAdmin) {
+		trail = h.auth.AuditTrail(auditPageSize)
+	}
+
+	c.Header("Content-Type", "text/html")
+	c.Header("Cache-Control", "no-store")
+	pages.Admin(session, trail).Render(c.Request.Context(), c.Writer)
+}
+
+// AuditTrail returns the latest login attempts and logouts (admin only)
+func (h *AdminHandler) AuditTrail(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(auditPageSize)))
+	if err != nil || limit <= 0 {
+		limit = auditPageSize
+	}
+
+	entries := h.auth.AuditTrail(limit)
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"total":   len(entries),
+	})
+}
+
+// Me returns the logged-in user
+func (h *AdminHandler) Me(c *gin.Context) {
+	session, _ := auth.CurrentSession(c.Request.Context())
+	c.JSON(http.StatusOK, session)
+}
@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"showcase-datastar-go/internal/auth"
+	"showcase-datastar-go/internal/export"
+	"showcase-datastar-go/internal/services"
+	"showcase-datastar-go/internal/templates/pages"
+
+	"github.com/a-h/templ"
+	"github.com/gin-gonic/gin"
+)
+
+// maxTableRequest bounds the store a table action posts
+const maxTableRequest = 1 << 20
+
+// exportTime is how dates are written in exports
+const exportTime = "2006-01-02 15:04:05"
+
+// tableRequest is what a table request carries: the filters, sort and page
+// and the rows to act on
+type tableRequest struct {
+	params services.TableParams
+
+	// selection is the selection signal as the page has it, checked or not
+	selection map[string]bool
+
+	// ids are the rows checked in the page or, for API clients, listed
+	// under "ids"
+	ids []string
+}
+
+// readTableRequest reads the Datastar store, which posts send as the body
+// and gets as the datastar query param. Links and API clients send the
+// params in the query string instead.
+func readTableRequest(c *gin.Context, t pages.AdminTable) (tableRequest, error) {
+	var req tableRequest
+
+	var raw []byte
+	if c.Request.Method == http.MethodGet {
+		raw = []byte(c.Query("datastar"))
+	} else if c.ContentType() == "application/json" {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxTableRequest))
+		if err != nil {
+			return req, err
+		}
+		raw = body
+	}
+	if len(raw) == 0 {
+		err := c.ShouldBindQuery(&req.params)
+		return req, err
+	}
+
+	var store map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &store); err != nil {
+		return req, err
+	}
+	if params, ok := store[t.TableKey()]; ok {
+		if err := json.Unmarshal(params, &req.params); err != nil {
+			return req, err
+		}
+	} else if err := c.ShouldBindQuery(&req.params); err != nil {
+		return req, err
+	}
+	if selection, ok := store[t.SelectionKey()]; ok {
+		if err := json.Unmarshal(selection, &req.selection); err != nil {
+			return req, err
+		}
+		for id, checked := range req.selection {
+			if checked {
+				req.ids = append(req.ids, id)
+			}
+		}
+	}
+	if ids, ok := store["ids"]; ok {
+		if err := json.Unmarshal(ids, &req.ids); err != nil {
+			return req, err
+		}
+	}
+	return req, nil
+}
+
+// wantsPage tells a browser, which gets the page, from an API client, which
+// gets JSON
+func wantsPage(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), "text/html")
+}
+
+// canEdit tells whether the current user may run the bulk actions
+func canEdit(c *gin.Context) bool {
+	session, _ := auth.CurrentSession(c.Request.Context())
+	return session.Role.Allows(auth.RoleEditor)
+}
+
+// sendTable answers a Datastar table request: the page's rows start
+// unselected, store is merged along, and the fragment replaces the table
+func sendTable(c *gin.Context, t pages.AdminTable, req tableRequest, ids []string, page int, fragment templ.Component, store gin.H) {
+	var html bytes.Buffer
+	if err := fragment.Render(c.Request.Context(), &html); err != nil {
+		log.Printf("Erro ao renderizar %s: %v", t.FragmentID(), err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	if store == nil {
+		store = gin.H{}
+	}
+	store[t.SelectionKey()] = pages.TableSelection(req.selection, ids)
+	// Only the page is sent back, moved when it was past the last one; the
+	// filters stay as typed
+	store[t.TableKey()] = gin.H{"page": page}
+
+	startDatastar(c)
+	sendSignal(c, store)
+	sendFragment(c, "", html.String())
+}
+
+// sendTableError reports a failed table request in the page notice or as
+// JSON
+func sendTableError(c *gin.Context, t pages.AdminTable, status int, message string) {
+	if isDatastar(c) {
+		startDatastar(c)
+		sendSignal(c, gin.H{t.NoticeKey(): message})
+		return
+	}
+	c.JSON(status, gin.H{"error": message})
+}
+
+// sendExport sends table as a download named after name and today
+func sendExport(c *gin.Context, name, format string, table export.Table) {
+	if format != export.FormatXLSX {
+		format = export.FormatCSV
+	}
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("2006-01-02"), format)
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	if err := export.Write(c.Writer, format, table); err != nil {
+		log.Printf("Erro ao exportar %s: %v", filename, err)
+	}
+}
+
+func formatExportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Local().Format(exportTime)
+}
+
+// GetContactMessages is the contact messages page for browsers and a page
+// of them as JSON for API clients, filtered by q, from, to, status, sort,
+// dir, page and size (admin endpoint)
+func (h *FormsHandler) GetContactMessages(c *gin.Context) {
+	var params services.TableParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query"})
+		return
+	}
+	page, err := h.formsService.ContactMessagesTable(params.Query())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load messages"})
+		return
+	}
+
+	if wantsPage(c) {
+		c.Header("Content-Type", "text/html")
+		c.Header("Cache-Control", "no-store")
+		pages.AdminContactMessages(page, canEdit(c)).Render(c.Request.Context(), c.Writer)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"messages": page.Rows,
+		"total":    page.Total,
+		"page":     page.Page,
+		"pageSize": page.PageSize,
+		"pages":    page.Pages,
+	})
+}
+
+// ContactMessagesTable re-renders the contact messages table for the page
+// store's filters, sort and page (admin endpoint)
+func (h *FormsHandler) ContactMessagesTable(c *gin.Context) {
+	req, err := readTableRequest(c, pages.ContactMessagesTable)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	h.sendContactMessages(c, req, nil)
+}
+
+func (h *FormsHandler) sendContactMessages(c *gin.Context, req tableRequest, store gin.H) {
+	t := pages.ContactMessagesTable
+	page, err := h.formsService.ContactMessagesTable(req.params.Query())
+	if err != nil {
+		sendTableError(c, t, http.StatusInternalServerError, "Não foi possível carregar as mensagens")
+		return
+	}
+
+	ids := make([]string, len(page.Rows))
+	for i, message := range page.Rows {
+		ids[i] = message.ID
+	}
+	sendTable(c, t, req, ids, page.Page, pages.ContactMessagesFragment(page, canEdit(c)), store)
+}
+
+// MarkContactMessagesRead marks the selected messages as read (admin
+// endpoint). The page gets the table again; API clients post {"ids": [...]}.
+func (h *FormsHandler) MarkContactMessagesRead(c *gin.Context) {
+	h.contactMessagesAction(c, "marcada como lida", "marcadas como lidas", func(ids []string) (int, error) {
+		return h.formsService.MarkContactMessagesRead(ids)
+	})
+}
+
+// DeleteContactMessages deletes the selected messages and their
+// attachments (admin endpoint)
+func (h *FormsHandler) DeleteContactMessages(c *gin.Context) {
+	h.contactMessagesAction(c, "excluída", "excluídas", func(ids []string) (int, error) {
+		return h.formsService.DeleteContactMessages(c.Request.Context(), ids)
+	})
+}
+
+func (h *FormsHandler) contactMessagesAction(c *gin.Context, singular, plural string, action func(ids []string) (int, error)) {
+	t := pages.ContactMessagesTable
+	req, err := readTableRequest(c, t)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if len(req.ids) == 0 {
+		sendTableError(c, t, http.StatusBadRequest, "Selecione ao menos uma mensagem")
+		return
+	}
+
+	n, err := action(req.ids)
+	if err != nil {
+		log.Printf("Erro na ação em lote de mensagens: %v", err)
+		sendTableError(c, t, http.StatusInternalServerError, "Não foi possível concluir, tente novamente em instantes")
+		return
+	}
+
+	notice := fmt.Sprintf("%d mensagens %s", n, plural)
+	if n == 1 {
+		notice = "1 mensagem " + singular
+	}
+	if !isDatastar(c) {
+		c.JSON(http.StatusOK, gin.H{"updated": n, "message": notice})
+		return
+	}
+	h.sendContactMessages(c, req, gin.H{t.NoticeKey(): notice})
+}
+
+// ExportContactMessages downloads every message matching the filters, in
+// the table's order, as CSV or, with format=xlsx, XLSX (admin endpoint)
+func (h *FormsHandler) ExportContactMessages(c *gin.Context) {
+	var params services.TableParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query"})
+		return
+	}
+	messages, err := h.formsService.ContactMessagesRows(params.Query())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load messages"})
+		return
+	}
+
+	table := export.Table{
+		Sheet:  "Mensagens",
+		Header: []string{"ID", "Data", "Nome", "Email", "Assunto", "Mensagem", "Anexos", "Lida"},
+		Rows:   make([][]string, len(messages)),
+	}
+	for i, message := range messages {
+		names := make([]string, len(message.Attachments))
+		for j, attachment := range message.Attachments {
+			names[j] = attachment.Name
+		}
+		read := "Não"
+		if message.Read {
+			read = "Sim"
+		}
+		table.Rows[i] = []string{
+			message.ID,
+			formatExportTime(&message.CreatedAt),
+			message.Name,
+			message.Email,
+			message.Subject,
+			message.Message,
+			strings.Join(names, ", "),
+			read,
+		}
+	}
+	sendExport(c, "mensagens", c.Query("format"), table)
+}
+
+// GetNewsletterSubscribers is the subscribers page for browsers and a page
+// of them as JSON for API clients, with the same filters as the messages
+// (admin endpoint)
+func (h *FormsHandler) GetNewsletterSubscribers(c *gin.Context) {
+	var params services.TableParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query"})
+		return
+	}
+	page, err := h.formsService.SubscribersTable(params.Query())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load subscribers"})
+		return
+	}
+
+	if wantsPage(c) {
+		c.Header("Content-Type", "text/html")
+		c.Header("Cache-Control", "no-store")
+		pages.AdminSubscribers(page, canEdit(c)).Render(c.Request.Context(), c.Writer)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"subscribers": page.Rows,
+		"total":       page.Total,
+		"page":        page.Page,
+		"pageSize":    page.PageSize,
+		"pages":       page.Pages,
+	})
+}
+
+// SubscribersTable re-renders the subscribers table (admin endpoint)
+func (h *FormsHandler) SubscribersTable(c *gin.Context) {
+	t := pages.SubscribersTable
+	req, err := readTableRequest(c, t)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	h.sendSubscribers(c, req, nil)
+}
+
+func (h *FormsHandler) sendSubscribers(c *gin.Context, req tableRequest, store gin.H) {
+	t := pages.SubscribersTable
+	page, err := h.formsService.SubscribersTable(req.params.Query())
+	if err != nil {
+		sendTableError(c, t, http.StatusInternalServerError, "Não foi possível carregar os inscritos")
+		return
+	}
+
+	ids := make([]string, len(page.Rows))
+	for i, subscriber := range page.Rows {
+		ids[i] = subscriber.ID
+	}
+	sendTable(c, t, req, ids, page.Page, pages.SubscribersFragment(page, canEdit(c)), store)
+}
+
+// DeleteSubscribers deletes the selected subscribers (admin endpoint)
+func (h *FormsHandler) DeleteSubscribers(c *gin.Context) {
+	t := pages.SubscribersTable
+	req, err := readTableRequest(c, t)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if len(req.ids) == 0 {
+		sendTableError(c, t, http.StatusBadRequest, "Selecione ao menos um inscrito")
+		return
+	}
+
+	n, err := h.formsService.DeleteSubscribers(req.ids)
+	if err != nil {
+		log.Printf("Erro ao excluir inscritos: %v", err)
+		sendTableError(c, t, http.StatusInternalServerError, "Não foi possível concluir, tente novamente em instantes")
+		return
+	}
+
+	notice := fmt.Sprintf("%d inscritos excluídos", n)
+	if n == 1 {
+		notice = "1 inscrito excluído"
+	}
+	if !isDatastar(c) {
+		c.JSON(http.StatusOK, gin.H{"updated": n, "message": notice})
+		return
+	}
+	h.sendSubscribers(c, req, gin.H{t.NoticeKey(): notice})
+}
+
+// ExportSubscribers downloads every subscriber matching the filters as CSV
+// or XLSX (admin endpoint)
+func (h *FormsHandler) ExportSubscribers(c *gin.Context) {
+	var params services.TableParams
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query"})
+		return
+	}
+	subscribers, err := h.formsService.SubscribersRows(params.Query())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load subscribers"})
+		return
+	}
+
+	table := export.Table{
+		Sheet:  "Inscritos",
+		Header: []string{"ID", "Inscrição", "Nome", "Email", "Status", "Confirmação", "Descadastro"},
+		Rows:   make([][]string, len(subscribers)),
+	}
+	for i, subscriber := range subscribers {
+		table.Rows[i] = []string{
+			subscriber.ID,
+			formatExportTime(&subscriber.CreatedAt),
+			subscriber.Name,
+			subscriber.Email,
+			pages.SubscriberStatusLabel(subscriber.Status),
+			formatExportTime(subscriber.ConfirmedAt),
+			formatExportTime(subscriber.UnsubscribedAt),
+		}
+	}
+	sendExport(c, "inscritos", c.Query("format"), table)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"showcase-datastar-go/internal/services"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCatalogUpload bounds the size of an uploaded catalog
+const maxCatalogUpload = 10 << 20
+
+type CatalogHandler struct {
+	searchService *services.SearchService
+}
+
+func NewCatalogHandler(searchService *services.SearchService) *CatalogHandler {
+	return &CatalogHandler{
+		searchService: searchService,
+	}
+}
+
+// ImportCatalog validates and applies a bulk catalog upload (admin endpoint).
+// The file comes either as the "file" multipart field or as the raw body;
+// ?dryRun=true only returns the per-row report and ?mode=merge upserts
+// instead of replacing the catalog.
+func (h *CatalogHandler) ImportCatalog(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCatalogUpload)
+
+	var (
+		body        io.Reader = c.Request.Body
+		name        string
+		contentType = c.ContentType()
+	)
+
+	if strings.HasPrefix(contentType, "multipart/") {
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing file field"})
+			return
+		}
+		defer file.Close()
+		body = file
+		name = header.Filename
+		contentType = header.Header.Get("Content-Type")
+	}
+
+	format, err := services.DetectImportFormat(name, contentType)
+	if requested := c.Query("format"); requested != "" {
+		format, err = services.ParseImportFormat(requested)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.searchService.ImportCatalog(body, services.ImportOptions{
+		Format: format,
+		DryRun: c.Query("dryRun") == "true",
+		Merge:  c.Query("mode") == "merge",
+	})
+
+	switch {
+	case errors.Is(err, services.ErrImportRejected):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Import rejected: catalog left unchanged",
+			"report": report,
+		})
+	case err != nil && report == nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  err.Error(),
+			"report": report,
+		})
+	default:
+		c.JSON(http.StatusOK, gin.H{"report": report})
+	}
+}
@@ -55,6 +55,8 @@ func (h *DashboardHandler) GetStats(c *gin.Context) {
 		"dashboardActivity":  stats.DashboardActivity,
 		"formsActivity":      stats.FormsActivity,
 		"componentsActivity": stats.ComponentsActivity,
+		"spamBlocked":        stats.SpamBlocked,
+		"spamReasons":        stats.SpamReasons,
 		"lastUpdate":         stats.LastUpdate.Format("15:04:05"),
 		"connected":          true,
 	}
@@ -119,6 +121,8 @@ func (h *DashboardHandler) LiveUpdates(c *gin.Context) {
 				"dashboardActivity":  stats.DashboardActivity,
 				"formsActivity":      stats.FormsActivity,
 				"componentsActivity": stats.ComponentsActivity,
+				"spamBlocked":        stats.SpamBlocked,
+				"spamReasons":        stats.SpamReasons,
 				"lastUpdate":         stats.LastUpdate.Format("15:04:05"),
 			}
 
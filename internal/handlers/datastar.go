@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// isDatastar tells whether the request comes from a Datastar action
+// ($$get, $$post...), which reads its answer as Server-Sent Events
+func isDatastar(c *gin.Context) bool {
+	return c.GetHeader("datastar-request") == "true"
+}
+
+// startDatastar begins an event stream answering a Datastar action
+func startDatastar(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+}
+
+// sendSignal merges store into the page store
+func sendSignal(c *gin.Context, store interface{}) {
+	storeJSON, _ := json.Marshal(store)
+	c.Writer.Write([]byte("event: datastar-signal\n"))
+	c.Writer.Write([]byte(fmt.Sprintf("data: store %s\n\n", storeJSON)))
+	c.Writer.Flush()
+}
+
+// sendFragment morphs html into the element matched by selector, or into
+// the element with the same id as its root when selector is empty
+func sendFragment(c *gin.Context, selector, html string) {
+	c.Writer.Write([]byte("event: datastar-fragment\n"))
+	if selector != "" {
+		c.Writer.Write([]byte(fmt.Sprintf("data: selector %s\n", selector)))
+	}
+	// The client reads the fragment from the first line only; the rest
+	// would be mistaken for options
+	html = strings.ReplaceAll(strings.TrimSpace(html), "\n", " ")
+	c.Writer.Write([]byte(fmt.Sprintf("data: fragment %s\n\n", html)))
+	c.Writer.Flush()
+}
+
+// sendRedirect sends the browser to url
+func sendRedirect(c *gin.Context, url string) {
+	c.Writer.Write([]byte("event: datastar-redirect\n"))
+	c.Writer.Write([]byte(fmt.Sprintf("data: url %s\n\n", url)))
+	c.Writer.Flush()
+}
@@ -1,29 +1,70 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"showcase-datastar-go/internal/search"
 	"showcase-datastar-go/internal/services"
 	"showcase-datastar-go/internal/templates/pages"
+	"showcase-datastar-go/internal/validation"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// uploadWatchTimeout bounds a progress stream, in case the upload never
+// starts or stalls
+const uploadWatchTimeout = 10 * time.Minute
+
 type FormsHandler struct {
 	formsService *services.FormsService
+	spamGuard    *services.SpamGuard
+	powGuard     *services.PowGuard
+	uploads      *services.UploadTracker
 }
 
-func NewFormsHandler(formsService *services.FormsService) *FormsHandler {
+func NewFormsHandler(formsService *services.FormsService, spamGuard *services.SpamGuard, powGuard *services.PowGuard) *FormsHandler {
 	return &FormsHandler{
 		formsService: formsService,
+		spamGuard:    spamGuard,
+		powGuard:     powGuard,
+		uploads:      services.NewUploadTracker(),
 	}
 }
 
 // FormsPage renders the forms page
 func (h *FormsHandler) FormsPage(c *gin.Context) {
 	c.Header("Content-Type", "text/html")
-	pages.Forms().Render(c.Request.Context(), c.Writer)
+	guard := pages.FormGuard{
+		Token:      h.spamGuard.RenderToken(),
+		Contact:    h.powChallenge(c),
+		Newsletter: h.powChallenge(c),
+	}
+	pages.Forms(guard).Render(c.Request.Context(), c.Writer)
+}
+
+// PowChallenge issues a fresh proof-of-work challenge, for pages whose
+// challenge is about to expire and for API clients
+func (h *FormsHandler) PowChallenge(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, h.powChallenge(c))
+}
+
+func (h *FormsHandler) powChallenge(c *gin.Context) pages.PowChallenge {
+	challenge := h.powGuard.Challenge(c.ClientIP())
+	return pages.PowChallenge{
+		Token:      challenge.Token,
+		Difficulty: challenge.Difficulty,
+		Expires:    challenge.Expires,
+	}
 }
 
 // ValidateEmail validates email field
@@ -37,10 +78,16 @@ func (h *FormsHandler) ValidateEmail(c *gin.Context) {
 		return
 	}
 
-	result := h.formsService.ValidateEmail(req.Email)
+	result, suggestion := h.formsService.ValidateEmail(c.Request.Context(), req.Email)
 
-	// Update store with validation result
-	storeUpdate := map[string]interface{}{}
+	// Update store with validation result; the suggestion names the address
+	// it was made for, so each form shows only its own
+	storeUpdate := map[string]interface{}{
+		"emailSuggestion": map[string]string{
+			"email":      req.Email,
+			"suggestion": suggestion,
+		},
+	}
 
 	if !result.Valid {
 		storeUpdate["contactErrors"] = map[string]string{
@@ -57,7 +104,11 @@ func (h *FormsHandler) ValidateEmail(c *gin.Context) {
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Datastar-Merge-Store", string(storeData))
 
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, gin.H{
+		"valid":      result.Valid,
+		"message":    result.Message,
+		"suggestion": suggestion,
+	})
 }
 
 // ValidateField validates generic form fields
@@ -145,6 +196,44 @@ func (h *FormsHandler) ValidateCPF(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// ValidateCNPJ validates a Brazilian CNPJ and masks it as it is typed
+func (h *FormsHandler) ValidateCNPJ(c *gin.Context) {
+	var req struct {
+		CNPJ string `json:"cnpj"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	result := h.formsService.ValidateCNPJ(req.CNPJ)
+
+	// Always write back the formatted value, valid or not, to mask as typed
+	storeUpdate := map[string]interface{}{
+		"validation": map[string]string{
+			"cnpj": result.Data,
+		},
+	}
+
+	if result.Valid {
+		storeUpdate["validationResults"] = map[string]string{
+			"cnpj": "valid",
+		}
+	} else {
+		storeUpdate["validationResults"] = map[string]string{
+			"cnpj": "invalid",
+		}
+	}
+
+	storeData, _ := json.Marshal(storeUpdate)
+	c.Header("Content-Type", "application/json")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Datastar-Merge-Store", string(storeData))
+
+	c.JSON(http.StatusOK, result)
+}
+
 // MaskPhone applies phone mask
 func (h *FormsHandler) MaskPhone(c *gin.Context) {
 	var req struct {
@@ -178,7 +267,57 @@ func (h *FormsHandler) MaskPhone(c *gin.Context) {
 	})
 }
 
-// ValidateCEP validates CEP and returns location data
+// ValidatePhone validates a phone number and masks it as it is typed
+func (h *FormsHandler) ValidatePhone(c *gin.Context) {
+	var req struct {
+		Phone string `json:"phone"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	result, phone := h.formsService.ValidatePhone(req.Phone)
+
+	// Always write back the formatted value, valid or not, to mask as typed
+	storeUpdate := map[string]interface{}{
+		"validation": map[string]string{
+			"phone": result.Data,
+		},
+	}
+
+	if result.Valid {
+		storeUpdate["validationResults"] = map[string]string{
+			"phone":        "valid",
+			"phoneMessage": result.Message,
+			"phoneE164":    phone.E164,
+			"phoneType":    string(phone.Type),
+		}
+	} else {
+		storeUpdate["validationResults"] = map[string]string{
+			"phone":        "invalid",
+			"phoneMessage": result.Message,
+			"phoneE164":    "",
+			"phoneType":    "",
+		}
+	}
+
+	storeData, _ := json.Marshal(storeUpdate)
+	c.Header("Content-Type", "application/json")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Datastar-Merge-Store", string(storeData))
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":   result.Valid,
+		"message": result.Message,
+		"data":    result.Data,
+		"phone":   phone,
+	})
+}
+
+// ValidateCEP validates CEP and autofills the address.* signals with the
+// street, neighborhood, city and UF it belongs to
 func (h *FormsHandler) ValidateCEP(c *gin.Context) {
 	var req struct {
 		CEP string `json:"cep"`
@@ -189,20 +328,84 @@ func (h *FormsHandler) ValidateCEP(c *gin.Context) {
 		return
 	}
 
-	result := h.formsService.ValidateCEP(req.CEP)
+	result, address := h.formsService.ValidateCEP(c.Request.Context(), req.CEP)
 
-	// Update store with validation result
-	storeUpdate := map[string]interface{}{}
+	// Update store with validation result; a new CEP means a new address
+	storeUpdate := map[string]interface{}{
+		"addressSuccess": false,
+	}
 
 	if result.Valid {
 		storeUpdate["validationResults"] = map[string]interface{}{
 			"cep":     "valid",
 			"cepData": result.Data,
 		}
+		// A CEP known only by its range leaves street and neighborhood
+		// empty for the user to fill in
+		storeUpdate["address"] = map[string]string{
+			"cep":          address.CEP,
+			"street":       address.Street,
+			"neighborhood": address.Neighborhood,
+			"city":         address.City,
+			"uf":           address.UF,
+		}
+		storeUpdate["addressErrors"] = map[string]string{
+			"cep": "",
+		}
+		storeUpdate["addressStatus"] = result.Message
 	} else {
 		storeUpdate["validationResults"] = map[string]string{
 			"cep": "invalid",
 		}
+		// Drop whatever a previous CEP filled in
+		storeUpdate["address"] = map[string]string{
+			"street":       "",
+			"neighborhood": "",
+			"city":         "",
+			"uf":           "",
+		}
+		storeUpdate["addressErrors"] = map[string]string{
+			"cep": result.Message,
+		}
+		storeUpdate["addressStatus"] = ""
+	}
+
+	storeData, _ := json.Marshal(storeUpdate)
+	c.Header("Content-Type", "application/json")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Datastar-Merge-Store", string(storeData))
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":   result.Valid,
+		"message": result.Message,
+		"data":    result.Data,
+		"address": address,
+	})
+}
+
+// ValidateAddressField validates a single address form field
+func (h *FormsHandler) ValidateAddressField(c *gin.Context) {
+	var req struct {
+		Field string `json:"field"`
+		Value string `json:"value"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	result := h.formsService.ValidateAddressField(req.Field, req.Value)
+
+	// Only the validated field's message changes
+	message := ""
+	if !result.Valid {
+		message = result.Message
+	}
+	storeUpdate := map[string]interface{}{
+		"addressErrors": map[string]string{
+			req.Field: message,
+		},
 	}
 
 	storeData, _ := json.Marshal(storeUpdate)
@@ -213,6 +416,59 @@ func (h *FormsHandler) ValidateCEP(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// SubmitAddress validates the whole address form
+func (h *FormsHandler) SubmitAddress(c *gin.Context) {
+	// The page posts its whole store, with the form under "address"
+	var req struct {
+		services.AddressForm
+		Store *services.AddressForm `json:"address"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	form := req.AddressForm
+	if req.Store != nil {
+		form = *req.Store
+	}
+
+	result, fieldErrors := h.formsService.SubmitAddress(c.Request.Context(), form)
+
+	// Update store with result
+	storeUpdate := map[string]interface{}{
+		"addressLoading": false,
+	}
+
+	if result.Valid {
+		storeUpdate["addressSuccess"] = true
+		storeUpdate["addressErrors"] = map[string]string{}
+		storeUpdate["addressStatus"] = result.Data
+	} else {
+		addressErrors := map[string]string{
+			"general": result.Message,
+		}
+		for field, message := range fieldErrors {
+			addressErrors[field] = message
+		}
+		storeUpdate["addressSuccess"] = false
+		storeUpdate["addressErrors"] = addressErrors
+	}
+
+	storeData, _ := json.Marshal(storeUpdate)
+	c.Header("Content-Type", "application/json")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Datastar-Merge-Store", string(storeData))
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":   result.Valid,
+		"message": result.Message,
+		"data":    result.Data,
+		"errors":  fieldErrors,
+	})
+}
+
 // CountChars counts characters and updates progress
 func (h *FormsHandler) CountChars(c *gin.Context) {
 	var req struct {
@@ -244,9 +500,12 @@ func (h *FormsHandler) CountChars(c *gin.Context) {
 
 // SubmitNewsletter handles newsletter subscription
 func (h *FormsHandler) SubmitNewsletter(c *gin.Context) {
+	// The page posts its whole store, with the form under "newsletter"
 	var req struct {
-		Name  string `json:"name"`
-		Email string `json:"email"`
+		services.NewsletterForm
+		Store *services.NewsletterForm        `json:"newsletter"`
+		Guard services.FormGuard              `json:"formGuard"`
+		Pow   map[string]services.PowSolution `json:"pow"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -254,18 +513,25 @@ func (h *FormsHandler) SubmitNewsletter(c *gin.Context) {
 		return
 	}
 
-	// Simulate loading delay
-	time.Sleep(500 * time.Millisecond)
+	form := req.NewsletterForm
+	if req.Store != nil {
+		form = *req.Store
+	}
 
-	result := h.formsService.SubmitNewsletter(req.Name, req.Email)
+	result := h.screen(c, services.FormNewsletter, form.Email, req.Guard, req.Pow[services.FormNewsletter])
+	if result == nil {
+		result, _ = h.formsService.SubmitNewsletter(c.Request.Context(), form)
+	}
 
-	// Update store with result
+	// Update store with result; the challenge is spent either way
 	storeUpdate := map[string]interface{}{
 		"newsletterLoading": false,
+		"pow":               map[string]interface{}{services.FormNewsletter: h.powChallenge(c)},
 	}
 
 	if result.Valid {
 		storeUpdate["newsletterSuccess"] = true
+		storeUpdate["newsletterMessage"] = result.Message
 		storeUpdate["newsletterError"] = ""
 		storeUpdate["newsletter"] = map[string]string{
 			"name":  "",
@@ -284,28 +550,52 @@ func (h *FormsHandler) SubmitNewsletter(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// contactRequest is the contact form as posted, either as the page store in
+// JSON or as multipart with files attached
+type contactRequest struct {
+	services.ContactForm
+	Store *services.ContactForm           `json:"contactForm"`
+	Guard services.FormGuard              `json:"formGuard"`
+	Pow   map[string]services.PowSolution `json:"pow"`
+
+	files []*multipart.FileHeader
+}
+
+// maxContactUpload bounds a multipart contact request: the files at their
+// limit plus room for the fields
+const maxContactUpload = services.MaxAttachments*services.MaxAttachmentSize + 1<<20
+
 // SubmitContact handles contact form submission
 func (h *FormsHandler) SubmitContact(c *gin.Context) {
-	var req struct {
-		Name    string `json:"name"`
-		Email   string `json:"email"`
-		Subject string `json:"subject"`
-		Message string `json:"message"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
+	var req contactRequest
+	multipartForm := c.ContentType() == "multipart/form-data"
+	if multipartForm {
+		if status, message := h.bindContactUpload(c, &req); message != "" {
+			c.JSON(status, gin.H{"error": message})
+			return
+		}
+		defer c.Request.MultipartForm.RemoveAll()
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		// The page posts its whole store, with the form under "contactForm"
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
 
-	// Simulate loading delay
-	time.Sleep(1 * time.Second)
+	form := req.ContactForm
+	if req.Store != nil {
+		form = *req.Store
+	}
 
-	result := h.formsService.SubmitContact(req.Name, req.Email, req.Subject, req.Message)
+	var fieldErrors validation.Errors
+	result := h.screen(c, services.FormContact, form.Email, req.Guard, req.Pow[services.FormContact])
+	if result == nil {
+		result, fieldErrors = h.formsService.SubmitContact(c.Request.Context(), form, req.files...)
+	}
 
-	// Update store with result
+	// Update store with result; the challenge is spent either way
 	storeUpdate := map[string]interface{}{
 		"contactLoading": false,
+		"pow":            map[string]interface{}{services.FormContact: h.powChallenge(c)},
 	}
 
 	if result.Valid {
@@ -318,34 +608,210 @@ func (h *FormsHandler) SubmitContact(c *gin.Context) {
 			"message": "",
 		}
 	} else {
-		storeUpdate["contactSuccess"] = false
-		storeUpdate["contactErrors"] = map[string]string{
+		// Per-field messages land next to each input; general sums them up
+		contactErrors := map[string]string{
 			"general": result.Message,
 		}
+		for field, message := range fieldErrors {
+			contactErrors[field] = message
+		}
+		storeUpdate["contactSuccess"] = false
+		storeUpdate["contactErrors"] = contactErrors
 	}
+	// attachments.js applies the update through the form inputs, which
+	// can't hold the errors map, so they're summed up in one message
+	uploadUpdate := map[string]interface{}{"percent": 0, "message": ""}
+	if multipartForm {
+		uploadUpdate["message"] = uploadMessage(result, fieldErrors)
+	}
+	storeUpdate["contactUpload"] = uploadUpdate
 
 	storeData, _ := json.Marshal(storeUpdate)
 	c.Header("Content-Type", "application/json")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Datastar-Merge-Store", string(storeData))
 
-	c.JSON(http.StatusOK, result)
+	c.JSON(http.StatusOK, gin.H{
+		"valid":   result.Valid,
+		"message": result.Message,
+		"data":    result.Data,
+		"errors":  fieldErrors,
+	})
 }
 
-// GetNewsletterSubscribers returns all newsletter subscribers (admin endpoint)
-func (h *FormsHandler) GetNewsletterSubscribers(c *gin.Context) {
-	subscribers := h.formsService.GetNewsletterSubscribers()
-	c.JSON(http.StatusOK, gin.H{
-		"subscribers": subscribers,
-		"total":       len(subscribers),
+// bindContactUpload reads a multipart contact form, reporting the progress
+// of the body to the watchers of the "upload" query parameter. Files over
+// the memory limit are spooled to disk until the request ends. A failure
+// comes back as the status and error to answer.
+func (h *FormsHandler) bindContactUpload(c *gin.Context, req *contactRequest) (int, string) {
+	body := io.Reader(http.MaxBytesReader(c.Writer, c.Request.Body, maxContactUpload))
+	if id := c.Query("upload"); id != "" {
+		if !services.ValidUploadID(id) {
+			return http.StatusBadRequest, "Invalid upload id"
+		}
+		tracked, err := h.uploads.Track(id, c.Request.ContentLength, body)
+		if err != nil {
+			return http.StatusTooManyRequests, "Too many uploads"
+		}
+		defer h.uploads.Finish(id)
+		body = tracked
+	}
+	c.Request.Body = io.NopCloser(body)
+
+	if err := c.Request.ParseMultipartForm(1 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return http.StatusRequestEntityTooLarge, fmt.Sprintf("Request larger than %d MB", maxContactUpload>>20)
+		}
+		return http.StatusBadRequest, "Invalid request"
+	}
+
+	// Fields are named after the store paths they're bound to
+	form := c.Request.MultipartForm
+	req.Name = c.PostForm("name")
+	req.Email = c.PostForm("email")
+	req.Subject = c.PostForm("subject")
+	req.Message = c.PostForm("message")
+	req.Guard = services.FormGuard{
+		Token:   c.PostForm("formGuard.token"),
+		Website: c.PostForm("website"),
+	}
+	req.Pow = map[string]services.PowSolution{
+		services.FormContact: {
+			Token: c.PostForm("pow.contact.token"),
+			Nonce: c.PostForm("pow.contact.nonce"),
+		},
+	}
+	req.files = form.File["attachments"]
+	return http.StatusOK, ""
+}
+
+// uploadMessage sums up the result of a multipart submission: the general
+// message followed by the field errors
+func uploadMessage(result *services.ValidationResult, fieldErrors validation.Errors) string {
+	if result.Valid {
+		return ""
+	}
+	messages := []string{result.Message}
+	for _, field := range []string{"name", "email", "subject", "message", "attachments"} {
+		if message, ok := fieldErrors[field]; ok {
+			messages = append(messages, message)
+		}
+	}
+	return strings.Join(messages, " · ")
+}
+
+// ContactUploadProgress streams the progress of a contact form upload as
+// Server-Sent Events, until the upload is done
+func (h *FormsHandler) ContactUploadProgress(c *gin.Context) {
+	id := c.Query("upload")
+	if !services.ValidUploadID(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload id"})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), uploadWatchTimeout)
+	defer cancel()
+
+	err := h.uploads.Watch(ctx, id, func(progress services.UploadProgress) {
+		storeJSON, _ := json.Marshal(map[string]interface{}{
+			"contactUpload": progress,
+		})
+		c.Writer.Write([]byte("event: upload-progress\n"))
+		c.Writer.Write([]byte(fmt.Sprintf("data: %s\n\n", storeJSON)))
+		c.Writer.Flush()
 	})
+	if errors.Is(err, services.ErrUploadLimit) {
+		c.Writer.Write([]byte("event: upload-error\n"))
+		c.Writer.Write([]byte("data: {\"error\": \"Too many uploads\"}\n\n"))
+		c.Writer.Flush()
+	}
+}
+
+// DownloadAttachment sends an attachment of a contact message (admin
+// endpoint). It's always a download, never rendered inline.
+func (h *FormsHandler) DownloadAttachment(c *gin.Context) {
+	attachment, reader, err := h.formsService.OpenAttachment(c.Request.Context(), c.Param("id"), c.Param("attachment"))
+	if errors.Is(err, services.ErrAttachmentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load attachment"})
+		return
+	}
+	defer reader.Close()
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, reader, nil)
+}
+
+// screen checks the proof of work and runs the spam checks on a public
+// form. Spam gets a decoy success, a missing or wrong proof and an expired
+// page an error, all returned in place of the service result; nil means the
+// submission goes through.
+func (h *FormsHandler) screen(c *gin.Context, form, email string, guard services.FormGuard, solution services.PowSolution) *services.ValidationResult {
+	if err := h.powGuard.Verify(c.ClientIP(), solution); err != nil {
+		return &services.ValidationResult{
+			Valid:   false,
+			Message: "Verificação de segurança não concluída. Aguarde um instante e envie de novo.",
+		}
+	}
+
+	reason, err := h.spamGuard.Check(services.Submission{
+		Form:  form,
+		IP:    c.ClientIP(),
+		Email: email,
+		Guard: guard,
+	})
+	if errors.Is(err, services.ErrFormExpired) {
+		return &services.ValidationResult{
+			Valid:   false,
+			Message: "Formulário expirado. Recarregue a página e tente novamente.",
+		}
+	}
+	if reason != "" {
+		return services.DecoyResult(form, email)
+	}
+	return nil
 }
 
-// GetContactMessages returns all contact messages (admin endpoint)
-func (h *FormsHandler) GetContactMessages(c *gin.Context) {
-	messages := h.formsService.GetContactMessages()
+// SearchContactMessages searches contact messages (admin endpoint)
+func (h *FormsHandler) SearchContactMessages(c *gin.Context) {
+	query := c.Query("q")
+	mode := c.DefaultQuery("mode", services.SearchModeLexical)
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		limit = 20
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), searchTimeout)
+	defer cancel()
+
+	result, err := h.formsService.SearchContactMessages(ctx, query, mode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load messages"})
+		return
+	}
+
+	results := make([]gin.H, 0, len(result.Hits))
+	for _, hit := range search.Paginate(result.Hits, 0, limit) {
+		results = append(results, gin.H{
+			"message": hit.Doc,
+			"score":   hit.Score,
+		})
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"messages": messages,
-		"total":    len(messages),
+		"results": results,
+		"query":   query,
+		"total":   len(result.Hits),
+		"partial": result.Partial,
 	})
 }
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"showcase-datastar-go/internal/mailer"
+	"showcase-datastar-go/internal/templates/pages"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InboxHandler shows the emails captured by the development outbox
+type InboxHandler struct {
+	outbox *mailer.Outbox
+}
+
+func NewInboxHandler(outbox *mailer.Outbox) *InboxHandler {
+	return &InboxHandler{
+		outbox: outbox,
+	}
+}
+
+// InboxPage lists the captured emails
+func (h *InboxHandler) InboxPage(c *gin.Context) {
+	messages, err := h.outbox.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/html")
+	pages.Inbox(messages).Render(c.Request.Context(), c.Writer)
+}
+
+// MessagePage shows one captured email
+func (h *InboxHandler) MessagePage(c *gin.Context) {
+	msg, err := h.outbox.Get(c.Param("id"))
+	if errors.Is(err, mailer.ErrMessageNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/html")
+	pages.InboxMessage(msg).Render(c.Request.Context(), c.Writer)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"showcase-datastar-go/internal/templates/pages"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfirmPage asks the subscriber to confirm the subscription the emailed
+// link points to
+func (h *FormsHandler) ConfirmPage(c *gin.Context) {
+	c.Header("Content-Type", "text/html")
+	pages.NewsletterConfirm(c.Query("token")).Render(c.Request.Context(), c.Writer)
+}
+
+// ConfirmNewsletter confirms a subscription. The page posts its store, with
+// the token under "confirm".
+func (h *FormsHandler) ConfirmNewsletter(c *gin.Context) {
+	var req struct {
+		Store struct {
+			Token string `json:"token"`
+		} `json:"confirm"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	result := h.formsService.ConfirmNewsletter(req.Store.Token)
+
+	// Update store with result
+	confirm := map[string]interface{}{
+		"done": result.Valid,
+	}
+	if result.Valid {
+		confirm["message"] = result.Message
+		confirm["error"] = ""
+	} else {
+		confirm["error"] = result.Message
+	}
+
+	storeData, _ := json.Marshal(map[string]interface{}{
+		"confirm": confirm,
+	})
+	c.Header("Content-Type", "application/json")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Datastar-Merge-Store", string(storeData))
+
+	c.JSON(http.StatusOK, result)
+}
+
+// UnsubscribePage asks the subscriber to confirm leaving the newsletter
+func (h *FormsHandler) UnsubscribePage(c *gin.Context) {
+	c.Header("Content-Type", "text/html")
+	pages.NewsletterUnsubscribe(c.Query("token")).Render(c.Request.Context(), c.Writer)
+}
+
+// UnsubscribeNewsletter cancels a subscription. The page posts its store,
+// with the token under "unsubscribe"; mail clients doing one-click
+// unsubscribe (RFC 8058) post to the emailed link instead.
+func (h *FormsHandler) UnsubscribeNewsletter(c *gin.Context) {
+	var req struct {
+		Token string `json:"token"`
+		Store *struct {
+			Token string `json:"token"`
+		} `json:"unsubscribe"`
+	}
+
+	token := c.Query("token")
+	if token == "" {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		token = req.Token
+		if req.Store != nil {
+			token = req.Store.Token
+		}
+	}
+
+	result := h.formsService.UnsubscribeNewsletter(token)
+
+	// Update store with result
+	unsubscribe := map[string]interface{}{
+		"done": result.Valid,
+	}
+	if result.Valid {
+		unsubscribe["message"] = result.Message
+		unsubscribe["error"] = ""
+	} else {
+		unsubscribe["error"] = result.Message
+	}
+
+	storeData, _ := json.Marshal(map[string]interface{}{
+		"unsubscribe": unsubscribe,
+	})
+	c.Header("Content-Type", "application/json")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Datastar-Merge-Store", string(storeData))
+
+	c.JSON(http.StatusOK, result)
+}
@@ -1,29 +1,45 @@
 package handlers
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
 	"showcase-datastar-go/internal/services"
 	"showcase-datastar-go/internal/templates/fragments"
 	"showcase-datastar-go/internal/templates/pages"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+const searchSessionCookie = "search_session"
+
+// searchTimeout bounds a single search; slower scans return partial results
+const searchTimeout = 300 * time.Millisecond
+
 type SearchHandler struct {
-	searchService *services.SearchService
+	searchService     *services.SearchService
+	historyService    *services.SearchHistoryService
+	siteSearchService *services.SiteSearchService
 }
 
-func NewSearchHandler(searchService *services.SearchService) *SearchHandler {
+func NewSearchHandler(searchService *services.SearchService, historyService *services.SearchHistoryService, siteSearchService *services.SiteSearchService) *SearchHandler {
 	return &SearchHandler{
-		searchService: searchService,
+		searchService:     searchService,
+		historyService:    historyService,
+		siteSearchService: siteSearchService,
 	}
 }
 
 // SearchPage renders the search page
 func (h *SearchHandler) SearchPage(c *gin.Context) {
+	sessionID := searchSessionID(c)
+
 	c.Header("Content-Type", "text/html")
-	pages.Search().Render(c.Request.Context(), c.Writer)
+	pages.Search(h.historyService.Recent(sessionID), h.searchService.Categories(), services.TagCloud(h.tagStats())).Render(c.Request.Context(), c.Writer)
 }
 
 // SearchResults handles search requests and returns HTML fragments
@@ -32,6 +48,7 @@ func (h *SearchHandler) SearchResults(c *gin.Context) {
 	query := c.Query("q")
 	category := c.DefaultQuery("category", "all")
 	sort := c.DefaultQuery("sort", "relevance")
+	mode := c.DefaultQuery("mode", services.SearchModeLexical)
 
 	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
 	if err != nil {
@@ -43,16 +60,30 @@ func (h *SearchHandler) SearchResults(c *gin.Context) {
 		limit = 10
 	}
 
+	sessionID := searchSessionID(c)
+
 	// Perform search
 	searchParams := services.SearchParams{
-		Query:    query,
-		Category: category,
-		Sort:     sort,
-		Offset:   offset,
-		Limit:    limit,
+		Query:          query,
+		Category:       category,
+		Sort:           sort,
+		Mode:           mode,
+		Tag:            c.Query("tag"),
+		Offset:         offset,
+		Limit:          limit,
+		CategoryBoosts: h.historyService.CategoryBoosts(sessionID),
 	}
 
-	response := h.searchService.Search(searchParams)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), searchTimeout)
+	defer cancel()
+
+	response := h.searchService.Search(ctx, searchParams)
+	pageResults, pagesPartial := h.searchPages(ctx, searchParams)
+
+	// Only the first page counts as a new search
+	if offset == 0 {
+		h.historyService.Record(sessionID, searchParams)
+	}
 
 	// Set headers for Datastar
 	c.Header("Content-Type", "text/html")
@@ -62,7 +93,16 @@ func (h *SearchHandler) SearchResults(c *gin.Context) {
 	c.Header("Datastar-Merge-Store", `{"loading": false}`)
 
 	// Render search results fragment
-	fragments.SearchResults(response.Results, query, response.TotalResults).Render(c.Request.Context(), c.Writer)
+	fragments.SearchResults(response.Results, query, response.TotalResults, response.Facets, pageResults, response.Partial || pagesPartial).Render(c.Request.Context(), c.Writer)
+}
+
+// searchPages finds showcase page sections for the first page of an
+// unfiltered search; pages have no catalog category so filters exclude them
+func (h *SearchHandler) searchPages(ctx context.Context, params services.SearchParams) ([]fragments.PageResult, bool) {
+	if params.Offset > 0 || (params.Category != "" && params.Category != "all") {
+		return nil, false
+	}
+	return h.siteSearchService.Search(ctx, params.Query, params.Mode, services.DefaultPageResults)
 }
 
 // GetSuggestions provides search suggestions (autocomplete)
@@ -89,7 +129,10 @@ func (h *SearchHandler) GetSuggestions(c *gin.Context) {
 		Limit:    limit,
 	}
 
-	response := h.searchService.Search(searchParams)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), searchTimeout)
+	defer cancel()
+
+	response := h.searchService.Search(ctx, searchParams)
 
 	// Extract suggestions from results
 	suggestions := make([]string, 0, len(response.Results))
@@ -109,24 +152,28 @@ func (h *SearchHandler) LiveSearch(c *gin.Context) {
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
-	c.Header("Access-Control-Allow-Origin", "*")
-	c.Header("Access-Control-Allow-Headers", "Cache-Control")
 
 	// Get query parameters
 	query := c.Query("q")
 	category := c.DefaultQuery("category", "all")
 	sort := c.DefaultQuery("sort", "relevance")
+	mode := c.DefaultQuery("mode", services.SearchModeLexical)
 
 	// Perform search
 	searchParams := services.SearchParams{
 		Query:    query,
 		Category: category,
 		Sort:     sort,
+		Mode:     mode,
 		Offset:   0,
 		Limit:    10,
 	}
 
-	response := h.searchService.Search(searchParams)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), searchTimeout)
+	defer cancel()
+
+	response := h.searchService.Search(ctx, searchParams)
+	pageResults, pagesPartial := h.searchPages(ctx, searchParams)
 
 	// Send SSE event with search results
 	c.Header("Datastar-Merge-Store", `{"loading": false}`)
@@ -136,8 +183,115 @@ func (h *SearchHandler) LiveSearch(c *gin.Context) {
 	c.Writer.Write([]byte("data: "))
 
 	// Render results as HTML and send
-	fragments.SearchResults(response.Results, query, response.TotalResults).Render(c.Request.Context(), c.Writer)
+	fragments.SearchResults(response.Results, query, response.TotalResults, response.Facets, pageResults, response.Partial || pagesPartial).Render(c.Request.Context(), c.Writer)
 
 	c.Writer.Write([]byte("\n\n"))
 	c.Writer.Flush()
 }
+
+// GetCategories returns the category taxonomy with rolled-up item counts
+func (h *SearchHandler) GetCategories(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"taxonomy":   h.searchService.Taxonomy().Roots(),
+		"categories": h.searchService.Categories(),
+	})
+}
+
+// GetTags returns every catalog tag with its document count, co-occurring
+// tags and trending score
+func (h *SearchHandler) GetTags(c *gin.Context) {
+	stats := h.tagStats()
+
+	c.JSON(http.StatusOK, gin.H{
+		"tags":  stats,
+		"total": len(stats),
+	})
+}
+
+// TagCloud renders the weighted tag cloud fragment
+func (h *SearchHandler) TagCloud(c *gin.Context) {
+	c.Header("Content-Type", "text/html")
+	fragments.TagCloud(services.TagCloud(h.tagStats())).Render(c.Request.Context(), c.Writer)
+}
+
+// tagStats combines catalog tag usage with recent activity
+func (h *SearchHandler) tagStats() []services.TagStat {
+	stats := h.searchService.TagStats()
+
+	tags := make([]string, len(stats))
+	for i, stat := range stats {
+		tags[i] = stat.Tag
+	}
+	services.ApplyTrends(stats, h.historyService.TrendingTags(tags))
+
+	return stats
+}
+
+// RecordClick registers a click on a result to personalize future rankings
+func (h *SearchHandler) RecordClick(c *gin.Context) {
+	var req struct {
+		ID string `json:"id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	item, found := h.searchService.GetByID(req.ID)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		return
+	}
+
+	h.historyService.RecordClick(searchSessionID(c), item)
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":       item.ID,
+		"category": item.Category,
+	})
+}
+
+// ClearHistory removes the visitor's search history and click profile
+func (h *SearchHandler) ClearHistory(c *gin.Context) {
+	h.historyService.Clear(searchSessionID(c))
+
+	c.Header("Content-Type", "text/html")
+	c.Header("Cache-Control", "no-cache")
+
+	// Re-render the (now empty) recent searches block
+	fragments.RecentSearches(nil).Render(c.Request.Context(), c.Writer)
+}
+
+// searchSessionID returns the visitor's search session, issuing a cookie if
+// needed; a cookie not in the form issued here is replaced
+func searchSessionID(c *gin.Context) string {
+	if sessionID, err := c.Cookie(searchSessionCookie); err == nil && validSearchSessionID(sessionID) {
+		return sessionID
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	sessionID := hex.EncodeToString(buf)
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(searchSessionCookie, sessionID, 30*24*60*60, "/", "", c.Request.TLS != nil, true)
+
+	return sessionID
+}
+
+// validSearchSessionID tells whether id is 32 lowercase hex characters, as
+// searchSessionID issues them
+func validSearchSessionID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	for _, r := range id {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
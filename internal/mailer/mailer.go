@@ -0,0 +1,160 @@
+// Package mailer sends email. Messages carry an HTML body and its plain-text
+// alternative, each rendered from a templ component; they go out through SMTP
+// in production or into an outbox directory during development, usually
+// behind a Queue that retries failed deliveries.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/a-h/templ"
+)
+
+// Mailer delivers a message or reports why it couldn't
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Message is an email with HTML and plain-text alternatives
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	HTML    string
+	Text    string
+
+	// Headers are extra headers such as List-Unsubscribe
+	Headers map[string]string
+}
+
+// Render fills the bodies of msg from the HTML and plain-text templates of
+// an email
+func Render(ctx context.Context, msg *Message, html, text templ.Component) error {
+	var htmlBody, textBody strings.Builder
+	if err := html.Render(ctx, &htmlBody); err != nil {
+		return err
+	}
+	if err := text.Render(ctx, &textBody); err != nil {
+		return err
+	}
+	msg.HTML, msg.Text = htmlBody.String(), textBody.String()
+	return nil
+}
+
+// addresses parses the sender and recipients of msg
+func (msg *Message) addresses() (*mail.Address, []*mail.Address, error) {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return nil, nil, fmt.Errorf("mailer: from: %w", err)
+	}
+	if len(msg.To) == 0 {
+		return nil, nil, errors.New("mailer: no recipients")
+	}
+	to := make([]*mail.Address, len(msg.To))
+	for i, address := range msg.To {
+		if to[i], err = mail.ParseAddress(address); err != nil {
+			return nil, nil, fmt.Errorf("mailer: to: %w", err)
+		}
+	}
+	return from, to, nil
+}
+
+// Encode renders msg as a MIME multipart/alternative message with CRLF
+// line endings, ready for SMTP or an .eml file
+func Encode(msg *Message) ([]byte, error) {
+	from, to, err := msg.addresses()
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make([]string, len(to))
+	for i, address := range to {
+		recipients[i] = address.String()
+	}
+
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", from.String())
+	header("To", strings.Join(recipients, ", "))
+	header("Subject", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+newID()+"@"+domainOf(from.Address)+">")
+	header("MIME-Version", "1.0")
+
+	keys := make([]string, 0, len(msg.Headers))
+	for key := range msg.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		header(textproto.CanonicalMIMEHeaderKey(key), msg.Headers[key])
+	}
+
+	header("Content-Type", `multipart/alternative; boundary="`+body.Boundary()+`"`)
+	buf.WriteString("\r\n")
+
+	// Plain text first: clients show the last alternative they understand
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", msg.Text},
+		{"text/html; charset=UTF-8", msg.HTML},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	// Bodies use CRLF on the wire
+	content = strings.ReplaceAll(strings.ReplaceAll(content, "\r\n", "\n"), "\n", "\r\n")
+	if _, err := io.WriteString(qp, content); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// newID returns a unique, sortable identifier for messages
+func newID() string {
+	random := make([]byte, 6)
+	if _, err := rand.Read(random); err != nil {
+		panic(err)
+	}
+	return time.Now().UTC().Format("20060102T150405.000000") + "-" + hex.EncodeToString(random)
+}
+
+func domainOf(address string) string {
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		return address[at+1:]
+	}
+	return "localhost"
+}
@@ -0,0 +1,164 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrMessageNotFound means the outbox has no message with that ID
+var ErrMessageNotFound = errors.New("mailer: message not found")
+
+// outboxIDRegex keeps IDs from naming files outside the outbox
+var outboxIDRegex = regexp.MustCompile(`^[0-9T.]+-[0-9a-f]+$`)
+
+// Outbox is a development mailer: messages are written as .eml files to a
+// directory instead of being sent, and can be read back for the dev inbox
+type Outbox struct {
+	dir string
+}
+
+// NewOutbox creates the directory if needed
+func NewOutbox(dir string) (*Outbox, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Outbox{dir: dir}, nil
+}
+
+// StoredMessage is a message read back from the outbox
+type StoredMessage struct {
+	ID      string
+	From    string
+	To      string
+	Subject string
+	Date    time.Time
+	Headers mail.Header
+	HTML    string
+	Text    string
+}
+
+func (o *Outbox) Send(_ context.Context, msg *Message) error {
+	data, err := Encode(msg)
+	if err != nil {
+		return err
+	}
+
+	// Write then rename, so List never sees half a message
+	id := newID()
+	tmp := filepath.Join(o.dir, "."+id+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, o.path(id))
+}
+
+// List returns the captured messages, newest first, without their bodies
+func (o *Outbox) List() ([]StoredMessage, error) {
+	entries, err := os.ReadDir(o.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := []StoredMessage{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".eml")
+		if !ok || !outboxIDRegex.MatchString(id) {
+			continue
+		}
+		msg, err := o.read(id, false)
+		if err != nil {
+			continue
+		}
+		messages = append(messages, *msg)
+	}
+
+	// IDs start with the time they were written
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID > messages[j].ID })
+	return messages, nil
+}
+
+// Get returns a captured message with its bodies
+func (o *Outbox) Get(id string) (*StoredMessage, error) {
+	if !outboxIDRegex.MatchString(id) {
+		return nil, ErrMessageNotFound
+	}
+	return o.read(id, true)
+}
+
+func (o *Outbox) path(id string) string {
+	return filepath.Join(o.dir, id+".eml")
+}
+
+func (o *Outbox) read(id string, withBody bool) (*StoredMessage, error) {
+	file, err := os.Open(o.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	raw, err := mail.ReadMessage(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoder mime.WordDecoder
+	subject, err := decoder.DecodeHeader(raw.Header.Get("Subject"))
+	if err != nil {
+		subject = raw.Header.Get("Subject")
+	}
+	date, _ := raw.Header.Date()
+
+	msg := &StoredMessage{
+		ID:      id,
+		From:    raw.Header.Get("From"),
+		To:      raw.Header.Get("To"),
+		Subject: subject,
+		Date:    date,
+		Headers: raw.Header,
+	}
+	if !withBody {
+		return msg, nil
+	}
+
+	_, params, err := mime.ParseMediaType(raw.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+
+	// NextPart undoes the quoted-printable encoding
+	parts := multipart.NewReader(raw.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		body := strings.ReplaceAll(string(content), "\r\n", "\n")
+		switch mediaType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type")); mediaType {
+		case "text/plain":
+			msg.Text = body
+		case "text/html":
+			msg.HTML = body
+		}
+	}
+	return msg, nil
+}
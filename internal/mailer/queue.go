@@ -0,0 +1,195 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQueueFull means too many messages are waiting to be delivered
+	ErrQueueFull = errors.New("mailer: queue full")
+
+	// ErrQueueClosed means the queue no longer accepts messages
+	ErrQueueClosed = errors.New("mailer: queue closed")
+)
+
+// QueueOptions tune a Queue; zero values pick the defaults
+type QueueOptions struct {
+	// Size is how many messages may wait for delivery (default 100)
+	Size int
+
+	// Workers is how many messages are delivered at once (default 4)
+	Workers int
+
+	// Attempts is how many times a message is tried before giving up
+	// (default 4)
+	Attempts int
+
+	// Backoff is the wait before the first retry, doubled after each
+	// failure (default 2s)
+	Backoff time.Duration
+
+	// Timeout bounds each delivery attempt (default 30s)
+	Timeout time.Duration
+}
+
+// Queue delivers messages in the background through another mailer,
+// retrying temporary failures with exponential backoff. Send returns as soon
+// as the message is queued, so request handlers don't wait on SMTP. A
+// message waiting for a retry doesn't hold up the others.
+type Queue struct {
+	next    Mailer
+	options QueueOptions
+
+	mu     sync.RWMutex
+	closed bool
+	jobs   chan *job
+
+	// pending counts the messages accepted and not yet delivered or given
+	// up on, retries included
+	pending sync.WaitGroup
+
+	// flush is closed by Close so waiting retries go at once
+	flush chan struct{}
+
+	workers sync.WaitGroup
+}
+
+// job is a message and how far along its retries are
+type job struct {
+	msg     *Message
+	attempt int
+	backoff time.Duration
+}
+
+// NewQueue starts delivering through next; Close stops it
+func NewQueue(next Mailer, options QueueOptions) *Queue {
+	if options.Size <= 0 {
+		options.Size = 100
+	}
+	if options.Workers <= 0 {
+		options.Workers = 4
+	}
+	if options.Attempts <= 0 {
+		options.Attempts = 4
+	}
+	if options.Backoff <= 0 {
+		options.Backoff = 2 * time.Second
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultSMTPTimeout
+	}
+
+	q := &Queue{
+		next:    next,
+		options: options,
+		jobs:    make(chan *job, options.Size),
+		flush:   make(chan struct{}),
+	}
+	q.workers.Add(options.Workers)
+	for range options.Workers {
+		go q.run()
+	}
+	return q
+}
+
+// Send queues msg. The context only matters for queueing: delivery goes on
+// after the request that sent the message is over.
+func (q *Queue) Send(_ context.Context, msg *Message) error {
+	if _, _, err := msg.addresses(); err != nil {
+		return err
+	}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	q.pending.Add(1)
+	select {
+	case q.jobs <- &job{msg: msg, attempt: 1, backoff: q.options.Backoff}:
+		return nil
+	default:
+		q.pending.Done()
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting messages and waits until the queued ones are
+// delivered or given up on, or ctx is done. Retries waiting for their
+// backoff are tried right away.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	first := !q.closed
+	q.closed = true
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		if first {
+			close(q.flush)
+			// Retries go back to jobs, so it's closed only once nothing
+			// is pending
+			q.pending.Wait()
+			close(q.jobs)
+		}
+		q.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) run() {
+	defer q.workers.Done()
+	for j := range q.jobs {
+		q.deliver(j)
+	}
+}
+
+// deliver tries j once; a temporary failure is retried later, unless it
+// was the last attempt
+func (q *Queue) deliver(j *job) {
+	ctx, cancel := context.WithTimeout(context.Background(), q.options.Timeout)
+	err := q.next.Send(ctx, j.msg)
+	cancel()
+
+	if err == nil {
+		q.pending.Done()
+		return
+	}
+	if IsPermanent(err) || j.attempt >= q.options.Attempts {
+		log.Printf("📧 Email para %v não entregue após %d tentativa(s): %v", j.msg.To, j.attempt, err)
+		q.pending.Done()
+		return
+	}
+
+	log.Printf("📧 Falha ao enviar email para %v (tentativa %d de %d), nova tentativa em %s: %v",
+		j.msg.To, j.attempt, q.options.Attempts, j.backoff, err)
+	go q.retry(j)
+}
+
+// retry puts j back in the queue after its backoff, or at once when the
+// queue is closing. It may wait for room; the worker that failed j is
+// already free.
+func (q *Queue) retry(j *job) {
+	timer := time.NewTimer(j.backoff)
+	select {
+	case <-timer.C:
+	case <-q.flush:
+		timer.Stop()
+	}
+
+	j.attempt++
+	j.backoff *= 2
+	q.jobs <- j
+}
@@ -0,0 +1,143 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// TLSMode is how the SMTP connection is secured
+type TLSMode string
+
+const (
+	// TLSStartTLS upgrades a plain connection (usually port 587) and refuses
+	// to go on if the server can't
+	TLSStartTLS TLSMode = "starttls"
+
+	// TLSImplicit connects over TLS from the start (usually port 465)
+	TLSImplicit TLSMode = "tls"
+
+	// TLSNone sends in the clear; only for local test servers
+	TLSNone TLSMode = "none"
+)
+
+// defaultSMTPTimeout bounds a whole delivery when the context has no deadline
+const defaultSMTPTimeout = 30 * time.Second
+
+// SMTPMailer delivers through an SMTP server
+type SMTPMailer struct {
+	Host string
+	Port int
+	TLS  TLSMode
+
+	// Username and Password enable PLAIN authentication, which is only
+	// attempted over TLS
+	Username string
+	Password string
+}
+
+func NewSMTPMailer(host string, port int, mode TLSMode, username, password string) *SMTPMailer {
+	return &SMTPMailer{
+		Host:     host,
+		Port:     port,
+		TLS:      mode,
+		Username: username,
+		Password: password,
+	}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	from, to, err := msg.addresses()
+	if err != nil {
+		return err
+	}
+	data, err := Encode(msg)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultSMTPTimeout)
+		defer cancel()
+	}
+
+	conn, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	// net/smtp has no context support: the deadline covers the whole session
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if m.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("mailer: server doesn't support STARTTLS")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: m.Host}); err != nil {
+			return err
+		}
+	}
+
+	if m.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, address := range to {
+		if err := client.Rcpt(address.Address); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (m *SMTPMailer) dial(ctx context.Context) (net.Conn, error) {
+	address := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	switch m.TLS {
+	case TLSImplicit:
+		dialer := &tls.Dialer{Config: &tls.Config{ServerName: m.Host}}
+		return dialer.DialContext(ctx, "tcp", address)
+	case TLSStartTLS, TLSNone:
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "tcp", address)
+	default:
+		return nil, fmt.Errorf("mailer: unknown TLS mode %q", m.TLS)
+	}
+}
+
+// IsPermanent tells failures that retrying won't fix, such as a rejected
+// recipient (SMTP 5xx replies), from temporary ones
+func IsPermanent(err error) bool {
+	var reply *textproto.Error
+	return errors.As(err, &reply) && reply.Code >= 500
+}
@@ -0,0 +1,374 @@
+// Package search is a small in-memory search engine usable for any document
+// type. A Mapping describes how to read the searchable fields of a document;
+// the Engine handles lexical scoring, the local vector index, rank fusion
+// and filtering.
+package search
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Ranking modes
+const (
+	ModeLexical = "lexical"
+	ModeVector  = "vector"
+	ModeHybrid  = "hybrid"
+)
+
+const (
+	// rrfK is the reciprocal rank fusion constant from the original paper
+	rrfK = 60
+
+	// defaultMinScore is the minimum lexical relevance to count as a match
+	defaultMinScore = 0.1
+
+	// defaultMinSimilarity filters out vector neighbours that are mostly noise
+	defaultMinSimilarity = 0.15
+
+	// defaultSegmentSize is how many documents each goroutine scans
+	defaultSegmentSize = 256
+
+	// cancelCheckInterval is how many documents are scored between context checks
+	cancelCheckInterval = 32
+)
+
+// Field describes one searchable field of a document
+type Field[T any] struct {
+	Name string
+
+	// Text reads a single-valued field; Terms reads a multi-valued one
+	// (tags, labels). Set exactly one of them.
+	Text  func(T) string
+	Terms func(T) []string
+
+	// Phrase is added when the whole query appears in the field; only the
+	// best phrase match across fields counts
+	Phrase float64
+	// Word is added for every query word found in the field (per term for
+	// multi-valued fields)
+	Word float64
+	// Fuzzy scales the in-order character match ratio of the query
+	Fuzzy float64
+	// Embed is how many times the field is repeated in the vector text;
+	// zero means once and a negative value leaves the field out
+	Embed int
+}
+
+// Mapping tells the engine how to read a document type
+type Mapping[T any] struct {
+	ID     func(T) string
+	Fields []Field[T]
+
+	// Boost adds a static, query-independent score (e.g. popularity)
+	Boost func(T) float64
+
+	// MinScore and MinSimilarity override the default match thresholds
+	MinScore      float64
+	MinSimilarity float64
+
+	// SegmentSize is how many documents are scanned per goroutine
+	SegmentSize int
+}
+
+// Query is a single search request
+type Query[T any] struct {
+	Text string
+	Mode string
+
+	// Filter drops documents before scoring
+	Filter func(T) bool
+	// Boost adds a per-request score (e.g. personalization) to matches
+	Boost func(T) float64
+}
+
+// Hit is a scored document
+type Hit[T any] struct {
+	Doc   T
+	Score float64
+}
+
+// Result holds the ranked hits of a search
+type Result[T any] struct {
+	Hits []Hit[T]
+
+	// Partial is set when the context was cancelled or its deadline passed
+	// before every segment was scanned; Hits then covers only what was seen
+	Partial bool
+}
+
+// Engine indexes a set of documents of type T
+type Engine[T any] struct {
+	mapping Mapping[T]
+
+	mu      sync.RWMutex
+	docs    []T
+	vectors *VectorIndex
+}
+
+// New creates an engine over docs; the slice must not be modified afterwards
+func New[T any](mapping Mapping[T], docs []T) *Engine[T] {
+	if mapping.MinScore == 0 {
+		mapping.MinScore = defaultMinScore
+	}
+	if mapping.MinSimilarity == 0 {
+		mapping.MinSimilarity = defaultMinSimilarity
+	}
+	if mapping.SegmentSize <= 0 {
+		mapping.SegmentSize = defaultSegmentSize
+	}
+
+	return &Engine[T]{
+		mapping: mapping,
+		docs:    docs,
+	}
+}
+
+// Replace atomically swaps the indexed documents
+func (e *Engine[T]) Replace(docs []T) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.docs = docs
+	e.vectors = nil
+}
+
+// Docs returns the indexed documents; callers must not modify the slice
+func (e *Engine[T]) Docs() []T {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.docs
+}
+
+// Get returns a document by ID
+func (e *Engine[T]) Get(id string) (T, bool) {
+	for _, doc := range e.Docs() {
+		if e.mapping.ID(doc) == id {
+			return doc, true
+		}
+	}
+
+	var zero T
+	return zero, false
+}
+
+// Search returns matching documents ordered by score. An empty query
+// matches every document (after the filter) with a zero score. Segments of
+// the index are scanned in parallel and the scan stops early when ctx is
+// done, in which case the result is flagged Partial.
+func (e *Engine[T]) Search(ctx context.Context, q Query[T]) Result[T] {
+	useVectors := q.Text != "" && (q.Mode == ModeVector || q.Mode == ModeHybrid)
+	docs, vectors := e.snapshot(useVectors)
+
+	scan := segmentScan{
+		query:   prepareQuery(q.Text),
+		lexical: q.Text != "" && q.Mode != ModeVector,
+	}
+	if useVectors {
+		scan.vector = vectors.EmbedQuery(q.Text)
+	}
+
+	segments := e.scanSegments(ctx, docs, vectors, scan, q)
+
+	var (
+		lexical, vector []ranked
+		partial         bool
+	)
+	for _, segment := range segments {
+		lexical = append(lexical, segment.lexical...)
+		vector = append(vector, segment.vector...)
+		partial = partial || segment.partial
+	}
+
+	// Segments are merged in document order, so stable sorts keep ties in it
+	byScore := func(results []ranked) {
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].score > results[j].score
+		})
+	}
+	byScore(lexical)
+	byScore(vector)
+
+	var ranking []ranked
+	switch {
+	case q.Text == "":
+		ranking = lexical
+	case q.Mode == ModeVector:
+		ranking = vector
+	case q.Mode == ModeHybrid:
+		ranking = fuseRankings(lexical, vector)
+	default:
+		ranking = lexical
+	}
+
+	hits := make([]Hit[T], 0, len(ranking))
+	for _, r := range ranking {
+		hit := Hit[T]{Doc: docs[r.index], Score: r.score}
+		if q.Boost != nil && q.Text != "" {
+			hit.Score += q.Boost(hit.Doc)
+		}
+		hits = append(hits, hit)
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+
+	return Result[T]{Hits: hits, Partial: partial}
+}
+
+// segmentScan is the per-query state shared by every segment goroutine
+type segmentScan struct {
+	query   preparedQuery
+	lexical bool
+	vector  []float32
+}
+
+// segmentResult is what a single segment contributes to a search
+type segmentResult struct {
+	lexical []ranked
+	vector  []ranked
+	partial bool
+}
+
+// scanSegments scores every segment in its own goroutine, running at most
+// GOMAXPROCS of them at once
+func (e *Engine[T]) scanSegments(ctx context.Context, docs []T, vectors *VectorIndex, scan segmentScan, q Query[T]) []segmentResult {
+	size := e.mapping.SegmentSize
+	results := make([]segmentResult, (len(docs)+size-1)/size)
+
+	slots := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+
+	for i := range results {
+		start := i * size
+		end := min(start+size, len(docs))
+
+		wg.Add(1)
+		go func(result *segmentResult) {
+			defer wg.Done()
+
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				result.partial = true
+				return
+			}
+
+			*result = e.scanSegment(ctx, docs, vectors, scan, q, start, end)
+		}(&results[i])
+	}
+
+	wg.Wait()
+	return results
+}
+
+// scanSegment scores docs[start:end], checking ctx every few documents
+func (e *Engine[T]) scanSegment(ctx context.Context, docs []T, vectors *VectorIndex, scan segmentScan, q Query[T], start, end int) segmentResult {
+	var result segmentResult
+
+	for i := start; i < end; i++ {
+		if (i-start)%cancelCheckInterval == 0 && ctx.Err() != nil {
+			result.partial = true
+			return result
+		}
+
+		doc := docs[i]
+		if q.Filter != nil && !q.Filter(doc) {
+			continue
+		}
+
+		if q.Text == "" {
+			result.lexical = append(result.lexical, ranked{index: i})
+			continue
+		}
+
+		if scan.lexical {
+			if score := e.score(doc, scan.query); score > e.mapping.MinScore {
+				result.lexical = append(result.lexical, ranked{index: i, score: score})
+			}
+		}
+
+		if scan.vector != nil {
+			if similarity := vectors.Similarity(scan.vector, i); similarity > e.mapping.MinSimilarity {
+				result.vector = append(result.vector, ranked{index: i, score: similarity})
+			}
+		}
+	}
+
+	return result
+}
+
+// snapshot returns the documents and, when needed, their vector index,
+// building it lazily the first time it is asked for
+func (e *Engine[T]) snapshot(needVectors bool) ([]T, *VectorIndex) {
+	e.mu.RLock()
+	docs, vectors := e.docs, e.vectors
+	e.mu.RUnlock()
+
+	if !needVectors || vectors != nil {
+		return docs, vectors
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.vectors == nil {
+		texts := make([]string, len(e.docs))
+		for i, doc := range e.docs {
+			texts[i] = e.embedText(doc)
+		}
+		e.vectors = NewVectorIndex(texts)
+	}
+	return e.docs, e.vectors
+}
+
+// ranked is a position in the document slice with its score
+type ranked struct {
+	index int
+	score float64
+}
+
+// fuseRankings merges ranked lists with reciprocal rank fusion, scaled so
+// a document ranked first in every list scores 1
+func fuseRankings(rankings ...[]ranked) []ranked {
+	fused := make(map[int]float64)
+	var order []int
+
+	for _, ranking := range rankings {
+		for rank, r := range ranking {
+			if _, exists := fused[r.index]; !exists {
+				order = append(order, r.index)
+			}
+			fused[r.index] += 1.0 / float64(rrfK+rank+1)
+		}
+	}
+
+	best := float64(len(rankings)) / float64(rrfK+1)
+
+	results := make([]ranked, 0, len(order))
+	for _, index := range order {
+		results = append(results, ranked{index: index, score: fused[index] / best})
+	}
+	return results
+}
+
+// Paginate returns the [offset, offset+limit) window of items
+func Paginate[T any](items []T, offset, limit int) []T {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(items) {
+		return []T{}
+	}
+
+	end := offset + limit
+	if limit <= 0 || end > len(items) {
+		end = len(items)
+	}
+	return items[offset:end]
+}
@@ -0,0 +1,115 @@
+package search
+
+import (
+	"strings"
+)
+
+// preparedQuery is a query folded like the fields it's compared with (see
+// Fold) and split into words
+type preparedQuery struct {
+	text  string
+	words []string
+}
+
+func prepareQuery(text string) preparedQuery {
+	text = Fold(text)
+	return preparedQuery{
+		text:  text,
+		words: strings.Fields(text),
+	}
+}
+
+// score computes the lexical relevance of a document: the best whole-phrase
+// match, per-word matches in every field, fuzzy matches and the static boost
+func (e *Engine[T]) score(doc T, query preparedQuery) float64 {
+	score := 0.0
+	bestPhrase := 0.0
+
+	for _, field := range e.mapping.Fields {
+		if field.Terms != nil {
+			for _, term := range field.Terms(doc) {
+				term = Fold(term)
+				if field.Phrase > bestPhrase && strings.Contains(term, query.text) {
+					bestPhrase = field.Phrase
+				}
+				for _, word := range query.words {
+					if strings.Contains(term, word) {
+						score += field.Word
+					}
+				}
+			}
+			continue
+		}
+
+		text := Fold(field.Text(doc))
+
+		// Exact match gets highest score
+		if field.Phrase > bestPhrase && strings.Contains(text, query.text) {
+			bestPhrase = field.Phrase
+		}
+
+		// Partial word matches
+		for _, word := range query.words {
+			if strings.Contains(text, word) {
+				score += field.Word
+			}
+		}
+
+		if field.Fuzzy > 0 {
+			score += fuzzyMatch(text, query.text) * field.Fuzzy
+		}
+	}
+
+	score += bestPhrase
+
+	if e.mapping.Boost != nil {
+		score += e.mapping.Boost(doc)
+	}
+
+	return score
+}
+
+// fuzzyMatch returns the share of query characters found in order in text
+func fuzzyMatch(text, query string) float64 {
+	if len(query) == 0 {
+		return 0
+	}
+
+	matches := 0
+	queryIndex := 0
+
+	for i := 0; i < len(text) && queryIndex < len(query); i++ {
+		if text[i] == query[queryIndex] {
+			matches++
+			queryIndex++
+		}
+	}
+
+	return float64(matches) / float64(len(query))
+}
+
+// embedText is the text fed to the vector index for a document
+func (e *Engine[T]) embedText(doc T) string {
+	var parts []string
+	for _, field := range e.mapping.Fields {
+		repeat := field.Embed
+		if repeat < 0 {
+			continue
+		}
+		if repeat == 0 {
+			repeat = 1
+		}
+
+		var text string
+		if field.Terms != nil {
+			text = strings.Join(field.Terms(doc), " ")
+		} else {
+			text = field.Text(doc)
+		}
+
+		for i := 0; i < repeat; i++ {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
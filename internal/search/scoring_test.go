@@ -0,0 +1,39 @@
+package search
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLexicalSearchFoldsAccents(t *testing.T) {
+	engine := snapshotEngine(snapshotDocs...)
+	engine.mapping.Fields = append(engine.mapping.Fields, Field[snapshotDoc]{
+		Name: "tags",
+		Terms: func(d snapshotDoc) []string {
+			if d.ID == "3" {
+				return []string{"Coração", "Mídia"}
+			}
+			return nil
+		},
+		Word: 0.5,
+	})
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"validacao", "1"},
+		{"VALIDAÇÃO", "1"},
+		{"validação de formularios", "1"},
+		{"formulários", "1"},
+		{"coracao", "3"},
+		{"MIDIA", "3"},
+	}
+
+	for _, tt := range tests {
+		result := engine.Search(context.Background(), Query[snapshotDoc]{Text: tt.query, Mode: ModeLexical})
+		if len(result.Hits) == 0 || result.Hits[0].Doc.ID != tt.want {
+			t.Errorf("Search(%q) = %+v, want %s first", tt.query, result.Hits, tt.want)
+		}
+	}
+}
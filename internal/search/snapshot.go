@@ -0,0 +1,219 @@
+package search
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// Snapshot layout (little endian):
+//
+//	magic    [8]byte  "SRCHSNAP"
+//	version  uint32   snapshotVersion
+//	source   [32]byte fingerprint of the documents the index was built from
+//	docs     uint64   number of indexed documents
+//	length   uint64   payload size in bytes
+//	payload  []byte   vector index (see encodeVectorIndex)
+//	checksum uint32   CRC-32C of everything above
+const snapshotVersion = 1
+
+var snapshotMagic = [8]byte{'S', 'R', 'C', 'H', 'S', 'N', 'A', 'P'}
+
+// maxSnapshotPayload is the largest payload length taken as plausible.
+// Shorter ones aren't trusted either: the payload is buffered as it arrives,
+// so a truncated snapshot fails at its end instead of allocating what its
+// header claims.
+const maxSnapshotPayload = 1 << 32
+
+var (
+	// ErrSnapshotCorrupt means the snapshot is truncated or fails its checksum
+	ErrSnapshotCorrupt = errors.New("search: corrupt index snapshot")
+
+	// ErrSnapshotStale means the snapshot is valid but was built by another
+	// format version or from different documents
+	ErrSnapshotStale = errors.New("search: stale index snapshot")
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type snapshotHeader struct {
+	Magic   [8]byte
+	Version uint32
+	Source  [sha256.Size]byte
+	Docs    uint64
+	Length  uint64
+}
+
+// Build computes the vector index now instead of on the first vector query
+func (e *Engine[T]) Build() {
+	e.snapshot(true)
+}
+
+// WriteSnapshot builds the index if needed and writes it to w. source
+// identifies the documents (e.g. a hash of the catalog) and must be passed
+// back to ReadSnapshot.
+func (e *Engine[T]) WriteSnapshot(w io.Writer, source [sha256.Size]byte) error {
+	docs, vectors := e.snapshot(true)
+
+	var payload bytes.Buffer
+	if err := encodeVectorIndex(&payload, vectors); err != nil {
+		return err
+	}
+
+	header := snapshotHeader{
+		Magic:   snapshotMagic,
+		Version: snapshotVersion,
+		Source:  source,
+		Docs:    uint64(len(docs)),
+		Length:  uint64(payload.Len()),
+	}
+
+	crc := crc32.New(crcTable)
+	out := io.MultiWriter(w, crc)
+
+	if err := binary.Write(out, binary.LittleEndian, header); err != nil {
+		return err
+	}
+	if _, err := out.Write(payload.Bytes()); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, crc.Sum32())
+}
+
+// ReadSnapshot restores the index written by WriteSnapshot for the current
+// documents. It returns ErrSnapshotStale when source or the document count
+// differ and ErrSnapshotCorrupt when the data cannot be trusted; the engine
+// is left untouched on error.
+func (e *Engine[T]) ReadSnapshot(r io.Reader, source [sha256.Size]byte) error {
+	crc := crc32.New(crcTable)
+	in := io.TeeReader(r, crc)
+
+	var header snapshotHeader
+	if err := binary.Read(in, binary.LittleEndian, &header); err != nil {
+		return fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	if header.Magic != snapshotMagic || header.Length > maxSnapshotPayload {
+		return ErrSnapshotCorrupt
+	}
+	if header.Version != snapshotVersion || header.Source != source {
+		return ErrSnapshotStale
+	}
+
+	var payload bytes.Buffer
+	if n, err := io.CopyN(&payload, in, int64(header.Length)); err != nil {
+		return fmt.Errorf("%w: payload has %d of %d bytes: %v", ErrSnapshotCorrupt, n, header.Length, err)
+	}
+
+	var checksum uint32
+	if err := binary.Read(r, binary.LittleEndian, &checksum); err != nil {
+		return fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	if checksum != crc.Sum32() {
+		return fmt.Errorf("%w: checksum mismatch", ErrSnapshotCorrupt)
+	}
+
+	vectors, err := decodeVectorIndex(bytes.NewReader(payload.Bytes()))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if uint64(len(e.docs)) != header.Docs || len(vectors.vectors) != len(e.docs) {
+		return ErrSnapshotStale
+	}
+	e.vectors = vectors
+	return nil
+}
+
+// encodeVectorIndex writes the IDF table followed by the dense vectors
+func encodeVectorIndex(w io.Writer, idx *VectorIndex) error {
+	le := binary.LittleEndian
+
+	if err := binary.Write(w, le, uint32(len(idx.idf))); err != nil {
+		return err
+	}
+	for feature, idf := range idx.idf {
+		if err := binary.Write(w, le, uint32(len(feature))); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, feature); err != nil {
+			return err
+		}
+		if err := binary.Write(w, le, idf); err != nil {
+			return err
+		}
+	}
+
+	if err := binary.Write(w, le, [2]uint32{uint32(len(idx.vectors)), vectorDims}); err != nil {
+		return err
+	}
+	for _, vector := range idx.vectors {
+		if err := binary.Write(w, le, vector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeVectorIndex is the inverse of encodeVectorIndex
+func decodeVectorIndex(r *bytes.Reader) (*VectorIndex, error) {
+	le := binary.LittleEndian
+
+	var features uint32
+	if err := binary.Read(r, le, &features); err != nil {
+		return nil, err
+	}
+	if int64(features) > int64(r.Len()) {
+		return nil, errors.New("feature count exceeds payload")
+	}
+
+	idx := &VectorIndex{idf: make(map[string]float64, features)}
+	for i := uint32(0); i < features; i++ {
+		var size uint32
+		if err := binary.Read(r, le, &size); err != nil {
+			return nil, err
+		}
+		if int64(size) > int64(r.Len()) {
+			return nil, errors.New("feature name exceeds payload")
+		}
+		name := make([]byte, size)
+		if _, err := io.ReadFull(r, name); err != nil {
+			return nil, err
+		}
+		var idf float64
+		if err := binary.Read(r, le, &idf); err != nil {
+			return nil, err
+		}
+		if math.IsNaN(idf) || math.IsInf(idf, 0) {
+			return nil, errors.New("invalid idf weight")
+		}
+		idx.idf[string(name)] = idf
+	}
+
+	var shape [2]uint32
+	if err := binary.Read(r, le, &shape); err != nil {
+		return nil, err
+	}
+	if shape[1] != vectorDims {
+		return nil, fmt.Errorf("vector size %d, want %d", shape[1], vectorDims)
+	}
+	if int64(shape[0])*vectorDims*4 != int64(r.Len()) {
+		return nil, errors.New("vector data does not match payload size")
+	}
+
+	idx.vectors = make([][]float32, shape[0])
+	for i := range idx.vectors {
+		idx.vectors[i] = make([]float32, vectorDims)
+		if err := binary.Read(r, le, idx.vectors[i]); err != nil {
+			return nil, err
+		}
+	}
+	return idx, nil
+}
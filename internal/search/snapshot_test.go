@@ -0,0 +1,169 @@
+package search
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+type snapshotDoc struct {
+	ID, Title string
+}
+
+func snapshotEngine(docs ...snapshotDoc) *Engine[snapshotDoc] {
+	return New(Mapping[snapshotDoc]{
+		ID: func(d snapshotDoc) string { return d.ID },
+		Fields: []Field[snapshotDoc]{
+			{Name: "title", Text: func(d snapshotDoc) string { return d.Title }, Word: 1},
+		},
+	}, docs)
+}
+
+var snapshotDocs = []snapshotDoc{
+	{"1", "Validação de formulários"},
+	{"2", "Busca em tempo real"},
+	{"3", "Upload de arquivos"},
+}
+
+var snapshotSource = sha256.Sum256([]byte("docs-v1"))
+
+// headerSize is the encoded size of snapshotHeader
+const headerSize = 8 + 4 + sha256.Size + 8 + 8
+
+func writeTestSnapshot(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := snapshotEngine(snapshotDocs...).WriteSnapshot(&buf, snapshotSource); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	source := snapshotEngine(snapshotDocs...)
+	var buf bytes.Buffer
+	if err := source.WriteSnapshot(&buf, snapshotSource); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := snapshotEngine(snapshotDocs...)
+	if err := restored.ReadSnapshot(&buf, snapshotSource); err != nil {
+		t.Fatalf("ReadSnapshot: %v", err)
+	}
+	if !reflect.DeepEqual(restored.vectors, source.vectors) {
+		t.Error("restored vector index differs from the one written")
+	}
+}
+
+func TestSnapshotRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(data []byte) []byte
+		source [sha256.Size]byte
+		docs   []snapshotDoc
+		want   error
+	}{
+		{
+			name:   "bad magic",
+			damage: func(data []byte) []byte { data[0] = 'X'; return data },
+			want:   ErrSnapshotCorrupt,
+		},
+		{
+			name: "other format version",
+			damage: func(data []byte) []byte {
+				binary.LittleEndian.PutUint32(data[8:], snapshotVersion+1)
+				return data
+			},
+			want: ErrSnapshotStale,
+		},
+		{
+			name:   "built from other documents",
+			source: sha256.Sum256([]byte("docs-v2")),
+			want:   ErrSnapshotStale,
+		},
+		{
+			name: "document count differs",
+			docs: snapshotDocs[:2],
+			want: ErrSnapshotStale,
+		},
+		{
+			name:   "payload bit flipped",
+			damage: func(data []byte) []byte { data[headerSize+4] ^= 0x01; return data },
+			want:   ErrSnapshotCorrupt,
+		},
+		{
+			name:   "checksum mismatch",
+			damage: func(data []byte) []byte { data[len(data)-1] ^= 0xFF; return data },
+			want:   ErrSnapshotCorrupt,
+		},
+		{
+			name:   "truncated payload",
+			damage: func(data []byte) []byte { return data[:headerSize+10] },
+			want:   ErrSnapshotCorrupt,
+		},
+		{
+			name:   "missing checksum",
+			damage: func(data []byte) []byte { return data[:len(data)-4] },
+			want:   ErrSnapshotCorrupt,
+		},
+		{
+			name:   "truncated header",
+			damage: func(data []byte) []byte { return data[:20] },
+			want:   ErrSnapshotCorrupt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := writeTestSnapshot(t)
+			if tt.damage != nil {
+				data = tt.damage(data)
+			}
+			source := snapshotSource
+			if tt.source != ([sha256.Size]byte{}) {
+				source = tt.source
+			}
+			docs := snapshotDocs
+			if tt.docs != nil {
+				docs = tt.docs
+			}
+
+			engine := snapshotEngine(docs...)
+			err := engine.ReadSnapshot(bytes.NewReader(data), source)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("ReadSnapshot = %v, want %v", err, tt.want)
+			}
+			if engine.vectors != nil {
+				t.Error("a rejected snapshot changed the engine")
+			}
+		})
+	}
+}
+
+func TestSnapshotHugeLengthDoesNotAllocate(t *testing.T) {
+	data := writeTestSnapshot(t)
+	// Claim the largest payload accepted, then end the file right there
+	binary.LittleEndian.PutUint64(data[headerSize-8:], maxSnapshotPayload)
+	data = data[:headerSize+16]
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	err := snapshotEngine(snapshotDocs...).ReadSnapshot(bytes.NewReader(data), snapshotSource)
+	runtime.ReadMemStats(&after)
+
+	if !errors.Is(err, ErrSnapshotCorrupt) {
+		t.Fatalf("ReadSnapshot = %v, want ErrSnapshotCorrupt", err)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("reading a truncated snapshot allocated %d bytes, want it bounded by the data present", allocated)
+	}
+
+	binary.LittleEndian.PutUint64(data[headerSize-8:], maxSnapshotPayload+1)
+	if err := snapshotEngine(snapshotDocs...).ReadSnapshot(bytes.NewReader(data), snapshotSource); !errors.Is(err, ErrSnapshotCorrupt) {
+		t.Errorf("ReadSnapshot with a length past the maximum = %v, want ErrSnapshotCorrupt", err)
+	}
+}
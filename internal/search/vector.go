@@ -0,0 +1,205 @@
+package search
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// vectorDims is the size of the hashed feature space
+	vectorDims = 1024
+
+	// charNGram is the length of the character n-grams used as sub-word features
+	charNGram = 3
+
+	// charNGramWeight scales sub-word features relative to whole words
+	charNGramWeight = 0.5
+)
+
+// portugueseStopwords are skipped when building vectors
+var portugueseStopwords = map[string]bool{
+	"a": true, "o": true, "e": true, "de": true, "da": true, "do": true, "das": true, "dos": true,
+	"em": true, "um": true, "uma": true, "para": true, "com": true, "sem": true, "que": true,
+	"os": true, "as": true, "por": true, "no": true, "na": true, "se": true, "the": true, "and": true,
+}
+
+// VectorIndex is a locally computed embedding index: TF-IDF weighted word
+// and character n-gram features hashed into a fixed number of dimensions
+type VectorIndex struct {
+	idf     map[string]float64
+	vectors [][]float32
+}
+
+// VectorHit is a nearest-neighbour match; Index points into the indexed texts
+type VectorHit struct {
+	Index      int
+	Similarity float64
+}
+
+// NewVectorIndex builds one vector per text
+func NewVectorIndex(texts []string) *VectorIndex {
+	docs := make([]map[string]float64, len(texts))
+	docFreq := make(map[string]int)
+
+	for i, text := range texts {
+		docs[i] = textFeatures(text)
+		for feature := range docs[i] {
+			docFreq[feature]++
+		}
+	}
+
+	idx := &VectorIndex{
+		idf:     make(map[string]float64, len(docFreq)),
+		vectors: make([][]float32, len(texts)),
+	}
+
+	n := float64(len(texts))
+	for feature, df := range docFreq {
+		idx.idf[feature] = math.Log((n+1)/(float64(df)+1)) + 1
+	}
+
+	for i, features := range docs {
+		idx.vectors[i] = idx.embed(features)
+	}
+
+	return idx
+}
+
+// Nearest returns up to k items most similar to text, above minSimilarity
+func (idx *VectorIndex) Nearest(text string, k int, minSimilarity float64) []VectorHit {
+	query := idx.EmbedQuery(text)
+
+	var hits []VectorHit
+	for i := range idx.vectors {
+		similarity := idx.Similarity(query, i)
+		if similarity > minSimilarity {
+			hits = append(hits, VectorHit{Index: i, Similarity: similarity})
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		return hits[i].Similarity > hits[j].Similarity
+	})
+
+	if k > 0 && len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+// EmbedQuery computes the vector of a query text in this index's space
+func (idx *VectorIndex) EmbedQuery(text string) []float32 {
+	return idx.embed(textFeatures(text))
+}
+
+// Similarity is the cosine similarity between a query vector and item i
+func (idx *VectorIndex) Similarity(query []float32, i int) float64 {
+	return cosine(query, idx.vectors[i])
+}
+
+// embed hashes weighted features into a unit-length dense vector; features
+// never seen while building the index carry no weight
+func (idx *VectorIndex) embed(features map[string]float64) []float32 {
+	vector := make([]float32, vectorDims)
+
+	for feature, tf := range features {
+		idf, known := idx.idf[feature]
+		if !known {
+			continue
+		}
+
+		h := fnv.New32a()
+		h.Write([]byte(feature))
+		sum := h.Sum32()
+
+		// The top bit picks a sign so collisions tend to cancel out
+		weight := float32((1 + math.Log(tf)) * idf)
+		if sum&(1<<31) != 0 {
+			weight = -weight
+		}
+		vector[sum%vectorDims] += weight
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+
+	return vector
+}
+
+// cosine assumes both vectors are already normalized
+func cosine(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+// textFeatures extracts term frequencies for words and character n-grams
+func textFeatures(text string) map[string]float64 {
+	features := make(map[string]float64)
+
+	for _, word := range tokenize(text) {
+		features["w:"+word]++
+
+		padded := []rune("#" + word + "#")
+		for i := 0; i+charNGram <= len(padded); i++ {
+			features["c:"+string(padded[i:i+charNGram])] += charNGramWeight
+		}
+	}
+
+	// Keep tf >= 1 so the log weighting stays positive
+	for feature, tf := range features {
+		if tf < 1 {
+			features[feature] = 1
+		}
+	}
+
+	return features
+}
+
+// tokenize lowercases, folds accents and splits on anything but letters and digits
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(foldAccents(strings.ToLower(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	words := fields[:0]
+	for _, field := range fields {
+		if len(field) > 1 && !portugueseStopwords[field] {
+			words = append(words, field)
+		}
+	}
+	return words
+}
+
+var accentFolder = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "ê", "e", "è", "e", "ë", "e",
+	"í", "i", "î", "i", "ì", "i", "ï", "i",
+	"ó", "o", "ô", "o", "õ", "o", "ò", "o", "ö", "o",
+	"ú", "u", "û", "u", "ù", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+)
+
+// Fold lowercases text and strips its accents, the way the engine compares
+// words, for filters that should match like searches do
+func Fold(text string) string {
+	return foldAccents(strings.ToLower(text))
+}
+
+// foldAccents strips the diacritics common in Portuguese text
+func foldAccents(text string) string {
+	return accentFolder.Replace(text)
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"showcase-datastar-go/internal/validation"
+)
+
+var (
+	// houseNumberRegex accepts 123, 123A, 123-B, S/N (sem número) and
+	// highway kilometers such as km 12,5
+	houseNumberRegex = regexp.MustCompile(`(?i)^(\d{1,6}(-?[a-z])?|s/?n|km ?\d{1,4}(,\d{1,3})?)$`)
+
+	// complementRegex allows what usually goes in a complement: "Apto 12",
+	// "Bloco B, sala 3", "Casa 2 - fundos", "2º andar"
+	complementRegex = regexp.MustCompile(`^[\p{L}\p{N} .,'/#ºª°-]+$`)
+)
+
+// AddressForm is a Brazilian address; its JSON names are the address.*
+// signals of the forms page
+type AddressForm struct {
+	CEP          string `json:"cep" validate:"required,pattern=^[0-9]{5}-?[0-9]{3}$" label:"CEP" msg:"pattern=CEP deve ter 8 dígitos"`
+	Street       string `json:"street" validate:"required,max=120" label:"Logradouro"`
+	Number       string `json:"number" validate:"required,max=12,housenumber" label:"Número" msg:"housenumber=Número inválido (ex.: 123, 123A ou S/N)"`
+	Complement   string `json:"complement" validate:"max=60,complement" label:"Complemento" msg:"complement=Complemento contém caracteres inválidos"`
+	Neighborhood string `json:"neighborhood" validate:"required,max=80" label:"Bairro"`
+	City         string `json:"city" validate:"required,max=80" label:"Cidade" msg:"required=Cidade é obrigatória|valid=Cidade válida"`
+	UF           string `json:"uf" validate:"required,oneof=AC AL AM AP BA CE DF ES GO MA MG MS MT PA PB PE PI PR RJ RN RO RR RS SC SE SP TO" label:"UF" msg:"required=UF é obrigatória|oneof=UF inválida|valid=UF válida"`
+}
+
+// newAddress turns a CEP lookup into an address to autofill the form
+func newAddress(cep string, data *CEPResponse) *AddressForm {
+	return &AddressForm{
+		CEP:          maskCEP(cep),
+		Street:       data.Logradouro,
+		Neighborhood: data.Bairro,
+		City:         data.Localidade,
+		UF:           data.UF,
+	}
+}
+
+// String formats the address on a single line
+func (a AddressForm) String() string {
+	line := strings.TrimSpace(a.Street) + ", " + strings.TrimSpace(a.Number)
+	if complement := strings.TrimSpace(a.Complement); complement != "" {
+		line += " - " + complement
+	}
+	return fmt.Sprintf("%s - %s, %s - %s, %s", line,
+		strings.TrimSpace(a.Neighborhood), strings.TrimSpace(a.City),
+		strings.ToUpper(strings.TrimSpace(a.UF)), strings.TrimSpace(a.CEP))
+}
+
+// ValidateAddressField validates a single address form field against its rules
+func (fs *FormsService) ValidateAddressField(field, value string) *ValidationResult {
+	if field == "uf" {
+		value = strings.ToUpper(value)
+	}
+	return fieldResult(fs.addressRules, field, value)
+}
+
+// SubmitAddress validates a whole address, including that the CEP exists and
+// belongs to the informed UF. If the CEP can't be checked right now the
+// address is accepted on its own rules.
+func (fs *FormsService) SubmitAddress(ctx context.Context, form AddressForm) (*ValidationResult, validation.Errors) {
+	form.UF = strings.ToUpper(strings.TrimSpace(form.UF))
+
+	errs := fs.addressRules.Validate(form)
+	if _, failed := errs["cep"]; !failed {
+		result, address, err := fs.lookupCEP(ctx, form.CEP)
+		switch {
+		case err != nil:
+		case !result.Valid:
+			errs["cep"] = result.Message
+		case address.UF != "" && address.UF != form.UF:
+			if _, failed := errs["uf"]; !failed {
+				errs["uf"] = "UF não corresponde ao CEP"
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return &ValidationResult{
+			Valid:   false,
+			Message: fs.addressRules.First(errs),
+		}, errs
+	}
+
+	form.CEP = maskCEP(normalizeCEP(form.CEP))
+	return &ValidationResult{
+		Valid:   true,
+		Message: "Endereço válido",
+		Data:    form.String(),
+	}, nil
+}
+
+// maskCEP formats an 8-digit CEP as 00000-000
+func maskCEP(cep string) string {
+	if len(cep) != 8 {
+		return cep
+	}
+	return cep[:5] + "-" + cep[5:]
+}
@@ -0,0 +1,372 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"showcase-datastar-go/internal/search"
+)
+
+// Admin table page sizes
+const (
+	DefaultTablePageSize = 20
+	MaxTablePageSize     = 100
+)
+
+// Sort columns shared by the admin tables; each table accepts its own subset
+const (
+	SortCreatedAt   = "createdAt"
+	SortName        = "name"
+	SortEmail       = "email"
+	SortSubject     = "subject"
+	SortStatus      = "status"
+	SortConfirmedAt = "confirmedAt"
+)
+
+// Status filters of the contact messages table
+const (
+	MessagesRead   = "read"
+	MessagesUnread = "unread"
+)
+
+// TableQuery is what an admin table shows: a text filter, a range of
+// creation dates, a status, the sort and the page
+type TableQuery struct {
+	Text string
+
+	// From is inclusive and To exclusive; zero leaves the range open
+	From time.Time
+	To   time.Time
+
+	Status   string
+	Sort     string
+	Desc     bool
+	Page     int
+	PageSize int
+}
+
+// tableDate is how TableParams writes dates, as date inputs do
+const tableDate = "2006-01-02"
+
+// TableParams is a TableQuery as the page store and links carry it: dates
+// are days, To included, and the sort direction is "asc" or "desc"
+type TableParams struct {
+	Q      string `json:"q" form:"q"`
+	From   string `json:"from" form:"from"`
+	To     string `json:"to" form:"to"`
+	Status string `json:"status" form:"status"`
+	Sort   string `json:"sort" form:"sort"`
+	Dir    string `json:"dir" form:"dir"`
+	Page   int    `json:"page" form:"page"`
+	Size   int    `json:"size" form:"size"`
+}
+
+// Query converts the params; dates that don't parse are left open
+func (p TableParams) Query() TableQuery {
+	q := TableQuery{
+		Text:     p.Q,
+		Status:   p.Status,
+		Sort:     p.Sort,
+		Desc:     p.Dir != "asc",
+		Page:     p.Page,
+		PageSize: p.Size,
+	}
+	if from, err := time.ParseInLocation(tableDate, p.From, time.Local); err == nil {
+		q.From = from
+	}
+	if to, err := time.ParseInLocation(tableDate, p.To, time.Local); err == nil {
+		q.To = to.AddDate(0, 0, 1)
+	}
+	return q
+}
+
+// Values encodes the params for a link, leaving out empty ones
+func (p TableParams) Values() url.Values {
+	values := url.Values{}
+	for _, param := range [][2]string{
+		{"q", p.Q}, {"from", p.From}, {"to", p.To}, {"status", p.Status},
+		{"sort", p.Sort}, {"dir", p.Dir},
+	} {
+		if param[1] != "" {
+			values.Set(param[0], param[1])
+		}
+	}
+	if p.Page > 0 {
+		values.Set("page", strconv.Itoa(p.Page))
+	}
+	if p.Size > 0 {
+		values.Set("size", strconv.Itoa(p.Size))
+	}
+	return values
+}
+
+// Params converts the query back, for the page store and links
+func (q TableQuery) Params() TableParams {
+	p := TableParams{
+		Q:      q.Text,
+		Status: q.Status,
+		Sort:   q.Sort,
+		Dir:    "asc",
+		Page:   q.Page,
+		Size:   q.PageSize,
+	}
+	if q.Desc {
+		p.Dir = "desc"
+	}
+	if !q.From.IsZero() {
+		p.From = q.From.Format(tableDate)
+	}
+	if !q.To.IsZero() {
+		p.To = q.To.AddDate(0, 0, -1).Format(tableDate)
+	}
+	return p
+}
+
+// TablePage is one page of a filtered and sorted admin table. Query is the
+// query as applied, with unknown sorts and out-of-range pages corrected.
+type TablePage[T any] struct {
+	Rows     []T        `json:"rows"`
+	Total    int        `json:"total"`
+	Page     int        `json:"page"`
+	PageSize int        `json:"pageSize"`
+	Pages    int        `json:"pages"`
+	Query    TableQuery `json:"-"`
+}
+
+// tableSpec describes how a table filters and sorts its rows
+type tableSpec[T any] struct {
+	text    func(T) []string
+	created func(T) time.Time
+	status  func(T) string
+	sorts   map[string]func(a, b T) int
+
+	// statuses are the values the status filter accepts
+	statuses []string
+}
+
+// normalize corrects the parts of q the table can't apply
+func (spec tableSpec[T]) normalize(q TableQuery) TableQuery {
+	if _, ok := spec.sorts[q.Sort]; !ok {
+		q.Sort, q.Desc = SortCreatedAt, true
+	}
+	if !slices.Contains(spec.statuses, q.Status) {
+		q.Status = ""
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = DefaultTablePageSize
+	}
+	q.PageSize = min(q.PageSize, MaxTablePageSize)
+	q.Page = max(q.Page, 1)
+	q.Text = strings.TrimSpace(q.Text)
+	return q
+}
+
+// rows filters and sorts items; ties keep the newest first
+func (spec tableSpec[T]) rows(items []T, q TableQuery) []T {
+	words := strings.Fields(search.Fold(q.Text))
+
+	rows := make([]T, 0, len(items))
+	for _, item := range items {
+		created := spec.created(item)
+		if !q.From.IsZero() && created.Before(q.From) {
+			continue
+		}
+		if !q.To.IsZero() && !created.Before(q.To) {
+			continue
+		}
+		if q.Status != "" && spec.status(item) != q.Status {
+			continue
+		}
+		if !matchesWords(spec.text(item), words) {
+			continue
+		}
+		rows = append(rows, item)
+	}
+
+	compare := spec.sorts[q.Sort]
+	slices.SortStableFunc(rows, func(a, b T) int {
+		c := compare(a, b)
+		if q.Desc {
+			c = -c
+		}
+		if c == 0 {
+			c = spec.created(b).Compare(spec.created(a))
+		}
+		return c
+	})
+	return rows
+}
+
+// page cuts the page q asks for out of rows, moving to the last page when
+// q is past it
+func (spec tableSpec[T]) page(rows []T, q TableQuery) TablePage[T] {
+	pages := max((len(rows)+q.PageSize-1)/q.PageSize, 1)
+	q.Page = min(q.Page, pages)
+	return TablePage[T]{
+		Rows:     search.Paginate(rows, (q.Page-1)*q.PageSize, q.PageSize),
+		Total:    len(rows),
+		Page:     q.Page,
+		PageSize: q.PageSize,
+		Pages:    pages,
+		Query:    q,
+	}
+}
+
+// matchesWords tells whether every word appears in one of fields, ignoring
+// case and accents
+func matchesWords(fields []string, words []string) bool {
+	if len(words) == 0 {
+		return true
+	}
+	text := search.Fold(strings.Join(fields, " "))
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func contactMessagesTable() tableSpec[ContactMessage] {
+	return tableSpec[ContactMessage]{
+		text: func(m ContactMessage) []string {
+			return []string{m.Name, m.Email, m.Subject, m.Message}
+		},
+		created: func(m ContactMessage) time.Time { return m.CreatedAt },
+		status: func(m ContactMessage) string {
+			if m.Read {
+				return MessagesRead
+			}
+			return MessagesUnread
+		},
+		sorts: map[string]func(a, b ContactMessage) int{
+			SortCreatedAt: func(a, b ContactMessage) int { return a.CreatedAt.Compare(b.CreatedAt) },
+			SortName:      func(a, b ContactMessage) int { return compareText(a.Name, b.Name) },
+			SortEmail:     func(a, b ContactMessage) int { return compareText(a.Email, b.Email) },
+			SortSubject:   func(a, b ContactMessage) int { return compareText(a.Subject, b.Subject) },
+		},
+		statuses: []string{MessagesRead, MessagesUnread},
+	}
+}
+
+func subscribersTable() tableSpec[NewsletterSubscriber] {
+	return tableSpec[NewsletterSubscriber]{
+		text: func(s NewsletterSubscriber) []string {
+			return []string{s.Name, s.Email}
+		},
+		created: func(s NewsletterSubscriber) time.Time { return s.CreatedAt },
+		status:  func(s NewsletterSubscriber) string { return string(s.Status) },
+		sorts: map[string]func(a, b NewsletterSubscriber) int{
+			SortCreatedAt: func(a, b NewsletterSubscriber) int { return a.CreatedAt.Compare(b.CreatedAt) },
+			SortName:      func(a, b NewsletterSubscriber) int { return compareText(a.Name, b.Name) },
+			SortEmail:     func(a, b NewsletterSubscriber) int { return compareText(a.Email, b.Email) },
+			SortStatus:    func(a, b NewsletterSubscriber) int { return cmp.Compare(a.Status, b.Status) },
+			SortConfirmedAt: func(a, b NewsletterSubscriber) int {
+				return compareTimes(a.ConfirmedAt, b.ConfirmedAt)
+			},
+		},
+		statuses: []string{string(SubscriberPending), string(SubscriberConfirmed), string(SubscriberUnsubscribed)},
+	}
+}
+
+// compareText orders text ignoring case and accents
+func compareText(a, b string) int {
+	return strings.Compare(search.Fold(a), search.Fold(b))
+}
+
+// compareTimes orders optional times, missing ones first
+func compareTimes(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	return a.Compare(*b)
+}
+
+// ContactMessagesTable returns a page of the contact messages matching q
+func (fs *FormsService) ContactMessagesTable(q TableQuery) (TablePage[ContactMessage], error) {
+	spec := contactMessagesTable()
+	q = spec.normalize(q)
+	messages, err := fs.repository.ContactMessages()
+	if err != nil {
+		return TablePage[ContactMessage]{}, err
+	}
+	return spec.page(spec.rows(messages, q), q), nil
+}
+
+// ContactMessagesRows returns every contact message matching q, sorted, for
+// export; the page is ignored
+func (fs *FormsService) ContactMessagesRows(q TableQuery) ([]ContactMessage, error) {
+	spec := contactMessagesTable()
+	messages, err := fs.repository.ContactMessages()
+	if err != nil {
+		return nil, err
+	}
+	return spec.rows(messages, spec.normalize(q)), nil
+}
+
+// SubscribersTable returns a page of the newsletter subscribers matching q
+func (fs *FormsService) SubscribersTable(q TableQuery) (TablePage[NewsletterSubscriber], error) {
+	spec := subscribersTable()
+	q = spec.normalize(q)
+	subscribers, err := fs.repository.Subscribers()
+	if err != nil {
+		return TablePage[NewsletterSubscriber]{}, err
+	}
+	return spec.page(spec.rows(subscribers, q), q), nil
+}
+
+// SubscribersRows returns every subscriber matching q, sorted, for export;
+// the page is ignored
+func (fs *FormsService) SubscribersRows(q TableQuery) ([]NewsletterSubscriber, error) {
+	spec := subscribersTable()
+	subscribers, err := fs.repository.Subscribers()
+	if err != nil {
+		return nil, err
+	}
+	return spec.rows(subscribers, spec.normalize(q)), nil
+}
+
+// MarkContactMessagesRead flags the messages as read and returns how many
+// weren't already
+func (fs *FormsService) MarkContactMessagesRead(ids []string) (int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.repository.MarkContactMessagesRead(ids)
+}
+
+// DeleteContactMessages removes the messages and their attachments and
+// returns how many there were
+func (fs *FormsService) DeleteContactMessages(ctx context.Context, ids []string) (int, error) {
+	fs.mu.Lock()
+	deleted, err := fs.repository.DeleteContactMessages(ids)
+	fs.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	for _, message := range deleted {
+		if fs.blobs == nil {
+			break
+		}
+		fs.deleteAttachments(ctx, message.Attachments)
+	}
+	return len(deleted), nil
+}
+
+// DeleteSubscribers removes the subscribers and returns how many there were
+func (fs *FormsService) DeleteSubscribers(ids []string) (int, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.repository.DeleteSubscribers(ids)
+}
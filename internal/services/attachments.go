@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Attachment limits of the contact form
+const (
+	MaxAttachments    = 3
+	MaxAttachmentSize = 5 << 20
+)
+
+// maxAttachmentName is the length, in characters, file names are cut to
+const maxAttachmentName = 100
+
+// attachmentTypes are the content types accepted, as sniffed from the file
+// itself; the name and the type the browser declares are not trusted
+var attachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// ErrAttachmentNotFound means the message has no such attachment
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+// Attachment is a file sent along with a contact message; the contents
+// live in the blob store under Key
+type Attachment struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	Key         string `json:"key"`
+}
+
+// UseBlobStore turns on contact form attachments, kept in store; without
+// it attachments are refused
+func (fs *FormsService) UseBlobStore(store BlobStore) {
+	fs.blobs = store
+}
+
+// checkAttachments validates the files of a contact message and sniffs
+// their types; the message is the field error, or "" if they're fine
+func (fs *FormsService) checkAttachments(files []*multipart.FileHeader) ([]Attachment, string) {
+	if len(files) == 0 {
+		return nil, ""
+	}
+	if fs.blobs == nil {
+		return nil, "Anexos não estão disponíveis no momento"
+	}
+	if len(files) > MaxAttachments {
+		return nil, fmt.Sprintf("Envie no máximo %d arquivos", MaxAttachments)
+	}
+
+	attachments := make([]Attachment, 0, len(files))
+	for _, file := range files {
+		name := attachmentName(file.Filename)
+		if file.Size == 0 {
+			return nil, name + " está vazio"
+		}
+		if file.Size > MaxAttachmentSize {
+			return nil, fmt.Sprintf("%s passa de %d MB", name, MaxAttachmentSize>>20)
+		}
+
+		contentType, err := sniffContentType(file)
+		if err != nil {
+			log.Printf("Erro ao ler anexo %s: %v", name, err)
+			return nil, "Não foi possível ler " + name
+		}
+		if !attachmentTypes[contentType] {
+			return nil, name + ": tipo de arquivo não permitido (use PNG, JPEG, GIF, WebP ou PDF)"
+		}
+
+		attachments = append(attachments, Attachment{
+			ID:          newRecordID("att"),
+			Name:        name,
+			ContentType: contentType,
+			Size:        file.Size,
+		})
+	}
+	return attachments, ""
+}
+
+// storeAttachments puts the files in the blob store under the message ID.
+// On failure the ones already stored are removed.
+func (fs *FormsService) storeAttachments(ctx context.Context, messageID string, attachments []Attachment, files []*multipart.FileHeader) error {
+	for i := range attachments {
+		attachments[i].Key = messageID + "/" + attachments[i].ID
+		if err := fs.putAttachment(ctx, attachments[i], files[i]); err != nil {
+			fs.deleteAttachments(ctx, attachments[:i+1])
+			return err
+		}
+	}
+	return nil
+}
+
+func (fs *FormsService) putAttachment(ctx context.Context, attachment Attachment, file *multipart.FileHeader) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	written, err := fs.blobs.Put(ctx, attachment.Key, io.LimitReader(reader, MaxAttachmentSize))
+	if err != nil {
+		return err
+	}
+	if written != attachment.Size {
+		return fmt.Errorf("attachment %s: wrote %d of %d bytes", attachment.Key, written, attachment.Size)
+	}
+	return nil
+}
+
+func (fs *FormsService) deleteAttachments(ctx context.Context, attachments []Attachment) {
+	for _, attachment := range attachments {
+		if err := fs.blobs.Delete(ctx, attachment.Key); err != nil {
+			log.Printf("Erro ao remover anexo %s: %v", attachment.Key, err)
+		}
+	}
+}
+
+// OpenAttachment reads an attachment of a contact message
+func (fs *FormsService) OpenAttachment(ctx context.Context, messageID, attachmentID string) (Attachment, io.ReadCloser, error) {
+	messages, err := fs.repository.ContactMessages()
+	if err != nil {
+		return Attachment{}, nil, err
+	}
+	for _, message := range messages {
+		if message.ID != messageID {
+			continue
+		}
+		for _, attachment := range message.Attachments {
+			if attachment.ID != attachmentID {
+				continue
+			}
+			if fs.blobs == nil {
+				return Attachment{}, nil, ErrAttachmentNotFound
+			}
+			reader, err := fs.blobs.Open(ctx, attachment.Key)
+			if errors.Is(err, ErrBlobNotFound) {
+				return Attachment{}, nil, ErrAttachmentNotFound
+			}
+			return attachment, reader, err
+		}
+	}
+	return Attachment{}, nil, ErrAttachmentNotFound
+}
+
+// sniffContentType detects the type from the first bytes of the file,
+// without parameters such as charset
+func sniffContentType(file *multipart.FileHeader) (string, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(head[:n]), ";")
+	return contentType, nil
+}
+
+// attachmentName keeps the base name of an uploaded file, without control
+// characters and cut to maxAttachmentName characters
+func attachmentName(filename string) string {
+	name := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	name = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			return -1
+		}
+		return r
+	}, name))
+	if runes := []rune(name); len(runes) > maxAttachmentName {
+		name = string(runes[:maxAttachmentName])
+	}
+	if name == "" || name == "." || name == "/" {
+		return "anexo"
+	}
+	return name
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrBlobNotFound means the store has nothing under that key
+var ErrBlobNotFound = errors.New("blob not found")
+
+// blobKeyRegex keeps keys to slash-separated names that can't climb out of
+// the store
+var blobKeyRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+(/[A-Za-z0-9_.-]+)*$`)
+
+// BlobStore keeps uploaded files. Keys are slash-separated names chosen by
+// the caller, such as contact_123/att_456.
+type BlobStore interface {
+	// Put stores r under key and returns how many bytes were written
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+
+	// Open reads the blob under key
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the blob under key; missing blobs are not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// LocalBlobStore keeps blobs as files under a directory
+type LocalBlobStore struct {
+	dir string
+}
+
+// NewLocalBlobStore creates the directory if needed
+func NewLocalBlobStore(dir string) (*LocalBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalBlobStore{dir: dir}, nil
+}
+
+func (s *LocalBlobStore) Put(_ context.Context, key string, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	var written int64
+	err = writeFileAtomic(path, func(w io.Writer) error {
+		n, err := io.Copy(w, r)
+		written = n
+		return err
+	})
+	return written, err
+}
+
+func (s *LocalBlobStore) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return file, err
+}
+
+func (s *LocalBlobStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	// Drop the message directory once its last file is gone
+	os.Remove(filepath.Dir(path))
+	return nil
+}
+
+func (s *LocalBlobStore) path(key string) (string, error) {
+	if !blobKeyRegex.MatchString(key) {
+		return "", errors.New("invalid blob key: " + key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,561 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"showcase-datastar-go/internal/templates/fragments"
+)
+
+// ImportFormat is the encoding of a bulk catalog import
+type ImportFormat string
+
+const (
+	ImportFormatCSV    ImportFormat = "csv"
+	ImportFormatJSON   ImportFormat = "json"
+	ImportFormatNDJSON ImportFormat = "ndjson"
+)
+
+var (
+	catalogIDRegex         = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+	catalogLastUpdateRegex = regexp.MustCompile(`^\d{4}-(0[1-9]|1[0-2])$`)
+)
+
+// ErrImportRejected is returned when an import has invalid rows and was not applied
+var ErrImportRejected = errors.New("import has invalid rows")
+
+// CatalogRecord is the wire format of a catalog item in imports and catalog files
+type CatalogRecord struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Category    string   `json:"category"`
+	Tags        []string `json:"tags"`
+	URL         string   `json:"url"`
+	Icon        string   `json:"icon,omitempty"`
+	Popularity  int      `json:"popularity"`
+	LastUpdate  string   `json:"lastUpdate,omitempty"`
+}
+
+// ImportOptions controls how an import is applied
+type ImportOptions struct {
+	Format ImportFormat
+	DryRun bool
+	// Merge upserts into the current catalog instead of replacing it
+	Merge bool
+}
+
+// ImportRowReport is the validation outcome of a single row
+type ImportRowReport struct {
+	Row    int      `json:"row"`
+	ID     string   `json:"id,omitempty"`
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ImportReport summarizes a bulk import
+type ImportReport struct {
+	Format      ImportFormat      `json:"format"`
+	DryRun      bool              `json:"dryRun"`
+	Merge       bool              `json:"merge"`
+	TotalRows   int               `json:"totalRows"`
+	ValidRows   int               `json:"validRows"`
+	InvalidRows int               `json:"invalidRows"`
+	Applied     bool              `json:"applied"`
+	CatalogSize int               `json:"catalogSize"`
+	Rows        []ImportRowReport `json:"rows"`
+}
+
+type importRow struct {
+	line   int
+	record CatalogRecord
+	err    error
+}
+
+// DetectImportFormat guesses the format from a file name or content type
+func DetectImportFormat(name, contentType string) (ImportFormat, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return ImportFormatCSV, nil
+	case ".json":
+		return ImportFormatJSON, nil
+	case ".ndjson", ".jsonl":
+		return ImportFormatNDJSON, nil
+	}
+
+	switch {
+	case strings.Contains(contentType, "csv"):
+		return ImportFormatCSV, nil
+	case strings.Contains(contentType, "ndjson"), strings.Contains(contentType, "jsonl"):
+		return ImportFormatNDJSON, nil
+	case strings.Contains(contentType, "json"):
+		return ImportFormatJSON, nil
+	}
+
+	return "", fmt.Errorf("unable to detect import format for %q", name)
+}
+
+// ParseImportFormat validates a user-supplied format name
+func ParseImportFormat(name string) (ImportFormat, error) {
+	switch format := ImportFormat(strings.ToLower(name)); format {
+	case ImportFormatCSV, ImportFormatJSON, ImportFormatNDJSON:
+		return format, nil
+	case "jsonl":
+		return ImportFormatNDJSON, nil
+	}
+	return "", fmt.Errorf("unknown import format %q", name)
+}
+
+// ImportCatalog validates every row and, unless it is a dry run or a row is
+// invalid, atomically swaps the catalog for the imported one
+func (s *SearchService) ImportCatalog(r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	rows, err := parseImportRows(r, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{
+		Format:    opts.Format,
+		DryRun:    opts.DryRun,
+		Merge:     opts.Merge,
+		TotalRows: len(rows),
+		Rows:      make([]ImportRowReport, 0, len(rows)),
+	}
+
+	items := make([]fragments.SearchResult, 0, len(rows))
+	seen := make(map[string]int, len(rows))
+
+	for _, row := range rows {
+		rowReport := ImportRowReport{Row: row.line, ID: row.record.ID}
+
+		if row.err != nil {
+			rowReport.Errors = []string{row.err.Error()}
+		} else {
+			rowReport.Errors = validateCatalogRecord(row.record, s.taxonomy)
+			if first, exists := seen[row.record.ID]; exists && row.record.ID != "" {
+				rowReport.Errors = append(rowReport.Errors, fmt.Sprintf("duplicate id (first seen on row %d)", first))
+			} else {
+				seen[row.record.ID] = row.line
+			}
+		}
+
+		rowReport.Valid = len(rowReport.Errors) == 0
+		if rowReport.Valid {
+			report.ValidRows++
+			items = append(items, row.record.toSearchResult())
+		} else {
+			report.InvalidRows++
+		}
+		report.Rows = append(report.Rows, rowReport)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	catalog := items
+	if opts.Merge {
+		catalog = mergeCatalog(s.engine.Docs(), items)
+	}
+	report.CatalogSize = len(catalog)
+
+	if opts.DryRun {
+		return report, nil
+	}
+	if report.InvalidRows > 0 || report.TotalRows == 0 {
+		return report, ErrImportRejected
+	}
+
+	// Swap the engine before persisting, so the file never holds a catalog
+	// that isn't being served; a failed write puts the previous one back
+	previous := s.engine.Docs()
+	s.engine.Replace(catalog)
+	if s.catalogPath != "" {
+		if err := s.persistCatalog(catalog); err != nil {
+			s.engine.Replace(previous)
+			return report, err
+		}
+	}
+	report.Applied = true
+
+	// Best effort: a snapshot that failed to update is detected as stale
+	// and rebuilt on the next start
+	if s.snapshotPath != "" {
+		_ = s.writeIndexSnapshot()
+	}
+
+	return report, nil
+}
+
+// persistCatalog writes the catalog file, preceded for a new catalog file by
+// the taxonomy it was validated against; callers must hold s.mu
+func (s *SearchService) persistCatalog(catalog []fragments.SearchResult) error {
+	taxonomyPath := TaxonomyFileFor(s.catalogPath)
+	if _, err := os.Stat(taxonomyPath); errors.Is(err, os.ErrNotExist) {
+		if err := WriteTaxonomyFile(taxonomyPath, s.taxonomy); err != nil {
+			return err
+		}
+	}
+	return WriteCatalogFile(s.catalogPath, catalog)
+}
+
+// TaxonomyFileFor is where the taxonomy of the catalog file at path lives:
+// data/catalog.json goes with data/catalog.taxonomy.json
+func TaxonomyFileFor(catalogPath string) string {
+	return strings.TrimSuffix(catalogPath, filepath.Ext(catalogPath)) + ".taxonomy.json"
+}
+
+// LoadCatalogFile replaces the catalog with the JSON file at path and keeps
+// path as the destination for future imports; a missing file keeps the
+// current catalog and is created on the first applied import. The taxonomy
+// is loaded first from TaxonomyFileFor(path), if it exists, and every
+// category of the catalog must be in it. Call it before serving requests.
+func (s *SearchService) LoadCatalogFile(path string) error {
+	if format, err := DetectImportFormat(path, ""); err != nil || format != ImportFormatJSON {
+		return fmt.Errorf("catalog file %q must be a .json file", path)
+	}
+
+	taxonomy, err := LoadTaxonomyFile(TaxonomyFileFor(path))
+	switch {
+	case err == nil:
+		s.mu.Lock()
+		s.taxonomy = taxonomy
+		s.mu.Unlock()
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// The current catalog stays, so it must fit the taxonomy just loaded
+		for _, item := range s.engine.Docs() {
+			if _, exists := s.taxonomy.Node(item.Category); !exists {
+				return fmt.Errorf("%s: category %q of %q is not in the taxonomy", TaxonomyFileFor(path), item.Category, item.ID)
+			}
+		}
+		s.catalogPath = path
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	report, err := s.ImportCatalog(file, ImportOptions{Format: ImportFormatJSON})
+	if errors.Is(err, ErrImportRejected) {
+		for _, row := range report.Rows {
+			if !row.Valid {
+				return fmt.Errorf("%s: row %d: %s", path, row.Row, strings.Join(row.Errors, "; "))
+			}
+		}
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.catalogPath = path
+	s.mu.Unlock()
+
+	return nil
+}
+
+// WriteCatalogFile writes the catalog as JSON through a temp file and rename,
+// so readers never see a partially written catalog
+func WriteCatalogFile(path string, items []fragments.SearchResult) error {
+	records := make([]CatalogRecord, 0, len(items))
+	for _, item := range items {
+		records = append(records, catalogRecordFrom(item))
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeFileAtomic writes path through a temp file in the same directory and
+// a rename, so readers see either the old or the new content
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	buffered := bufio.NewWriter(tmp)
+	if err := write(buffered); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// validateCatalogRecord returns every problem found in a record
+func validateCatalogRecord(record CatalogRecord, taxonomy *Taxonomy) []string {
+	var problems []string
+
+	if record.ID == "" {
+		problems = append(problems, "id is required")
+	} else if !catalogIDRegex.MatchString(record.ID) {
+		problems = append(problems, "id must contain only lowercase letters, digits and dashes")
+	}
+	if strings.TrimSpace(record.Title) == "" {
+		problems = append(problems, "title is required")
+	}
+	if strings.TrimSpace(record.Description) == "" {
+		problems = append(problems, "description is required")
+	}
+
+	if record.Category == "" {
+		problems = append(problems, "category is required")
+	} else if _, exists := taxonomy.Node(record.Category); !exists {
+		problems = append(problems, fmt.Sprintf("unknown category %q", record.Category))
+	}
+
+	if record.URL == "" {
+		problems = append(problems, "url is required")
+	} else if u, err := url.Parse(record.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(problems, fmt.Sprintf("invalid url %q", record.URL))
+	}
+
+	if record.Popularity < 0 {
+		problems = append(problems, "popularity must not be negative")
+	}
+	if record.LastUpdate != "" && !catalogLastUpdateRegex.MatchString(record.LastUpdate) {
+		problems = append(problems, "lastUpdate must use the YYYY-MM format")
+	}
+
+	return problems
+}
+
+// parseImportRows decodes every row, keeping per-row decode errors
+func parseImportRows(r io.Reader, format ImportFormat) ([]importRow, error) {
+	switch format {
+	case ImportFormatCSV:
+		return parseCSVRows(r)
+	case ImportFormatJSON:
+		return parseJSONRows(r)
+	case ImportFormatNDJSON:
+		return parseNDJSONRows(r)
+	default:
+		return nil, fmt.Errorf("unknown import format %q", format)
+	}
+}
+
+func parseCSVRows(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading csv header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"id", "title", "category", "url"} {
+		if _, exists := columns[required]; !exists {
+			return nil, fmt.Errorf("csv header is missing the %q column", required)
+		}
+	}
+
+	var rows []importRow
+	for line := 2; ; line++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rows = append(rows, importRow{line: line, err: err})
+			continue
+		}
+
+		get := func(name string) string {
+			if i, exists := columns[strings.ToLower(name)]; exists && i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+
+		row := importRow{
+			line: line,
+			record: CatalogRecord{
+				ID:          get("id"),
+				Title:       get("title"),
+				Description: get("description"),
+				Category:    get("category"),
+				Tags:        splitTags(get("tags")),
+				URL:         get("url"),
+				Icon:        get("icon"),
+				LastUpdate:  get("lastUpdate"),
+			},
+		}
+
+		if popularity := get("popularity"); popularity != "" {
+			value, err := strconv.Atoi(popularity)
+			if err != nil {
+				row.err = fmt.Errorf("invalid popularity %q", popularity)
+			}
+			row.record.Popularity = value
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseJSONRows(r io.Reader) ([]importRow, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decoding json array: %w", err)
+	}
+
+	rows := make([]importRow, 0, len(raw))
+	for i, message := range raw {
+		rows = append(rows, decodeJSONRow(i+1, message))
+	}
+	return rows, nil
+}
+
+func parseNDJSONRows(r io.Reader) ([]importRow, error) {
+	var rows []importRow
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		rows = append(rows, decodeJSONRow(line, text))
+	}
+
+	return rows, scanner.Err()
+}
+
+func decodeJSONRow(line int, data []byte) importRow {
+	row := importRow{line: line}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&row.record); err != nil {
+		row.err = fmt.Errorf("invalid json: %v", err)
+	}
+
+	return row
+}
+
+// splitTags accepts tags separated by "|" or ";"
+func splitTags(value string) []string {
+	tags := []string{}
+	for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == '|' || r == ';' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// mergeCatalog upserts items into current, keeping the current order
+func mergeCatalog(current, items []fragments.SearchResult) []fragments.SearchResult {
+	updates := make(map[string]fragments.SearchResult, len(items))
+	for _, item := range items {
+		updates[item.ID] = item
+	}
+
+	merged := make([]fragments.SearchResult, 0, len(current)+len(items))
+	for _, item := range current {
+		if update, exists := updates[item.ID]; exists {
+			merged = append(merged, update)
+			delete(updates, item.ID)
+			continue
+		}
+		merged = append(merged, item)
+	}
+	for _, item := range items {
+		if _, pending := updates[item.ID]; pending {
+			merged = append(merged, item)
+		}
+	}
+
+	return merged
+}
+
+func (record CatalogRecord) toSearchResult() fragments.SearchResult {
+	icon := record.Icon
+	if icon == "" {
+		icon = "trending-up"
+	}
+	tags := record.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return fragments.SearchResult{
+		ID:          record.ID,
+		Title:       strings.TrimSpace(record.Title),
+		Description: strings.TrimSpace(record.Description),
+		Category:    record.Category,
+		Tags:        tags,
+		URL:         record.URL,
+		Icon:        icon,
+		Popularity:  record.Popularity,
+		LastUpdate:  record.LastUpdate,
+	}
+}
+
+func catalogRecordFrom(item fragments.SearchResult) CatalogRecord {
+	return CatalogRecord{
+		ID:          item.ID,
+		Title:       item.Title,
+		Description: item.Description,
+		Category:    item.Category,
+		Tags:        item.Tags,
+		URL:         item.URL,
+		Icon:        item.Icon,
+		Popularity:  item.Popularity,
+		LastUpdate:  item.LastUpdate,
+	}
+}
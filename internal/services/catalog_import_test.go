@@ -0,0 +1,298 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseImportRows(t *testing.T) {
+	react := CatalogRecord{
+		ID:          "react",
+		Title:       "React",
+		Description: "Biblioteca de UI",
+		Category:    "react",
+		Tags:        []string{"ui", "jsx"},
+		URL:         "https://react.dev",
+		Popularity:  90,
+		LastUpdate:  "2024-05",
+	}
+	vue := CatalogRecord{ID: "vue", Title: "Vue", Category: "vue", Tags: []string{}, URL: "https://vuejs.org"}
+
+	tests := []struct {
+		name   string
+		format ImportFormat
+		input  string
+		lines  []int
+		want   []CatalogRecord
+		errs   []string
+	}{
+		{
+			name:   "csv",
+			format: ImportFormatCSV,
+			input: "ID, Title, Description, Category, Tags, URL, Popularity, lastUpdate\n" +
+				"react, React, Biblioteca de UI, react, ui|jsx, https://react.dev, 90, 2024-05\n" +
+				"vue,Vue,,vue,,https://vuejs.org\n",
+			lines: []int{2, 3},
+			want:  []CatalogRecord{react, vue},
+			errs:  []string{"", ""},
+		},
+		{
+			name:   "csv row errors",
+			format: ImportFormatCSV,
+			input: "id,title,category,url,popularity\n" +
+				"vue,Vue,vue,https://vuejs.org,muita\n" +
+				"\"vue,Vue,vue,https://vuejs.org,1\n",
+			lines: []int{2, 3},
+			errs:  []string{`invalid popularity "muita"`, "extraneous or missing \" in quoted-field"},
+		},
+		{
+			name:   "json",
+			format: ImportFormatJSON,
+			input: `[{"id": "react", "title": "React", "description": "Biblioteca de UI", "category": "react", "tags": ["ui", "jsx"], "url": "https://react.dev", "popularity": 90, "lastUpdate": "2024-05"},
+				{"id": "vue", "title": "Vue", "category": "vue", "tags": [], "url": "https://vuejs.org", "stars": 5},
+				{"id": "vue", "popularity": "90"}]`,
+			lines: []int{1, 2, 3},
+			want:  []CatalogRecord{react, {}, {}},
+			errs:  []string{"", `unknown field "stars"`, "cannot unmarshal string"},
+		},
+		{
+			name:   "ndjson",
+			format: ImportFormatNDJSON,
+			input: `{"id": "react", "title": "React", "description": "Biblioteca de UI", "category": "react", "tags": ["ui", "jsx"], "url": "https://react.dev", "popularity": 90, "lastUpdate": "2024-05"}` + "\n" +
+				"\n" +
+				`{"id": "vue", "title": "Vue", "category": "vue", "tags": [], "url": "https://vuejs.org"}` + "\n" +
+				`{"id": "broken",` + "\n",
+			lines: []int{1, 3, 4},
+			want:  []CatalogRecord{react, vue, {}},
+			errs:  []string{"", "", "invalid json"},
+		},
+		{
+			name:   "empty csv",
+			format: ImportFormatCSV,
+			input:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := parseImportRows(strings.NewReader(tt.input), tt.format)
+			if err != nil {
+				t.Fatalf("parseImportRows: %v", err)
+			}
+			if len(rows) != len(tt.lines) {
+				t.Fatalf("got %d rows, want %d", len(rows), len(tt.lines))
+			}
+			for i, row := range rows {
+				if row.line != tt.lines[i] {
+					t.Errorf("row %d: line = %d, want %d", i, row.line, tt.lines[i])
+				}
+				if tt.errs[i] == "" {
+					if row.err != nil {
+						t.Errorf("row %d: unexpected error %v", i, row.err)
+					}
+				} else if row.err == nil || !strings.Contains(row.err.Error(), tt.errs[i]) {
+					t.Errorf("row %d: error = %v, want %q", i, row.err, tt.errs[i])
+				}
+				if tt.want != nil && row.err == nil && !reflect.DeepEqual(row.record, tt.want[i]) {
+					t.Errorf("row %d: record = %+v, want %+v", i, row.record, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseImportRowsRejectsInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		format ImportFormat
+		input  string
+	}{
+		{"csv without the url column", ImportFormatCSV, "id,title,category\nvue,Vue,vue\n"},
+		{"json object instead of array", ImportFormatJSON, `{"id": "vue"}`},
+		{"truncated json", ImportFormatJSON, `[{"id": "vue"}`},
+		{"unknown format", ImportFormat("xml"), "<catalog/>"},
+	}
+	for _, tt := range tests {
+		if _, err := parseImportRows(strings.NewReader(tt.input), tt.format); err == nil {
+			t.Errorf("%s: parseImportRows accepted it", tt.name)
+		}
+	}
+}
+
+func TestValidateCatalogRecord(t *testing.T) {
+	valid := CatalogRecord{
+		ID:          "react",
+		Title:       "React",
+		Description: "Biblioteca de UI",
+		Category:    "react",
+		URL:         "https://react.dev",
+		LastUpdate:  "2024-05",
+	}
+	taxonomy := NewTaxonomy(getMockTaxonomy())
+
+	tests := []struct {
+		name   string
+		change func(r *CatalogRecord)
+		want   []string
+	}{
+		{"valid", func(r *CatalogRecord) {}, nil},
+		{"missing id", func(r *CatalogRecord) { r.ID = "" }, []string{"id is required"}},
+		{"uppercase id", func(r *CatalogRecord) { r.ID = "React" }, []string{"id must contain only lowercase letters, digits and dashes"}},
+		{"blank title", func(r *CatalogRecord) { r.Title = "  " }, []string{"title is required"}},
+		{"missing description", func(r *CatalogRecord) { r.Description = "" }, []string{"description is required"}},
+		{"missing category", func(r *CatalogRecord) { r.Category = "" }, []string{"category is required"}},
+		{"unknown category", func(r *CatalogRecord) { r.Category = "angular" }, []string{`unknown category "angular"`}},
+		{"missing url", func(r *CatalogRecord) { r.URL = "" }, []string{"url is required"}},
+		{"url without scheme", func(r *CatalogRecord) { r.URL = "react.dev" }, []string{`invalid url "react.dev"`}},
+		{"javascript url", func(r *CatalogRecord) { r.URL = "javascript:alert(1)" }, []string{`invalid url "javascript:alert(1)"`}},
+		{"negative popularity", func(r *CatalogRecord) { r.Popularity = -1 }, []string{"popularity must not be negative"}},
+		{"full date", func(r *CatalogRecord) { r.LastUpdate = "2024-05-01" }, []string{"lastUpdate must use the YYYY-MM format"}},
+		{"month 13", func(r *CatalogRecord) { r.LastUpdate = "2024-13" }, []string{"lastUpdate must use the YYYY-MM format"}},
+		{"every problem at once", func(r *CatalogRecord) { *r = CatalogRecord{Popularity: -5} }, []string{
+			"id is required", "title is required", "description is required", "category is required",
+			"url is required", "popularity must not be negative",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := valid
+			tt.change(&record)
+			if got := validateCatalogRecord(record, taxonomy); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateCatalogRecord = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportCatalogReportsRows(t *testing.T) {
+	s := NewSearchService()
+	before := len(s.engine.Docs())
+
+	input := `{"id": "react", "title": "React", "description": "UI", "category": "react", "url": "https://react.dev"}
+{"id": "vue", "title": "Vue", "description": "UI", "category": "vue", "url": "ftp://vuejs.org"}
+{"id": "react", "title": "React de novo", "description": "UI", "category": "react", "url": "https://react.dev"}
+{"id": "svelte", "title": "Svelte", "description": "UI", "category": "frontend", "url": "https://svelte.dev", "extra": 1}
+`
+	report, err := s.ImportCatalog(strings.NewReader(input), ImportOptions{Format: ImportFormatNDJSON})
+	if !errors.Is(err, ErrImportRejected) {
+		t.Fatalf("ImportCatalog = %v, want ErrImportRejected", err)
+	}
+	if report.TotalRows != 4 || report.ValidRows != 1 || report.InvalidRows != 3 || report.Applied {
+		t.Errorf("report = %+v, want 4 rows, 1 valid, 3 invalid, not applied", report)
+	}
+
+	want := []ImportRowReport{
+		{Row: 1, ID: "react", Valid: true},
+		{Row: 2, ID: "vue", Errors: []string{`invalid url "ftp://vuejs.org"`}},
+		{Row: 3, ID: "react", Errors: []string{"duplicate id (first seen on row 1)"}},
+		{Row: 4, ID: "svelte", Errors: []string{`invalid json: json: unknown field "extra"`}},
+	}
+	if !reflect.DeepEqual(report.Rows, want) {
+		t.Errorf("rows =\n%+v\nwant\n%+v", report.Rows, want)
+	}
+	if len(s.engine.Docs()) != before {
+		t.Error("a rejected import changed the catalog")
+	}
+}
+
+func TestImportCatalogRejectsEmptyImport(t *testing.T) {
+	s := NewSearchService()
+	if _, err := s.ImportCatalog(strings.NewReader("[]"), ImportOptions{Format: ImportFormatJSON}); !errors.Is(err, ErrImportRejected) {
+		t.Errorf("empty import = %v, want ErrImportRejected", err)
+	}
+}
+
+func TestImportCatalogDryRunAndMerge(t *testing.T) {
+	s := NewSearchService()
+	current := s.engine.Docs()
+	input := `[{"id": "go", "title": "Go 2", "description": "Atualizado", "category": "systems-languages", "url": "https://go.dev"},
+		{"id": "zig", "title": "Zig", "description": "Nova", "category": "systems-languages", "url": "https://ziglang.org"}]`
+
+	report, err := s.ImportCatalog(strings.NewReader(input), ImportOptions{Format: ImportFormatJSON, Merge: true, DryRun: true})
+	if err != nil || report.Applied || report.CatalogSize != len(current)+1 {
+		t.Fatalf("dry run = %+v, %v; want not applied with catalog size %d", report, err, len(current)+1)
+	}
+	if len(s.engine.Docs()) != len(current) {
+		t.Fatal("a dry run changed the catalog")
+	}
+
+	if _, err := s.ImportCatalog(strings.NewReader(input), ImportOptions{Format: ImportFormatJSON, Merge: true}); err != nil {
+		t.Fatal(err)
+	}
+	docs := s.engine.Docs()
+	if len(docs) != len(current)+1 || docs[0].ID != "go" || docs[0].Title != "Go 2" || docs[len(docs)-1].ID != "zig" {
+		t.Errorf("merged catalog keeps the order and upserts: got %d docs, first %q, last %q", len(docs), docs[0].Title, docs[len(docs)-1].ID)
+	}
+}
+
+func TestImportCatalogPersists(t *testing.T) {
+	catalogPath := filepath.Join(t.TempDir(), "catalog.json")
+	s := NewSearchService()
+	if err := s.LoadCatalogFile(catalogPath); err != nil {
+		t.Fatal(err)
+	}
+
+	input := `[{"id": "vue", "title": "Vue", "description": "UI", "category": "vue", "url": "https://vuejs.org"}]`
+	if _, err := s.ImportCatalog(strings.NewReader(input), ImportOptions{Format: ImportFormatJSON}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(catalogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []CatalogRecord
+	if err := json.Unmarshal(data, &records); err != nil || len(records) != 1 || records[0].ID != "vue" {
+		t.Errorf("catalog file = %s, want the imported catalog", data)
+	}
+}
+
+func TestImportCatalogWriteFailureKeepsCatalog(t *testing.T) {
+	// A catalog path under a regular file can't be written
+	blocker := filepath.Join(t.TempDir(), "blocker")
+	writeTestFile(t, blocker, "")
+
+	s := NewSearchService()
+	s.catalogPath = filepath.Join(blocker, "catalog.json")
+	current := s.engine.Docs()
+
+	input := `[{"id": "vue", "title": "Vue", "description": "UI", "category": "vue", "url": "https://vuejs.org"}]`
+	report, err := s.ImportCatalog(strings.NewReader(input), ImportOptions{Format: ImportFormatJSON})
+	if err == nil || errors.Is(err, ErrImportRejected) {
+		t.Fatalf("ImportCatalog = %v, want the write error", err)
+	}
+	if report.Applied {
+		t.Error("report says applied though the catalog wasn't persisted")
+	}
+	if docs := s.engine.Docs(); !reflect.DeepEqual(docs, current) {
+		t.Errorf("engine serves %d docs after a failed write, want the previous %d", len(docs), len(current))
+	}
+}
+
+func TestDetectImportFormat(t *testing.T) {
+	tests := []struct {
+		name, contentType string
+		want              ImportFormat
+	}{
+		{"catalog.CSV", "", ImportFormatCSV},
+		{"catalog.json", "text/plain", ImportFormatJSON},
+		{"catalog.jsonl", "", ImportFormatNDJSON},
+		{"catalog.ndjson", "", ImportFormatNDJSON},
+		{"upload", "text/csv", ImportFormatCSV},
+		{"upload", "application/x-ndjson", ImportFormatNDJSON},
+		{"upload", "application/json", ImportFormatJSON},
+	}
+	for _, tt := range tests {
+		if got, err := DetectImportFormat(tt.name, tt.contentType); err != nil || got != tt.want {
+			t.Errorf("DetectImportFormat(%q, %q) = %q, %v; want %q", tt.name, tt.contentType, got, err, tt.want)
+		}
+	}
+	if _, err := DetectImportFormat("catalog.xml", "application/xml"); err == nil {
+		t.Error("DetectImportFormat accepted XML")
+	}
+}
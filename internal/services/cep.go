@@ -0,0 +1,353 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCEPNotFound means a provider knows the CEP does not exist
+	ErrCEPNotFound = errors.New("cep: not found")
+
+	// ErrCEPUnavailable means no provider could answer the lookup
+	ErrCEPUnavailable = errors.New("cep: lookup unavailable")
+)
+
+type CEPResponse struct {
+	CEP        string `json:"cep"`
+	Logradouro string `json:"logradouro"`
+	Bairro     string `json:"bairro"`
+	Localidade string `json:"localidade"`
+	UF         string `json:"uf"`
+}
+
+// CEPProvider looks up the address of an 8-digit, unformatted CEP. It
+// returns ErrCEPNotFound when the CEP doesn't exist and any other error when
+// it couldn't tell.
+type CEPProvider interface {
+	Lookup(ctx context.Context, cep string) (*CEPResponse, error)
+}
+
+// MockCEPProvider answers from a handful of well-known CEPs
+type MockCEPProvider struct {
+	data map[string]*CEPResponse
+}
+
+func NewMockCEPProvider() *MockCEPProvider {
+	return &MockCEPProvider{data: getMockCEPData()}
+}
+
+func (p *MockCEPProvider) Lookup(_ context.Context, cep string) (*CEPResponse, error) {
+	if address, ok := p.data[cep]; ok {
+		return address, nil
+	}
+	return nil, ErrCEPNotFound
+}
+
+// DatasetCEPProvider answers from an offline list of addresses
+type DatasetCEPProvider struct {
+	data map[string]*CEPResponse
+}
+
+func NewDatasetCEPProvider(addresses []CEPResponse) *DatasetCEPProvider {
+	data := make(map[string]*CEPResponse, len(addresses))
+	for i := range addresses {
+		data[normalizeCEP(addresses[i].CEP)] = &addresses[i]
+	}
+	return &DatasetCEPProvider{data: data}
+}
+
+// LoadCEPDataset reads a JSON array of ViaCEP-style addresses
+func LoadCEPDataset(path string) (*DatasetCEPProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []CEPResponse
+	if err := json.Unmarshal(data, &addresses); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return NewDatasetCEPProvider(addresses), nil
+}
+
+func (p *DatasetCEPProvider) Lookup(_ context.Context, cep string) (*CEPResponse, error) {
+	if address, ok := p.data[cep]; ok {
+		return address, nil
+	}
+	return nil, ErrCEPNotFound
+}
+
+// HTTPCEPProvider queries a ViaCEP-compatible API: GET {BaseURL}/{cep}/json/
+// answering the address, or {"erro": true} for unknown CEPs
+type HTTPCEPProvider struct {
+	BaseURL string
+	Timeout time.Duration
+
+	// Retries is how many extra attempts are made after network errors and
+	// 5xx responses
+	Retries int
+
+	// Client defaults to http.DefaultClient
+	Client *http.Client
+}
+
+func NewHTTPCEPProvider(baseURL string, timeout time.Duration, retries int) *HTTPCEPProvider {
+	return &HTTPCEPProvider{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Timeout: timeout,
+		Retries: retries,
+	}
+}
+
+func (p *HTTPCEPProvider) Lookup(ctx context.Context, cep string) (*CEPResponse, error) {
+	var err error
+	for attempt := 0; attempt <= p.Retries; attempt++ {
+		if attempt > 0 {
+			// Linear backoff, cut short if the caller gives up
+			select {
+			case <-time.After(time.Duration(attempt) * 100 * time.Millisecond):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		var address *CEPResponse
+		var retry bool
+		address, retry, err = p.lookupOnce(ctx, cep)
+		if err == nil || !retry {
+			return address, err
+		}
+	}
+	return nil, err
+}
+
+// lookupOnce makes a single request; retry tells whether a failure is
+// worth another attempt
+func (p *HTTPCEPProvider) lookupOnce(ctx context.Context, cep string) (*CEPResponse, bool, error) {
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.BaseURL+"/"+cep+"/json/", nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, ctx.Err() == nil || errors.Is(ctx.Err(), context.DeadlineExceeded), err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusNotFound:
+		return nil, false, ErrCEPNotFound
+	case resp.StatusCode >= 500:
+		return nil, true, fmt.Errorf("cep: %s answered %s", p.BaseURL, resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		return nil, false, fmt.Errorf("cep: %s answered %s", p.BaseURL, resp.Status)
+	}
+
+	var body struct {
+		CEPResponse
+		// ViaCEP sends "erro": true, some mirrors send it as a string
+		Erro json.RawMessage `json:"erro"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body); err != nil {
+		return nil, false, fmt.Errorf("cep: invalid response: %w", err)
+	}
+	if erro := strings.Trim(string(body.Erro), `"`); erro == "true" {
+		return nil, false, ErrCEPNotFound
+	}
+
+	return &body.CEPResponse, false, nil
+}
+
+// ChainCEPProvider tries each provider in order until one finds the CEP
+type ChainCEPProvider []CEPProvider
+
+// Lookup returns ErrCEPNotFound only if every provider reported the CEP as
+// not found; if some of them failed instead, the result is ErrCEPUnavailable
+func (chain ChainCEPProvider) Lookup(ctx context.Context, cep string) (*CEPResponse, error) {
+	var failures []error
+
+	for _, provider := range chain {
+		address, err := provider.Lookup(ctx, cep)
+		if err == nil {
+			return address, nil
+		}
+		if !errors.Is(err, ErrCEPNotFound) {
+			failures = append(failures, err)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if len(failures) > 0 {
+		return nil, fmt.Errorf("%w: %w", ErrCEPUnavailable, errors.Join(failures...))
+	}
+	return nil, ErrCEPNotFound
+}
+
+// CachedCEPProvider remembers lookups for a while. Unknown CEPs are cached
+// too (negative caching), never for longer than found ones; failures are not
+// cached. Past maxEntries the least recently used entry is evicted, so a
+// stream of made-up CEPs can't grow the cache without bound.
+type CachedCEPProvider struct {
+	next        CEPProvider
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxEntries  int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// recent orders the entries from most to least recently used
+	recent *list.List
+}
+
+type cepCacheEntry struct {
+	cep     string
+	address *CEPResponse
+	expires time.Time
+}
+
+// DefaultCEPCacheEntries is the cache size when none is given
+const DefaultCEPCacheEntries = 10000
+
+// NewCachedCEPProvider caches next. negativeTTL is capped at ttl, and
+// maxEntries <= 0 means DefaultCEPCacheEntries.
+func NewCachedCEPProvider(next CEPProvider, ttl, negativeTTL time.Duration, maxEntries int) *CachedCEPProvider {
+	if maxEntries <= 0 {
+		maxEntries = DefaultCEPCacheEntries
+	}
+	return &CachedCEPProvider{
+		next:        next,
+		ttl:         ttl,
+		negativeTTL: min(negativeTTL, ttl),
+		maxEntries:  maxEntries,
+		entries:     make(map[string]*list.Element),
+		recent:      list.New(),
+	}
+}
+
+func (p *CachedCEPProvider) Lookup(ctx context.Context, cep string) (*CEPResponse, error) {
+	now := time.Now()
+
+	if entry, ok := p.cached(cep, now); ok {
+		if entry.address == nil {
+			return nil, ErrCEPNotFound
+		}
+		return entry.address, nil
+	}
+
+	address, err := p.next.Lookup(ctx, cep)
+	switch {
+	case err == nil:
+		p.store(cepCacheEntry{cep: cep, address: address, expires: now.Add(p.ttl)})
+	case errors.Is(err, ErrCEPNotFound) && p.negativeTTL > 0:
+		p.store(cepCacheEntry{cep: cep, expires: now.Add(p.negativeTTL)})
+	}
+	return address, err
+}
+
+// cached returns the live entry for cep, marking it as recently used; an
+// expired one is dropped
+func (p *CachedCEPProvider) cached(cep string, now time.Time) (cepCacheEntry, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	element, ok := p.entries[cep]
+	if !ok {
+		return cepCacheEntry{}, false
+	}
+	entry := element.Value.(cepCacheEntry)
+	if !now.Before(entry.expires) {
+		p.recent.Remove(element)
+		delete(p.entries, cep)
+		return cepCacheEntry{}, false
+	}
+	p.recent.MoveToFront(element)
+	return entry, true
+}
+
+func (p *CachedCEPProvider) store(entry cepCacheEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if element, ok := p.entries[entry.cep]; ok {
+		element.Value = entry
+		p.recent.MoveToFront(element)
+		return
+	}
+	p.entries[entry.cep] = p.recent.PushFront(entry)
+	for p.recent.Len() > p.maxEntries {
+		oldest := p.recent.Back()
+		p.recent.Remove(oldest)
+		delete(p.entries, oldest.Value.(cepCacheEntry).cep)
+	}
+}
+
+// normalizeCEP strips the mask from a CEP
+func normalizeCEP(cep string) string {
+	return strings.NewReplacer("-", "", ".", "", " ", "").Replace(cep)
+}
+
+// getMockCEPData returns mock CEP data
+func getMockCEPData() map[string]*CEPResponse {
+	return map[string]*CEPResponse{
+		"01310100": {
+			CEP:        "01310-100",
+			Logradouro: "Avenida Paulista",
+			Bairro:     "Bela Vista",
+			Localidade: "São Paulo",
+			UF:         "SP",
+		},
+		"20040020": {
+			CEP:        "20040-020",
+			Logradouro: "Rua da Assembleia",
+			Bairro:     "Centro",
+			Localidade: "Rio de Janeiro",
+			UF:         "RJ",
+		},
+		"30112000": {
+			CEP:        "30112-000",
+			Logradouro: "Rua da Bahia",
+			Bairro:     "Centro",
+			Localidade: "Belo Horizonte",
+			UF:         "MG",
+		},
+		"40070110": {
+			CEP:        "40070-110",
+			Logradouro: "Rua Chile",
+			Bairro:     "Centro",
+			Localidade: "Salvador",
+			UF:         "BA",
+		},
+		"80010000": {
+			CEP:        "80010-000",
+			Logradouro: "Rua XV de Novembro",
+			Bairro:     "Centro",
+			Localidade: "Curitiba",
+			UF:         "PR",
+		},
+	}
+}
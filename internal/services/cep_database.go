@@ -0,0 +1,440 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CEP database file layout: magic, then uvarint-encoded sections (name
+// table, state ranges, city ranges, streets; CEPs delta-encoded), then the
+// CRC-32C of everything before it
+var cepDatabaseMagic = [8]byte{'C', 'E', 'P', 'R', 'A', 'N', 'G', 'E'}
+
+const cepDatabaseVersion = 1
+
+var cepCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrCEPDatabaseCorrupt means a compiled CEP database failed to decode
+var ErrCEPDatabaseCorrupt = errors.New("cep: corrupt database")
+
+// CEPLocator tells which state and city a CEP belongs to, from its range,
+// even when the exact CEP is unknown
+type CEPLocator interface {
+	Locate(cep string) (*CEPResponse, bool)
+}
+
+// CEPDatabase is an offline CEP dataset: state and city ranges plus known
+// streets, held as sorted arrays of small integers over a shared name table
+type CEPDatabase struct {
+	names   []string
+	states  []cepRange
+	cities  []cepRange
+	streets []cepStreet
+}
+
+// cepRange covers [start, end]; city is 0 (the empty name) for state ranges
+type cepRange struct {
+	start, end uint32
+	uf, city   uint32
+}
+
+type cepStreet struct {
+	cep                        uint32
+	street, district, city, uf uint32
+}
+
+// CEPDatabaseStats summarizes a compiled database
+type CEPDatabaseStats struct {
+	States  int
+	Cities  int
+	Streets int
+	Names   int
+}
+
+// Lookup finds a known street; it makes the database usable as a CEPProvider
+func (db *CEPDatabase) Lookup(_ context.Context, cep string) (*CEPResponse, error) {
+	n, ok := parseCEP(cep)
+	if !ok {
+		return nil, ErrCEPNotFound
+	}
+
+	i := sort.Search(len(db.streets), func(i int) bool { return db.streets[i].cep >= n })
+	if i == len(db.streets) || db.streets[i].cep != n {
+		return nil, ErrCEPNotFound
+	}
+
+	s := db.streets[i]
+	return &CEPResponse{
+		CEP:        formatCEP(n),
+		Logradouro: db.names[s.street],
+		Bairro:     db.names[s.district],
+		Localidade: db.names[s.city],
+		UF:         db.names[s.uf],
+	}, nil
+}
+
+// Locate returns the state, and the city when a city range covers it, of
+// any CEP inside a known range
+func (db *CEPDatabase) Locate(cep string) (*CEPResponse, bool) {
+	n, ok := parseCEP(cep)
+	if !ok {
+		return nil, false
+	}
+
+	if r, ok := findRange(db.cities, n); ok {
+		return &CEPResponse{CEP: formatCEP(n), Localidade: db.names[r.city], UF: db.names[r.uf]}, true
+	}
+	if r, ok := findRange(db.states, n); ok {
+		return &CEPResponse{CEP: formatCEP(n), UF: db.names[r.uf]}, true
+	}
+	return nil, false
+}
+
+// Stats returns the size of each section
+func (db *CEPDatabase) Stats() CEPDatabaseStats {
+	return CEPDatabaseStats{
+		States:  len(db.states),
+		Cities:  len(db.cities),
+		Streets: len(db.streets),
+		Names:   len(db.names),
+	}
+}
+
+// findRange binary-searches sorted, non-overlapping ranges
+func findRange(ranges []cepRange, n uint32) (cepRange, bool) {
+	i := sort.Search(len(ranges), func(i int) bool { return ranges[i].end >= n })
+	if i < len(ranges) && ranges[i].start <= n {
+		return ranges[i], true
+	}
+	return cepRange{}, false
+}
+
+// CompileCEPDatabase builds a database from CSV with the header
+//
+//	cep_inicial,cep_final,uf,localidade,bairro,logradouro
+//
+// Rows with a logradouro are streets (cep_final may be empty). Other rows
+// are ranges: state ranges when localidade is empty, city ranges otherwise.
+// Ranges of the same kind must not overlap.
+func CompileCEPDatabase(r io.Reader) (*CEPDatabase, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cep csv: missing header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"cep_inicial", "uf"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("cep csv: missing column %q", required)
+		}
+	}
+
+	b := newCEPDatabaseBuilder()
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cep csv: line %d: %w", line, err)
+		}
+
+		get := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		if err := b.add(get("cep_inicial"), get("cep_final"), get("uf"), get("localidade"), get("bairro"), get("logradouro")); err != nil {
+			return nil, fmt.Errorf("cep csv: line %d: %w", line, err)
+		}
+	}
+
+	return b.build()
+}
+
+type cepDatabaseBuilder struct {
+	db      *CEPDatabase
+	nameIDs map[string]uint32
+}
+
+func newCEPDatabaseBuilder() *cepDatabaseBuilder {
+	return &cepDatabaseBuilder{
+		// Name 0 is the empty string, used for "no city"
+		db:      &CEPDatabase{names: []string{""}},
+		nameIDs: map[string]uint32{"": 0},
+	}
+}
+
+func (b *cepDatabaseBuilder) name(s string) uint32 {
+	if id, ok := b.nameIDs[s]; ok {
+		return id
+	}
+	id := uint32(len(b.db.names))
+	b.db.names = append(b.db.names, s)
+	b.nameIDs[s] = id
+	return id
+}
+
+func (b *cepDatabaseBuilder) add(first, last, uf, city, district, street string) error {
+	start, ok := parseCEP(first)
+	if !ok {
+		return fmt.Errorf("invalid cep_inicial %q", first)
+	}
+	end := start
+	if last != "" {
+		if end, ok = parseCEP(last); !ok {
+			return fmt.Errorf("invalid cep_final %q", last)
+		}
+	}
+	if end < start {
+		return fmt.Errorf("cep_final %s before cep_inicial %s", last, first)
+	}
+
+	uf = strings.ToUpper(uf)
+	if len(uf) != 2 {
+		return fmt.Errorf("invalid uf %q", uf)
+	}
+
+	if street != "" {
+		if start != end {
+			return errors.New("a street must have a single CEP")
+		}
+		if city == "" {
+			return errors.New("a street needs localidade")
+		}
+		b.db.streets = append(b.db.streets, cepStreet{
+			cep:      start,
+			street:   b.name(street),
+			district: b.name(district),
+			city:     b.name(city),
+			uf:       b.name(uf),
+		})
+		return nil
+	}
+
+	r := cepRange{start: start, end: end, uf: b.name(uf), city: b.name(city)}
+	if city == "" {
+		b.db.states = append(b.db.states, r)
+	} else {
+		b.db.cities = append(b.db.cities, r)
+	}
+	return nil
+}
+
+func (b *cepDatabaseBuilder) build() (*CEPDatabase, error) {
+	db := b.db
+
+	for _, ranges := range [][]cepRange{db.states, db.cities} {
+		sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+		for i := 1; i < len(ranges); i++ {
+			if ranges[i].start <= ranges[i-1].end {
+				return nil, fmt.Errorf("cep csv: range %s-%s overlaps %s-%s",
+					formatCEP(ranges[i].start), formatCEP(ranges[i].end),
+					formatCEP(ranges[i-1].start), formatCEP(ranges[i-1].end))
+			}
+		}
+	}
+
+	sort.Slice(db.streets, func(i, j int) bool { return db.streets[i].cep < db.streets[j].cep })
+	for i := 1; i < len(db.streets); i++ {
+		if db.streets[i].cep == db.streets[i-1].cep {
+			return nil, fmt.Errorf("cep csv: duplicate street CEP %s", formatCEP(db.streets[i].cep))
+		}
+	}
+
+	return db, nil
+}
+
+// WriteCEPDatabaseFile writes the compiled database through a temp file
+func WriteCEPDatabaseFile(path string, db *CEPDatabase) error {
+	return writeFileAtomic(path, db.write)
+}
+
+// LoadCEPDatabase reads a database compiled by WriteCEPDatabaseFile
+func LoadCEPDatabase(path string) (*CEPDatabase, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := decodeCEPDatabase(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return db, nil
+}
+
+func (db *CEPDatabase) write(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.Write(cepDatabaseMagic[:])
+
+	put := func(v uint32) { buf.Write(binary.AppendUvarint(nil, uint64(v))) }
+
+	put(cepDatabaseVersion)
+
+	put(uint32(len(db.names)))
+	for _, name := range db.names {
+		put(uint32(len(name)))
+		buf.WriteString(name)
+	}
+
+	for _, ranges := range [][]cepRange{db.states, db.cities} {
+		put(uint32(len(ranges)))
+		prev := uint32(0)
+		for _, r := range ranges {
+			put(r.start - prev)
+			put(r.end - r.start)
+			put(r.uf)
+			put(r.city)
+			prev = r.start
+		}
+	}
+
+	put(uint32(len(db.streets)))
+	prev := uint32(0)
+	for _, s := range db.streets {
+		put(s.cep - prev)
+		put(s.street)
+		put(s.district)
+		put(s.city)
+		put(s.uf)
+		prev = s.cep
+	}
+
+	checksum := crc32.Checksum(buf.Bytes(), cepCRCTable)
+	if err := binary.Write(&buf, binary.LittleEndian, checksum); err != nil {
+		return err
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func decodeCEPDatabase(data []byte) (*CEPDatabase, error) {
+	if len(data) < len(cepDatabaseMagic)+4 || !bytes.Equal(data[:len(cepDatabaseMagic)], cepDatabaseMagic[:]) {
+		return nil, ErrCEPDatabaseCorrupt
+	}
+
+	body, trailer := data[:len(data)-4], data[len(data)-4:]
+	if crc32.Checksum(body, cepCRCTable) != binary.LittleEndian.Uint32(trailer) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrCEPDatabaseCorrupt)
+	}
+
+	d := cepDecoder{data: body[len(cepDatabaseMagic):]}
+	if version := d.next(); version != cepDatabaseVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrCEPDatabaseCorrupt, version)
+	}
+
+	db := &CEPDatabase{}
+
+	db.names = make([]string, d.count())
+	for i := range db.names {
+		db.names[i] = d.string()
+	}
+	nameID := func() uint32 {
+		id := d.next()
+		if int(id) >= len(db.names) {
+			d.err = ErrCEPDatabaseCorrupt
+			return 0
+		}
+		return id
+	}
+
+	for _, ranges := range []*[]cepRange{&db.states, &db.cities} {
+		*ranges = make([]cepRange, d.count())
+		prev := uint32(0)
+		for i := range *ranges {
+			start := prev + d.next()
+			(*ranges)[i] = cepRange{start: start, end: start + d.next(), uf: nameID(), city: nameID()}
+			prev = start
+		}
+	}
+
+	db.streets = make([]cepStreet, d.count())
+	prev := uint32(0)
+	for i := range db.streets {
+		cep := prev + d.next()
+		db.streets[i] = cepStreet{cep: cep, street: nameID(), district: nameID(), city: nameID(), uf: nameID()}
+		prev = cep
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	return db, nil
+}
+
+// cepDecoder reads uvarints, remembering the first error
+type cepDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *cepDecoder) next() uint32 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 || v > 1<<32-1 {
+		d.err = ErrCEPDatabaseCorrupt
+		return 0
+	}
+	d.data = d.data[n:]
+	return uint32(v)
+}
+
+// count reads a length, capped by the remaining bytes so a corrupt value
+// can't cause a huge allocation
+func (d *cepDecoder) count() int {
+	n := int(d.next())
+	if n > len(d.data) {
+		d.err = ErrCEPDatabaseCorrupt
+		return 0
+	}
+	return n
+}
+
+func (d *cepDecoder) string() string {
+	n := d.count()
+	if d.err != nil {
+		return ""
+	}
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+	return s
+}
+
+// parseCEP reads an 8-digit CEP, with or without mask, as a number
+func parseCEP(cep string) (uint32, bool) {
+	cep = normalizeCEP(cep)
+	if len(cep) != 8 {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(cep, 10, 32)
+	return uint32(n), err == nil
+}
+
+// formatCEP renders a CEP number as 00000-000
+func formatCEP(n uint32) string {
+	s := fmt.Sprintf("%08d", n)
+	return s[:5] + "-" + s[5:]
+}
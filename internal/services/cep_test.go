@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// cepStub serves a ViaCEP-style API from handle and counts the requests
+func cepStub(t *testing.T, handle func(w http.ResponseWriter, r *http.Request, hit int32)) (*HTTPCEPProvider, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handle(w, r, hits.Add(1))
+	}))
+	t.Cleanup(server.Close)
+	return NewHTTPCEPProvider(server.URL+"/", time.Second, 0), &hits
+}
+
+func TestHTTPCEPProviderResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+		err    error
+	}{
+		{"found", http.StatusOK, `{"cep":"01310-100","logradouro":"Avenida Paulista","localidade":"São Paulo","uf":"SP"}`, "Avenida Paulista", nil},
+		{"erro as bool", http.StatusOK, `{"erro": true}`, "", ErrCEPNotFound},
+		{"erro as string", http.StatusOK, `{"erro": "true"}`, "", ErrCEPNotFound},
+		{"bad request", http.StatusBadRequest, `{}`, "", ErrCEPNotFound},
+		{"not found", http.StatusNotFound, ``, "", ErrCEPNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, _ := cepStub(t, func(w http.ResponseWriter, r *http.Request, _ int32) {
+				if r.URL.Path != "/01310100/json/" {
+					t.Errorf("requested %s, want /01310100/json/", r.URL.Path)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			address, err := provider.Lookup(context.Background(), "01310100")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && address.Logradouro != tt.want {
+				t.Errorf("Logradouro = %q, want %q", address.Logradouro, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPCEPProviderRetriesServerErrors(t *testing.T) {
+	provider, hits := cepStub(t, func(w http.ResponseWriter, _ *http.Request, _ int32) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	provider.Retries = 2
+
+	_, err := provider.Lookup(context.Background(), "01310100")
+	if err == nil || errors.Is(err, ErrCEPNotFound) {
+		t.Fatalf("err = %v, want a failure that isn't ErrCEPNotFound", err)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Errorf("made %d requests, want 1 + 2 retries", got)
+	}
+}
+
+func TestHTTPCEPProviderTimeoutPerAttempt(t *testing.T) {
+	provider, hits := cepStub(t, func(w http.ResponseWriter, r *http.Request, hit int32) {
+		// The first attempt hangs past the timeout; the retry answers
+		if hit == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Write([]byte(`{"cep":"01310-100","logradouro":"Avenida Paulista"}`))
+	})
+	provider.Timeout = 50 * time.Millisecond
+	provider.Retries = 1
+
+	start := time.Now()
+	address, err := provider.Lookup(context.Background(), "01310100")
+	if err != nil {
+		t.Fatalf("err = %v, want the retry to succeed", err)
+	}
+	if address.Logradouro != "Avenida Paulista" {
+		t.Errorf("Logradouro = %q", address.Logradouro)
+	}
+	if hits.Load() != 2 {
+		t.Errorf("made %d requests, want 2", hits.Load())
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("lookup took %v; the timeout didn't cut the first attempt", elapsed)
+	}
+}
+
+// failingCEPProvider can't answer at all
+type failingCEPProvider struct{}
+
+func (failingCEPProvider) Lookup(context.Context, string) (*CEPResponse, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestChainCEPProvider(t *testing.T) {
+	mock := NewMockCEPProvider()
+	tests := []struct {
+		name  string
+		chain ChainCEPProvider
+		cep   string
+		err   error
+	}{
+		{"falls through to a provider that knows it", ChainCEPProvider{NewDatasetCEPProvider(nil), failingCEPProvider{}, mock}, "01310100", nil},
+		{"not found by every provider", ChainCEPProvider{NewDatasetCEPProvider(nil), mock}, "99999999", ErrCEPNotFound},
+		{"a failure makes it unavailable", ChainCEPProvider{failingCEPProvider{}, mock}, "99999999", ErrCEPUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, err := tt.chain.Lookup(context.Background(), tt.cep)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && address == nil {
+				t.Error("no address for a CEP a provider knows")
+			}
+		})
+	}
+}
+
+// countingCEPProvider knows every CEP not starting with 9 and counts lookups
+type countingCEPProvider struct {
+	lookups map[string]int
+}
+
+func (p *countingCEPProvider) Lookup(_ context.Context, cep string) (*CEPResponse, error) {
+	p.lookups[cep]++
+	if cep[0] == '9' {
+		return nil, ErrCEPNotFound
+	}
+	return &CEPResponse{CEP: cep}, nil
+}
+
+func TestCachedCEPProviderNegativeTTLCappedAtTTL(t *testing.T) {
+	next := &countingCEPProvider{lookups: map[string]int{}}
+	cache := NewCachedCEPProvider(next, 20*time.Millisecond, time.Hour, 10)
+	if cache.negativeTTL != cache.ttl {
+		t.Errorf("negativeTTL = %v, want it capped at ttl (%v)", cache.negativeTTL, cache.ttl)
+	}
+
+	ctx := context.Background()
+	for range 2 {
+		if _, err := cache.Lookup(ctx, "99999999"); !errors.Is(err, ErrCEPNotFound) {
+			t.Fatalf("err = %v, want ErrCEPNotFound", err)
+		}
+	}
+	if next.lookups["99999999"] != 1 {
+		t.Fatalf("unknown CEP looked up %d times, want the second answered from cache", next.lookups["99999999"])
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	cache.Lookup(ctx, "99999999")
+	if next.lookups["99999999"] != 2 {
+		t.Errorf("unknown CEP looked up %d times, want it looked up again past ttl", next.lookups["99999999"])
+	}
+}
+
+func TestCachedCEPProviderEvictsLeastRecentlyUsed(t *testing.T) {
+	next := &countingCEPProvider{lookups: map[string]int{}}
+	cache := NewCachedCEPProvider(next, time.Hour, time.Minute, 3)
+
+	ctx := context.Background()
+	// 11111111 is used again before 44444444 comes in, so 22222222 goes
+	for _, cep := range []string{"11111111", "22222222", "33333333", "11111111", "44444444", "11111111", "22222222"} {
+		if _, err := cache.Lookup(ctx, cep); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]int{"11111111": 1, "22222222": 2, "33333333": 1, "44444444": 1}
+	for cep, lookups := range want {
+		if next.lookups[cep] != lookups {
+			t.Errorf("%s looked up %d times, want %d", cep, next.lookups[cep], lookups)
+		}
+	}
+	if len(cache.entries) != 3 || cache.recent.Len() != 3 {
+		t.Errorf("cache holds %d entries (%d in order), want 3", len(cache.entries), cache.recent.Len())
+	}
+}
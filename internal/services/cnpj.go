@@ -0,0 +1,124 @@
+package services
+
+import (
+	"strings"
+)
+
+// cnpjLength is the number of characters of an unformatted CNPJ: 12
+// identifier characters (digits, or also letters since the 2026
+// alphanumeric format) followed by 2 numeric check digits
+const cnpjLength = 14
+
+var (
+	cnpjFirstWeights  = []int{5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+	cnpjSecondWeights = []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+)
+
+// ValidateCNPJ validates a Brazilian CNPJ, numeric or alphanumeric. Data
+// carries the number formatted as far as it was typed, so the field can be
+// masked even while it is still incomplete.
+func (fs *FormsService) ValidateCNPJ(cnpj string) *ValidationResult {
+	cnpj = normalizeCNPJ(cnpj)
+	formatted := fs.FormatCNPJ(cnpj)
+
+	if len(cnpj) != cnpjLength {
+		return &ValidationResult{
+			Valid:   false,
+			Message: "CNPJ deve ter 14 caracteres",
+			Data:    formatted,
+		}
+	}
+
+	for i := 0; i < cnpjLength; i++ {
+		if !isCNPJChar(cnpj[i], i) {
+			return &ValidationResult{
+				Valid:   false,
+				Message: "CNPJ contém caracteres inválidos",
+				Data:    formatted,
+			}
+		}
+	}
+
+	// Repeated sequences such as 00.000.000/0000-00 pass the check digits
+	if strings.Count(cnpj, cnpj[:1]) == cnpjLength {
+		return &ValidationResult{
+			Valid:   false,
+			Message: "CNPJ inválido",
+			Data:    formatted,
+		}
+	}
+
+	if cnpjCheckDigit(cnpj[:12], cnpjFirstWeights) != cnpj[12] ||
+		cnpjCheckDigit(cnpj[:13], cnpjSecondWeights) != cnpj[13] {
+		return &ValidationResult{
+			Valid:   false,
+			Message: "CNPJ inválido",
+			Data:    formatted,
+		}
+	}
+
+	return &ValidationResult{
+		Valid:   true,
+		Message: "CNPJ válido",
+		Data:    formatted,
+	}
+}
+
+// FormatCNPJ applies the XX.XXX.XXX/XXXX-XX mask to the characters typed so far
+func (fs *FormsService) FormatCNPJ(cnpj string) string {
+	cnpj = normalizeCNPJ(cnpj)
+	if len(cnpj) > cnpjLength {
+		cnpj = cnpj[:cnpjLength]
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(cnpj); i++ {
+		switch i {
+		case 2, 5:
+			b.WriteByte('.')
+		case 8:
+			b.WriteByte('/')
+		case 12:
+			b.WriteByte('-')
+		}
+		b.WriteByte(cnpj[i])
+	}
+	return b.String()
+}
+
+// normalizeCNPJ uppercases and drops the mask and anything else that can't
+// be part of a CNPJ
+func normalizeCNPJ(cnpj string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(cnpj) {
+		if (r >= '0' && r <= '9') || (r >= 'A' && r <= 'Z') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// isCNPJChar reports whether c is allowed at position i: letters only in the
+// identifier, check digits are always numeric
+func isCNPJChar(c byte, i int) bool {
+	if c >= '0' && c <= '9' {
+		return true
+	}
+	return i < 12 && c >= 'A' && c <= 'Z'
+}
+
+// cnpjCheckDigit computes a modulo 11 check digit. Each character is worth
+// its ASCII code minus 48, which keeps digits at their face value and is how
+// the alphanumeric CNPJ extends the classic algorithm.
+func cnpjCheckDigit(base string, weights []int) byte {
+	sum := 0
+	for i := 0; i < len(base); i++ {
+		sum += int(base[i]-'0') * weights[i]
+	}
+
+	remainder := sum % 11
+	if remainder < 2 {
+		return '0'
+	}
+	return byte('0' + 11 - remainder)
+}
@@ -0,0 +1,64 @@
+package services
+
+import "testing"
+
+func TestValidateCNPJ(t *testing.T) {
+	tests := []struct {
+		name    string
+		cnpj    string
+		valid   bool
+		message string
+		data    string
+	}{
+		{"numeric", "11222333000181", true, "CNPJ válido", "11.222.333/0001-81"},
+		{"numeric, masked", "11.444.777/0001-61", true, "CNPJ válido", "11.444.777/0001-61"},
+		{"numeric, first check digit 0", "33.000.167/0001-01", true, "CNPJ válido", "33.000.167/0001-01"},
+		{"numeric, leading zeros", "00.000.000/0001-91", true, "CNPJ válido", "00.000.000/0001-91"},
+		{"alphanumeric", "12ABC34501DE35", true, "CNPJ válido", "12.ABC.345/01DE-35"},
+		{"alphanumeric, masked", "12.ABC.345/01DE-35", true, "CNPJ válido", "12.ABC.345/01DE-35"},
+		{"alphanumeric, lowercase", "12.abc.345/01de-35", true, "CNPJ válido", "12.ABC.345/01DE-35"},
+		{"masked with spaces", " 11 222 333 0001 81 ", true, "CNPJ válido", "11.222.333/0001-81"},
+		{"wrong first check digit", "11222333000191", false, "CNPJ inválido", "11.222.333/0001-91"},
+		{"wrong second check digit", "11222333000182", false, "CNPJ inválido", "11.222.333/0001-82"},
+		{"alphanumeric, wrong check digits", "12ABC34501DE53", false, "CNPJ inválido", "12.ABC.345/01DE-53"},
+		{"letter in the check digits", "12ABC34501DE3A", false, "CNPJ contém caracteres inválidos", "12.ABC.345/01DE-3A"},
+		{"repeated zeros", "00.000.000/0000-00", false, "CNPJ inválido", "00.000.000/0000-00"},
+		{"repeated digit", "11111111111111", false, "CNPJ inválido", "11.111.111/1111-11"},
+		{"too short, masked as typed", "11.222.3", false, "CNPJ deve ter 14 caracteres", "11.222.3"},
+		{"too long", "112223330001810", false, "CNPJ deve ter 14 caracteres", "11.222.333/0001-81"},
+		{"empty", "", false, "CNPJ deve ter 14 caracteres", ""},
+	}
+
+	fs := NewFormsService(nil, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := fs.ValidateCNPJ(tt.cnpj)
+			if result.Valid != tt.valid || result.Message != tt.message || result.Data != tt.data {
+				t.Errorf("ValidateCNPJ(%q) = %v %q %q, want %v %q %q",
+					tt.cnpj, result.Valid, result.Message, result.Data, tt.valid, tt.message, tt.data)
+			}
+		})
+	}
+}
+
+func TestCNPJCheckDigit(t *testing.T) {
+	tests := []struct {
+		base    string
+		weights []int
+		want    byte
+	}{
+		{"112223330001", cnpjFirstWeights, '8'},
+		{"1122233300018", cnpjSecondWeights, '1'},
+		// Letters are worth their ASCII code minus 48: A = 17, B = 18...
+		{"12ABC34501DE", cnpjFirstWeights, '3'},
+		{"12ABC34501DE3", cnpjSecondWeights, '5'},
+		// Remainders 0 and 1 give 0
+		{"330001670001", cnpjFirstWeights, '0'},
+	}
+
+	for _, tt := range tests {
+		if got := cnpjCheckDigit(tt.base, tt.weights); got != tt.want {
+			t.Errorf("cnpjCheckDigit(%q) = %c, want %c", tt.base, got, tt.want)
+		}
+	}
+}
@@ -16,6 +16,7 @@ type DashboardService struct {
 	stopChan     chan struct{}
 	activities   []ActivityItem
 	activitiesMu sync.RWMutex
+	spamGuard    *SpamGuard
 }
 
 type DashboardStats struct {
@@ -44,6 +45,10 @@ type DashboardStats struct {
 	FormsActivity      float64 `json:"formsActivity"`
 	ComponentsActivity float64 `json:"componentsActivity"`
 
+	// Spam dropped by the public forms
+	SpamBlocked int64                `json:"spamBlocked"`
+	SpamReasons map[SpamReason]int64 `json:"spamReasons"`
+
 	// Metadata
 	LastUpdate time.Time `json:"lastUpdate"`
 }
@@ -90,6 +95,11 @@ func NewDashboardService() *DashboardService {
 	return ds
 }
 
+// UseSpamGuard reports the spam counters of guard with the stats
+func (ds *DashboardService) UseSpamGuard(guard *SpamGuard) {
+	ds.spamGuard = guard
+}
+
 // GetStats returns current dashboard statistics
 func (ds *DashboardService) GetStats() *DashboardStats {
 	ds.mu.RLock()
@@ -105,6 +115,11 @@ func (ds *DashboardService) GetStats() *DashboardStats {
 	if stats.MemoryPercent > 100 {
 		stats.MemoryPercent = float64(rand.Intn(40)) + 30
 	}
+	if ds.spamGuard != nil {
+		spam := ds.spamGuard.Stats()
+		stats.SpamBlocked = spam.Blocked
+		stats.SpamReasons = spam.Reasons
+	}
 	stats.LastUpdate = time.Now()
 
 	return &stats
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// emailLookupTimeout bounds the DNS checks of a single address
+const emailLookupTimeout = 2 * time.Second
+
+// DomainResolver answers the DNS queries behind email checks; *net.Resolver
+// satisfies it
+type DomainResolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// defaultDisposableDomains are well-known throwaway mailbox providers, used
+// until UseDisposableDomains replaces them
+var defaultDisposableDomains = []string{
+	"10minutemail.com", "dispostable.com", "getnada.com", "guerrillamail.com",
+	"guerrillamail.net", "mailinator.com", "maildrop.cc", "mintemail.com",
+	"sharklasers.com", "temp-mail.org", "tempmail.com", "throwawaymail.com",
+	"trashmail.com", "yopmail.com",
+}
+
+// commonEmailDomains are the providers typos are corrected to, in order of
+// preference when two are equally close
+var commonEmailDomains = []string{
+	"gmail.com", "hotmail.com", "outlook.com", "yahoo.com", "yahoo.com.br",
+	"icloud.com", "live.com", "msn.com", "bol.com.br", "uol.com.br",
+	"terra.com.br", "globo.com", "ig.com.br", "protonmail.com",
+	"hotmail.com.br", "outlook.com.br",
+}
+
+// knownEmailDomains are real providers close enough to a common one to look
+// like a typo of it ("mail.com" is one letter away from "gmail.com")
+var knownEmailDomains = map[string]bool{
+	"mail.com": true, "aol.com": true, "gmx.com": true, "gmx.net": true,
+	"me.com": true, "mac.com": true, "zoho.com": true, "yandex.com": true,
+	"ymail.com": true, "proton.me": true, "pm.me": true, "email.com": true,
+}
+
+// UseDomainResolver turns on the MX/A checks of ValidateEmail and the form
+// submissions; without a resolver only the syntax and domain lists are checked
+func (fs *FormsService) UseDomainResolver(resolver DomainResolver) {
+	fs.emailResolver = resolver
+}
+
+// UseDisposableDomains replaces the list of blocked disposable domains;
+// subdomains of a listed domain are blocked too
+func (fs *FormsService) UseDisposableDomains(domains []string) {
+	fs.disposableDomains = make(map[string]bool, len(domains))
+	for _, domain := range domains {
+		if ascii, err := idna.Lookup.ToASCII(strings.TrimSpace(domain)); err == nil && ascii != "" {
+			fs.disposableDomains[ascii] = true
+		}
+	}
+}
+
+// LoadDisposableDomains reads a domain list, one per line; blank lines and
+// lines starting with # are skipped
+func LoadDisposableDomains(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var domains []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, line)
+	}
+	return domains, scanner.Err()
+}
+
+// ValidateEmail validates an address beyond its syntax: disposable domains
+// are refused and, with a resolver, so are domains that can't receive mail.
+// suggestion is the address with a likely domain typo fixed, or "".
+func (fs *FormsService) ValidateEmail(ctx context.Context, email string) (result *ValidationResult, suggestion string) {
+	if result := fieldResult(fs.newsletterRules, "email", email); !result.Valid {
+		return result, ""
+	}
+
+	email = strings.TrimSpace(email)
+	if message := fs.checkEmailDomain(ctx, email); message != "" {
+		return &ValidationResult{
+			Valid:   false,
+			Message: message,
+		}, suggestEmail(email)
+	}
+
+	return &ValidationResult{
+		Valid:   true,
+		Message: fs.newsletterRules.ValidMessage("email"),
+	}, suggestEmail(email)
+}
+
+// checkEmailDomain returns why mail can't be delivered to a syntactically
+// valid address, or "" if it can (or if DNS couldn't tell)
+func (fs *FormsService) checkEmailDomain(ctx context.Context, email string) string {
+	domain, err := emailDomain(email)
+	if err != nil {
+		return "Domínio de email inválido"
+	}
+
+	for parent := domain; parent != ""; {
+		if fs.disposableDomains[parent] {
+			return "Emails temporários não são aceitos"
+		}
+		_, parent, _ = strings.Cut(parent, ".")
+	}
+
+	if fs.emailResolver == nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, emailLookupTimeout)
+	defer cancel()
+
+	records, err := fs.emailResolver.LookupMX(ctx, domain)
+	if err == nil && len(records) > 0 {
+		// A single "." record is a null MX (RFC 7505): no mail accepted
+		if len(records) == 1 && (records[0].Host == "." || records[0].Host == "") {
+			return "Este domínio não recebe emails"
+		}
+		return ""
+	}
+	if err != nil && !isNotFound(err) {
+		return ""
+	}
+
+	// No MX: mail goes to the domain's own address (RFC 5321, section 5.1)
+	if _, err := fs.emailResolver.LookupHost(ctx, domain); err != nil && isNotFound(err) {
+		return "Domínio de email não existe"
+	}
+	return ""
+}
+
+// isNotFound tells a domain that doesn't exist apart from a lookup that failed
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// emailDomain returns the domain of an address in lowercase ASCII, with
+// internationalized names in punycode
+func emailDomain(email string) (string, error) {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return "", errors.New("email: missing @")
+	}
+	return idna.Lookup.ToASCII(strings.ToLower(email[at+1:]))
+}
+
+// validEmailSyntax checks an address, including internationalized ones
+// (RFC 6531): UTF-8 letters are allowed in the local part and the domain
+// must be a valid IDN
+func validEmailSyntax(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return false
+	}
+	local, domain := email[:at], email[at+1:]
+
+	if len(local) > 64 || strings.HasPrefix(local, ".") || strings.HasSuffix(local, ".") ||
+		strings.Contains(local, "..") || !utf8.ValidString(local) {
+		return false
+	}
+	for _, r := range local {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(".!#$%&'*+/=?^_`{|}~-", r) {
+			return false
+		}
+	}
+
+	ascii, err := idna.Lookup.ToASCII(strings.ToLower(domain))
+	if err != nil || len(ascii) > 253 {
+		return false
+	}
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		return false
+	}
+
+	// The TLD is letters only, or punycode for internationalized TLDs
+	tld := labels[len(labels)-1]
+	if strings.HasPrefix(tld, "xn--") {
+		return len(tld) > 4
+	}
+	if len(tld) < 2 {
+		return false
+	}
+	for _, r := range tld {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// suggestEmail fixes a likely typo in the domain of email ("gmial.com" →
+// "gmail.com"), returning "" when the domain looks right
+func suggestEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	local, domain := email[:at], strings.ToLower(email[at+1:])
+	if knownEmailDomains[domain] {
+		return ""
+	}
+
+	// Short domains need a closer match to avoid silly suggestions
+	limit := 2
+	if len(domain) <= 8 {
+		limit = 1
+	}
+
+	best, bestDistance := "", limit+1
+	for _, candidate := range commonEmailDomains {
+		if candidate == domain {
+			return ""
+		}
+		if d := editDistance(domain, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return local + "@" + best
+}
+
+// editDistance is the optimal string alignment distance: insertions,
+// deletions, substitutions and swaps of adjacent characters cost one each
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	rows := make([][]int, len(ra)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(rb)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(ra)][len(rb)]
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeResolver answers from fixed records; names it doesn't know don't exist
+type fakeResolver struct {
+	mx    map[string][]*net.MX
+	hosts map[string][]string
+
+	// hang makes every lookup wait for the context, as a dead DNS server does
+	hang bool
+
+	queried []string
+}
+
+func notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	r.queried = append(r.queried, name)
+	if r.hang {
+		<-ctx.Done()
+		return nil, &net.DNSError{Err: ctx.Err().Error(), Name: name, IsTimeout: true}
+	}
+	if records, ok := r.mx[name]; ok {
+		return records, nil
+	}
+	return nil, notFound(name)
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if r.hang {
+		<-ctx.Done()
+		return nil, &net.DNSError{Err: ctx.Err().Error(), Name: host, IsTimeout: true}
+	}
+	if addrs, ok := r.hosts[host]; ok {
+		return addrs, nil
+	}
+	return nil, notFound(host)
+}
+
+func TestCheckEmailDomain(t *testing.T) {
+	resolver := &fakeResolver{
+		mx: map[string][]*net.MX{
+			"example.com.br":      {{Host: "mx1.example.com.br.", Pref: 10}, {Host: "mx2.example.com.br.", Pref: 20}},
+			"nomail.example":      {{Host: ".", Pref: 0}},
+			"xn--caf-dma.com.br":  {{Host: "mx.xn--caf-dma.com.br.", Pref: 10}},
+			"xn--tst-bma.example": {{Host: ".", Pref: 0}},
+			"mx-and-null.example": {{Host: ".", Pref: 0}, {Host: "mx.mx-and-null.example.", Pref: 10}},
+		},
+		hosts: map[string][]string{
+			"a-only.example":    {"192.0.2.10"},
+			"aaaa-only.example": {"2001:db8::10"},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		email string
+		want  string
+	}{
+		{"MX present", "ana@example.com.br", ""},
+		{"no MX, A record", "ana@a-only.example", ""},
+		{"no MX, AAAA record", "ana@aaaa-only.example", ""},
+		{"null MX", "ana@nomail.example", "Este domínio não recebe emails"},
+		{"null MX among real ones", "ana@mx-and-null.example", ""},
+		{"NXDOMAIN", "ana@nao-existe.example", "Domínio de email não existe"},
+		{"disposable domain", "ana@yopmail.com", "Emails temporários não são aceitos"},
+		{"disposable through a subdomain", "ana@caixa.mailinator.com", "Emails temporários não são aceitos"},
+		{"disposable, mixed case", "ana@Sub.YopMail.com", "Emails temporários não são aceitos"},
+		{"IDNA domain with MX", "josé@café.com.br", ""},
+		{"IDNA domain with null MX", "ana@tést.example", "Este domínio não recebe emails"},
+		{"invalid IDNA domain", "ana@exa_mple..com", "Domínio de email inválido"},
+	}
+
+	fs := NewFormsService(nil, nil)
+	fs.UseDomainResolver(resolver)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fs.checkEmailDomain(context.Background(), tt.email); got != tt.want {
+				t.Errorf("checkEmailDomain(%q) = %q, want %q", tt.email, got, tt.want)
+			}
+		})
+	}
+
+	// The resolver sees internationalized names in punycode
+	resolver.queried = nil
+	fs.checkEmailDomain(context.Background(), "josé@café.com.br")
+	if len(resolver.queried) != 1 || resolver.queried[0] != "xn--caf-dma.com.br" {
+		t.Errorf("resolver queried %v, want [xn--caf-dma.com.br]", resolver.queried)
+	}
+}
+
+func TestCheckEmailDomainTimeoutIsNotInvalid(t *testing.T) {
+	fs := NewFormsService(nil, nil)
+	fs.UseDomainResolver(&fakeResolver{hang: true})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if got := fs.checkEmailDomain(ctx, "ana@lento.example"); got != "" {
+		t.Errorf("checkEmailDomain on a timeout = %q, want \"\" (can't tell)", got)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("lookup took %v, want it bounded by the context", elapsed)
+	}
+}
+
+func TestCheckEmailDomainWithoutResolver(t *testing.T) {
+	fs := NewFormsService(nil, nil)
+	if got := fs.checkEmailDomain(context.Background(), "ana@nao-existe.example"); got != "" {
+		t.Errorf("checkEmailDomain without a resolver = %q, want only the domain lists checked", got)
+	}
+}
+
+func TestValidateEmail(t *testing.T) {
+	fs := NewFormsService(nil, nil)
+	fs.UseDomainResolver(&fakeResolver{mx: map[string][]*net.MX{
+		"gmial.com":          {{Host: "mx.gmial.com.", Pref: 10}},
+		"xn--caf-dma.com.br": {{Host: "mx.xn--caf-dma.com.br.", Pref: 10}},
+	}})
+
+	tests := []struct {
+		email      string
+		valid      bool
+		suggestion string
+	}{
+		{"josé@café.com.br", true, ""},
+		{"ana@gmial.com", true, "ana@gmail.com"},
+		{"ana@mailinator.com", false, ""},
+		{"ana@", false, ""},
+		{"ana..silva@example.com", false, ""},
+	}
+
+	for _, tt := range tests {
+		result, suggestion := fs.ValidateEmail(context.Background(), tt.email)
+		if result.Valid != tt.valid || suggestion != tt.suggestion {
+			t.Errorf("ValidateEmail(%q) = %v, %q (%s); want %v, %q",
+				tt.email, result.Valid, suggestion, result.Message, tt.valid, tt.suggestion)
+		}
+	}
+}
+
+func TestSuggestEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"ana@gmial.com", "ana@gmail.com"},
+		{"ana@gmail.con", "ana@gmail.com"},
+		{"ana@hotmial.com", "ana@hotmail.com"},
+		{"ana@hotmail.com.bt", "ana@hotmail.com.br"},
+		{"ana@yaho.com.br", "ana@yahoo.com.br"},
+		{"Ana.Silva@OUTLOK.COM", "Ana.Silva@outlook.com"},
+		{"ana@gmail.com", ""},
+		{"ana@mail.com", ""},
+		{"ana@empresa.com.br", ""},
+		{"ana@ig.com", ""},
+		{"sem-arroba", ""},
+	}
+
+	for _, tt := range tests {
+		if got := suggestEmail(tt.email); got != tt.want {
+			t.Errorf("suggestEmail(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
@@ -1,23 +1,94 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"mime/multipart"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
+
+	"showcase-datastar-go/internal/search"
+	"showcase-datastar-go/internal/signing"
+	"showcase-datastar-go/internal/validation"
+)
+
+// Success messages, shared with the decoys answered to spam
+const (
+	contactSentMessage       = "Mensagem enviada com sucesso!"
+	newsletterPendingMessage = "Quase lá! Enviamos um link de confirmação para "
 )
 
+// defaultCounterLength is the limit of fields without a max rule, such as
+// the character counter demo
+const defaultCounterLength = 100
+
+// formValidators are the custom rules available to form struct tags
+var formValidators = map[string]validation.Func{
+	"email": func(value, _ string) bool {
+		return validEmailSyntax(value)
+	},
+	"housenumber": func(value, _ string) bool {
+		return houseNumberRegex.MatchString(value)
+	},
+	"complement": func(value, _ string) bool {
+		return complementRegex.MatchString(value)
+	},
+}
+
 type FormsService struct {
-	newsletterSubscribers []NewsletterSubscriber
-	contactMessages       []ContactMessage
+	repository FormsRepository
+
+	// mu serializes the read-modify-write of subscribers and the admin
+	// bulk changes
+	mu sync.Mutex
+
+	contactRules    *validation.Schema[ContactForm]
+	newsletterRules *validation.Schema[NewsletterForm]
+	addressRules    *validation.Schema[AddressForm]
+
+	cepProvider CEPProvider
+	cepLocator  CEPLocator
+
+	emailResolver     DomainResolver
+	disposableDomains map[string]bool
+
+	signer           *signing.Signer
+	newsletterMailer NewsletterMailer
+	contactMailer    ContactMailer
+	baseURL          string
+
+	blobs BlobStore
+}
+
+// ContactForm is the contact form as posted by the page
+type ContactForm struct {
+	Name    string `json:"name" validate:"required,min=2,max=100" label:"Nome" msg:"max=Nome muito longo"`
+	Email   string `json:"email" validate:"required,email" label:"Email"`
+	Subject string `json:"subject" validate:"required,oneof=duvida feedback parceria bug outro" label:"Assunto"`
+	Message string `json:"message" validate:"required,min=10,max=500" label:"Mensagem" msg:"required=Mensagem é obrigatória|min=Mensagem muito curta (mínimo {param} caracteres)|max=Mensagem muito longa (máximo {param} caracteres)|valid=Mensagem válida"`
+}
+
+// NewsletterForm is the newsletter signup form
+type NewsletterForm struct {
+	Name  string `json:"name" validate:"max=100" label:"Nome" msg:"max=Nome muito longo"`
+	Email string `json:"email" validate:"required,email" label:"Email"`
 }
 
 type NewsletterSubscriber struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID             string           `json:"id"`
+	Name           string           `json:"name"`
+	Email          string           `json:"email"`
+	Status         SubscriberStatus `json:"status"`
+	CreatedAt      time.Time        `json:"createdAt"`
+	ConfirmedAt    *time.Time       `json:"confirmedAt,omitempty"`
+	UnsubscribedAt *time.Time       `json:"unsubscribedAt,omitempty"`
 }
 
 type ContactMessage struct {
@@ -27,6 +98,9 @@ type ContactMessage struct {
 	Subject   string    `json:"subject"`
 	Message   string    `json:"message"`
 	CreatedAt time.Time `json:"createdAt"`
+	Read      bool      `json:"read,omitempty"`
+
+	Attachments []Attachment `json:"attachments,omitempty"`
 }
 
 type ValidationResult struct {
@@ -35,112 +109,75 @@ type ValidationResult struct {
 	Data    string `json:"data,omitempty"`
 }
 
-type CEPResponse struct {
-	CEP        string `json:"cep"`
-	Logradouro string `json:"logradouro"`
-	Bairro     string `json:"bairro"`
-	Localidade string `json:"localidade"`
-	UF         string `json:"uf"`
+// NewFormsService creates the service; a nil cepProvider falls back to the
+// built-in mock CEPs. cepLocator, if set, recognizes CEPs that no provider
+// knows by their range.
+func NewFormsService(cepProvider CEPProvider, cepLocator CEPLocator) *FormsService {
+	if cepProvider == nil {
+		cepProvider = NewMockCEPProvider()
+	}
+
+	fs := &FormsService{
+		repository:       NewMemoryFormsRepository(),
+		contactRules:     validation.MustParse[ContactForm](formValidators),
+		newsletterRules:  validation.MustParse[NewsletterForm](formValidators),
+		addressRules:     validation.MustParse[AddressForm](formValidators),
+		cepProvider:      cepProvider,
+		cepLocator:       cepLocator,
+		signer:           signing.NewRandom(),
+		newsletterMailer: LogNewsletterMailer{},
+		baseURL:          "http://localhost:8080",
+	}
+	fs.UseDisposableDomains(defaultDisposableDomains)
+	return fs
+}
+
+// UseRepository replaces the in-memory storage of subscribers and contact
+// messages; call it before serving requests
+func (fs *FormsService) UseRepository(repository FormsRepository) {
+	fs.repository = repository
 }
 
-func NewFormsService() *FormsService {
-	return &FormsService{
-		newsletterSubscribers: []NewsletterSubscriber{},
-		contactMessages:       []ContactMessage{},
+// lastRecordID keeps IDs unique when two records are created within the
+// same clock tick
+var lastRecordID atomic.Int64
+
+// newRecordID returns prefix_<unix nanoseconds>, bumped past the last ID
+// handed out
+func newRecordID(prefix string) string {
+	for {
+		last := lastRecordID.Load()
+		next := max(time.Now().UnixNano(), last+1)
+		if lastRecordID.CompareAndSwap(last, next) {
+			return fmt.Sprintf("%s_%d", prefix, next)
+		}
 	}
 }
 
-// ValidateEmail validates email format
-func (fs *FormsService) ValidateEmail(email string) *ValidationResult {
-	if email == "" {
+// ValidateField validates a single contact form field against its rules
+func (fs *FormsService) ValidateField(field, value string) *ValidationResult {
+	return fieldResult(fs.contactRules, field, value)
+}
+
+// fieldResult validates one field of a schema as a ValidationResult
+func fieldResult[T any](rules *validation.Schema[T], field, value string) *ValidationResult {
+	message, ok := rules.ValidateField(field, value)
+	if !ok {
 		return &ValidationResult{
 			Valid:   false,
-			Message: "Email é obrigatório",
+			Message: "Campo não reconhecido",
 		}
 	}
-
-	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
-	if !emailRegex.MatchString(email) {
+	if message != "" {
 		return &ValidationResult{
 			Valid:   false,
-			Message: "Email inválido",
+			Message: message,
 		}
 	}
 
 	return &ValidationResult{
 		Valid:   true,
-		Message: "Email válido",
-	}
-}
-
-// ValidateField validates generic fields
-func (fs *FormsService) ValidateField(field, value string) *ValidationResult {
-	switch field {
-	case "name":
-		if value == "" {
-			return &ValidationResult{
-				Valid:   false,
-				Message: "Nome é obrigatório",
-			}
-		}
-		if len(value) < 2 {
-			return &ValidationResult{
-				Valid:   false,
-				Message: "Nome deve ter pelo menos 2 caracteres",
-			}
-		}
-		if len(value) > 100 {
-			return &ValidationResult{
-				Valid:   false,
-				Message: "Nome muito longo",
-			}
-		}
-		return &ValidationResult{
-			Valid:   true,
-			Message: "Nome válido",
-		}
-
-	case "subject":
-		if value == "" {
-			return &ValidationResult{
-				Valid:   false,
-				Message: "Assunto é obrigatório",
-			}
-		}
-		return &ValidationResult{
-			Valid:   true,
-			Message: "Assunto válido",
-		}
-
-	case "message":
-		if value == "" {
-			return &ValidationResult{
-				Valid:   false,
-				Message: "Mensagem é obrigatória",
-			}
-		}
-		if len(value) < 10 {
-			return &ValidationResult{
-				Valid:   false,
-				Message: "Mensagem muito curta (mínimo 10 caracteres)",
-			}
-		}
-		if len(value) > 500 {
-			return &ValidationResult{
-				Valid:   false,
-				Message: "Mensagem muito longa (máximo 500 caracteres)",
-			}
-		}
-		return &ValidationResult{
-			Valid:   true,
-			Message: "Mensagem válida",
-		}
-
-	default:
-		return &ValidationResult{
-			Valid:   false,
-			Message: "Campo não reconhecido",
-		}
+		Message: rules.ValidMessage(field),
 	}
 }
 
@@ -229,232 +266,336 @@ func (fs *FormsService) formatCPF(cpf string) string {
 	return fmt.Sprintf("%s.%s.%s-%s", cpf[0:3], cpf[3:6], cpf[6:9], cpf[9:11])
 }
 
-// MaskPhone applies phone mask
+// MaskPhone applies the phone mask to the digits typed so far. Numbers
+// starting with + keep their country code; anything too long to be a
+// Brazilian number is left unmasked instead of cut, so ValidatePhone can
+// point it out.
 func (fs *FormsService) MaskPhone(phone string) string {
-	// Remove all non-digits
-	digits := regexp.MustCompile(`\D`).ReplaceAllString(phone, "")
+	phone = strings.TrimSpace(phone)
+	digits := onlyDigits(phone)
 
-	if len(digits) == 0 {
-		return ""
+	if strings.HasPrefix(phone, "+") {
+		if rest, ok := strings.CutPrefix(digits, brazilCountryCode); ok && rest != "" {
+			return "+" + brazilCountryCode + " " + maskNationalPhone(rest)
+		}
+		return "+" + digits
 	}
+	return maskNationalPhone(digits)
+}
 
-	// Apply mask based on length
-	if len(digits) <= 2 {
+// maskNationalPhone formats digits as (DD) NNNN-NNNN or (DD) NNNNN-NNNN
+func maskNationalPhone(digits string) string {
+	switch {
+	case len(digits) == 0:
+		return ""
+	case len(digits) <= 2:
 		return fmt.Sprintf("(%s", digits)
-	} else if len(digits) <= 7 {
+	case len(digits) <= 7:
 		return fmt.Sprintf("(%s) %s", digits[0:2], digits[2:])
-	} else if len(digits) <= 11 {
-		if len(digits) == 11 {
-			return fmt.Sprintf("(%s) %s-%s", digits[0:2], digits[2:7], digits[7:11])
-		} else {
-			return fmt.Sprintf("(%s) %s-%s", digits[0:2], digits[2:6], digits[6:])
-		}
+	case len(digits) <= 10:
+		return fmt.Sprintf("(%s) %s-%s", digits[0:2], digits[2:6], digits[6:])
+	case len(digits) == 11:
+		return fmt.Sprintf("(%s) %s-%s", digits[0:2], digits[2:7], digits[7:11])
+	default:
+		return digits
 	}
+}
 
-	// Max 11 digits
-	return fmt.Sprintf("(%s) %s-%s", digits[0:2], digits[2:7], digits[7:11])
+var cepRegex = regexp.MustCompile(`^\d{8}$`)
+
+// ValidateCEP validates a CEP and, when it exists, returns its address for
+// autofilling; the address has only city and UF if the CEP is known just by
+// its range
+func (fs *FormsService) ValidateCEP(ctx context.Context, cep string) (*ValidationResult, *AddressForm) {
+	result, address, _ := fs.lookupCEP(ctx, cep)
+	return result, address
 }
 
-// ValidateCEP validates and fetches CEP data
-func (fs *FormsService) ValidateCEP(cep string) *ValidationResult {
+// lookupCEP is ValidateCEP that also returns the provider error, telling a
+// CEP that doesn't exist apart from one that couldn't be checked
+func (fs *FormsService) lookupCEP(ctx context.Context, cep string) (*ValidationResult, *AddressForm, error) {
 	// Remove formatting
-	cep = strings.ReplaceAll(cep, "-", "")
-	cep = strings.ReplaceAll(cep, " ", "")
+	cep = normalizeCEP(cep)
 
 	// Basic validation
 	if len(cep) != 8 {
 		return &ValidationResult{
 			Valid:   false,
 			Message: "CEP deve ter 8 dígitos",
-		}
+		}, nil, nil
 	}
 
 	// Check if all digits
-	if !regexp.MustCompile(`^\d{8}$`).MatchString(cep) {
+	if !cepRegex.MatchString(cep) {
 		return &ValidationResult{
 			Valid:   false,
 			Message: "CEP deve conter apenas números",
+		}, nil, nil
+	}
+
+	// Outside every known range the CEP can't exist; no need to ask around
+	var located *CEPResponse
+	if fs.cepLocator != nil {
+		var ok bool
+		if located, ok = fs.cepLocator.Locate(cep); !ok {
+			return &ValidationResult{
+				Valid:   false,
+				Message: "CEP inválido",
+			}, nil, nil
 		}
 	}
 
-	// Mock CEP data (in real app, would call ViaCEP API)
-	cepData := fs.getMockCEPData(cep)
-	if cepData == nil {
+	cepData, err := fs.cepProvider.Lookup(ctx, cep)
+	if errors.Is(err, ErrCEPNotFound) && located != nil {
+		return &ValidationResult{
+			Valid:   true,
+			Message: "CEP válido, logradouro não encontrado",
+			Data:    formatLocation(located),
+		}, newAddress(cep, located), nil
+	}
+	if errors.Is(err, ErrCEPNotFound) {
 		return &ValidationResult{
 			Valid:   false,
 			Message: "CEP não encontrado",
-		}
+		}, nil, nil
+	}
+	if err != nil {
+		return &ValidationResult{
+			Valid:   false,
+			Message: "Consulta de CEP indisponível, tente novamente",
+		}, nil, err
 	}
 
 	return &ValidationResult{
 		Valid:   true,
 		Message: "CEP válido",
 		Data:    fmt.Sprintf("%s - %s, %s", cepData.Localidade, cepData.Bairro, cepData.UF),
-	}
+	}, newAddress(cep, cepData), nil
 }
 
-// getMockCEPData returns mock CEP data
-func (fs *FormsService) getMockCEPData(cep string) *CEPResponse {
-	mockData := map[string]*CEPResponse{
-		"01310100": {
-			CEP:        "01310-100",
-			Logradouro: "Avenida Paulista",
-			Bairro:     "Bela Vista",
-			Localidade: "São Paulo",
-			UF:         "SP",
-		},
-		"20040020": {
-			CEP:        "20040-020",
-			Logradouro: "Rua da Assembleia",
-			Bairro:     "Centro",
-			Localidade: "Rio de Janeiro",
-			UF:         "RJ",
-		},
-		"30112000": {
-			CEP:        "30112-000",
-			Logradouro: "Rua da Bahia",
-			Bairro:     "Centro",
-			Localidade: "Belo Horizonte",
-			UF:         "MG",
-		},
-		"40070110": {
-			CEP:        "40070-110",
-			Logradouro: "Rua Chile",
-			Bairro:     "Centro",
-			Localidade: "Salvador",
-			UF:         "BA",
-		},
-		"80010000": {
-			CEP:        "80010-000",
-			Logradouro: "Rua XV de Novembro",
-			Bairro:     "Centro",
-			Localidade: "Curitiba",
-			UF:         "PR",
-		},
+// formatLocation describes a CEP known only by its range
+func formatLocation(location *CEPResponse) string {
+	if location.Localidade == "" {
+		return location.UF
 	}
-
-	return mockData[cep]
+	return fmt.Sprintf("%s, %s", location.Localidade, location.UF)
 }
 
-// CountCharacters counts and validates character limits
+// CountCharacters counts characters against the field's max rule
 func (fs *FormsService) CountCharacters(text, field string) map[string]interface{} {
-	length := len(text)
+	length := utf8.RuneCountInString(text)
 
-	result := map[string]interface{}{
-		"length":    length,
-		"remaining": 0,
-		"maxLength": 100,
-		"progress":  0.0,
-	}
-
-	switch field {
-	case "message":
-		result["maxLength"] = 500
-		result["remaining"] = 500 - length
-		result["progress"] = float64(length) / 500.0 * 100
-	case "counter":
-		result["maxLength"] = 100
-		result["remaining"] = 100 - length
-		result["progress"] = float64(length) / 100.0 * 100
-	default:
-		result["remaining"] = 100 - length
-		result["progress"] = float64(length) / 100.0 * 100
+	maxLength := fs.contactRules.MaxLength(field)
+	if maxLength == 0 {
+		maxLength = defaultCounterLength
 	}
 
-	if result["progress"].(float64) > 100 {
-		result["progress"] = 100.0
+	progress := float64(length) / float64(maxLength) * 100
+	if progress > 100 {
+		progress = 100.0
 	}
 
-	return result
+	return map[string]interface{}{
+		"length":    length,
+		"remaining": maxLength - length,
+		"maxLength": maxLength,
+		"progress":  progress,
+	}
 }
 
-// SubmitNewsletter adds email to newsletter
-func (fs *FormsService) SubmitNewsletter(name, email string) *ValidationResult {
-	// Validate email
-	emailValidation := fs.ValidateEmail(email)
-	if !emailValidation.Valid {
-		return emailValidation
+// SubmitNewsletter signs an email up as a pending subscriber and mails
+// the link that confirms it; field errors are returned alongside an invalid
+// result
+func (fs *FormsService) SubmitNewsletter(ctx context.Context, form NewsletterForm) (*ValidationResult, validation.Errors) {
+	if errs := fs.newsletterRules.Validate(form); len(errs) > 0 {
+		return &ValidationResult{
+			Valid:   false,
+			Message: fs.newsletterRules.First(errs),
+		}, errs
 	}
 
-	// Check if already subscribed
-	for _, subscriber := range fs.newsletterSubscribers {
-		if subscriber.Email == email {
-			return &ValidationResult{
-				Valid:   false,
-				Message: "Este email já está inscrito",
-			}
-		}
+	name, email := strings.TrimSpace(form.Name), strings.TrimSpace(form.Email)
+
+	if message := fs.checkEmailDomain(ctx, email); message != "" {
+		return &ValidationResult{
+			Valid:   false,
+			Message: message,
+		}, validation.Errors{"email": message}
 	}
 
-	// Add subscriber
-	subscriber := NewsletterSubscriber{
-		ID:        fmt.Sprintf("news_%d", time.Now().UnixNano()),
-		Name:      name,
-		Email:     email,
-		CreatedAt: time.Now(),
+	fs.mu.Lock()
+	subscriber, found, err := fs.repository.SubscriberByEmail(email)
+	if err != nil {
+		fs.mu.Unlock()
+		log.Printf("Erro ao consultar inscrições: %v", err)
+		return storageFailure(), nil
+	}
+	if found && subscriber.Status == SubscriberConfirmed {
+		fs.mu.Unlock()
+		return &ValidationResult{
+			Valid:   false,
+			Message: "Este email já está inscrito",
+		}, validation.Errors{"email": "Este email já está inscrito"}
 	}
 
-	fs.newsletterSubscribers = append(fs.newsletterSubscribers, subscriber)
+	// Pending subscribers get a new link; those who left start over
+	if found {
+		subscriber.Status = SubscriberPending
+		subscriber.UnsubscribedAt = nil
+		if name != "" {
+			subscriber.Name = name
+		}
+	} else {
+		subscriber = NewsletterSubscriber{
+			ID:        newRecordID("news"),
+			Name:      name,
+			Email:     email,
+			Status:    SubscriberPending,
+			CreatedAt: time.Now(),
+		}
+	}
+	err = fs.repository.SaveSubscriber(subscriber)
+	fs.mu.Unlock()
+	if err != nil {
+		log.Printf("Erro ao salvar inscrição de %s: %v", email, err)
+		return storageFailure(), nil
+	}
+
+	if err := fs.sendNewsletterConfirmation(ctx, subscriber); err != nil {
+		return &ValidationResult{
+			Valid:   false,
+			Message: "Não foi possível enviar o email de confirmação, tente novamente",
+		}, nil
+	}
 
 	return &ValidationResult{
 		Valid:   true,
-		Message: "Inscrito com sucesso!",
+		Message: newsletterPendingMessage + subscriber.Email,
 		Data:    subscriber.ID,
-	}
+	}, nil
 }
 
-// SubmitContact adds contact message
-func (fs *FormsService) SubmitContact(name, email, subject, message string) *ValidationResult {
-	// Validate required fields
-	if name == "" || email == "" || subject == "" || message == "" {
-		return &ValidationResult{
-			Valid:   false,
-			Message: "Todos os campos são obrigatórios",
+// SubmitContact adds contact message, with files attached if any; field
+// errors are returned alongside an invalid result
+func (fs *FormsService) SubmitContact(ctx context.Context, form ContactForm, files ...*multipart.FileHeader) (*ValidationResult, validation.Errors) {
+	errs := fs.contactRules.Validate(form)
+	if _, failed := errs["email"]; !failed {
+		if message := fs.checkEmailDomain(ctx, strings.TrimSpace(form.Email)); message != "" {
+			errs["email"] = message
 		}
 	}
-
-	// Validate email
-	emailValidation := fs.ValidateEmail(email)
-	if !emailValidation.Valid {
-		return emailValidation
+	attachments, message := fs.checkAttachments(files)
+	if message != "" {
+		errs["attachments"] = message
 	}
-
-	// Validate field lengths
-	nameValidation := fs.ValidateField("name", name)
-	if !nameValidation.Valid {
-		return nameValidation
-	}
-
-	messageValidation := fs.ValidateField("message", message)
-	if !messageValidation.Valid {
-		return messageValidation
+	if len(errs) > 0 {
+		return &ValidationResult{
+			Valid:   false,
+			Message: "Corrija os campos destacados",
+		}, errs
 	}
 
 	// Add contact message
 	contact := ContactMessage{
-		ID:        fmt.Sprintf("contact_%d", time.Now().UnixNano()),
-		Name:      name,
-		Email:     email,
-		Subject:   subject,
-		Message:   message,
+		ID:        newRecordID("contact"),
+		Name:      strings.TrimSpace(form.Name),
+		Email:     strings.TrimSpace(form.Email),
+		Subject:   form.Subject,
+		Message:   strings.TrimSpace(form.Message),
 		CreatedAt: time.Now(),
+
+		Attachments: attachments,
 	}
 
-	fs.contactMessages = append(fs.contactMessages, contact)
+	if err := fs.storeAttachments(ctx, contact.ID, attachments, files); err != nil {
+		log.Printf("Erro ao salvar anexos de %s: %v", contact.Email, err)
+		return storageFailure(), nil
+	}
+	if err := fs.repository.AddContactMessage(contact); err != nil {
+		log.Printf("Erro ao salvar mensagem de %s: %v", contact.Email, err)
+		fs.deleteAttachments(ctx, attachments)
+		return storageFailure(), nil
+	}
+
+	// The acknowledgement is a courtesy: the message is in either way
+	if fs.contactMailer != nil {
+		if err := fs.contactMailer.SendContactAcknowledgement(ctx, contact); err != nil {
+			log.Printf("Erro ao enviar confirmação de contato para %s: %v", contact.Email, err)
+		}
+	}
 
 	return &ValidationResult{
 		Valid:   true,
-		Message: "Mensagem enviada com sucesso!",
+		Message: contactSentMessage,
 		Data:    contact.ID,
+	}, nil
+}
+
+// storageFailure is the result when the repository can't be read or written
+func storageFailure() *ValidationResult {
+	return &ValidationResult{
+		Valid:   false,
+		Message: "Não foi possível salvar agora, tente novamente em instantes",
 	}
 }
 
-// GetNewsletterSubscribers returns all newsletter subscribers
-func (fs *FormsService) GetNewsletterSubscribers() []NewsletterSubscriber {
-	return fs.newsletterSubscribers
+// GetNewsletterSubscribers returns all newsletter subscribers, in any status
+func (fs *FormsService) GetNewsletterSubscribers() ([]NewsletterSubscriber, error) {
+	return fs.repository.Subscribers()
 }
 
 // GetContactMessages returns all contact messages
-func (fs *FormsService) GetContactMessages() []ContactMessage {
-	return fs.contactMessages
+func (fs *FormsService) GetContactMessages() ([]ContactMessage, error) {
+	return fs.repository.ContactMessages()
+}
+
+// contactMessageMapping describes how the generic engine reads contact messages
+func contactMessageMapping() search.Mapping[ContactMessage] {
+	return search.Mapping[ContactMessage]{
+		ID: func(m ContactMessage) string { return m.ID },
+		Fields: []search.Field[ContactMessage]{
+			{
+				Name:   "subject",
+				Text:   func(m ContactMessage) string { return m.Subject },
+				Phrase: 1.0,
+				Word:   0.6,
+			},
+			{
+				Name:   "message",
+				Text:   func(m ContactMessage) string { return m.Message },
+				Phrase: 0.7,
+				Word:   0.4,
+				Fuzzy:  0.2,
+			},
+			{
+				Name:   "name",
+				Text:   func(m ContactMessage) string { return m.Name },
+				Phrase: 0.8,
+				Word:   0.5,
+			},
+			{
+				Name:   "email",
+				Text:   func(m ContactMessage) string { return m.Email },
+				Phrase: 0.8,
+				Word:   0.5,
+				Embed:  -1,
+			},
+		},
+		// Require at least one word hit; fuzzy matches alone are too noisy here
+		MinScore: 0.3,
+	}
+}
+
+// SearchContactMessages searches contact messages by subject, body, name and email
+func (fs *FormsService) SearchContactMessages(ctx context.Context, query, mode string) (search.Result[ContactMessage], error) {
+	messages, err := fs.GetContactMessages()
+	if err != nil {
+		return search.Result[ContactMessage]{}, err
+	}
+	engine := search.New(contactMessageMapping(), messages)
+
+	return engine.Search(ctx, search.Query[ContactMessage]{
+		Text: query,
+		Mode: mode,
+	}), nil
 }
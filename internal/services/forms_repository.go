@@ -0,0 +1,558 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// FormsRepository stores what the forms collect. Implementations are safe
+// for concurrent use; read-modify-write sequences are serialized by the
+// FormsService.
+type FormsRepository interface {
+	// Subscriber returns the subscriber with that ID
+	Subscriber(id string) (NewsletterSubscriber, bool, error)
+
+	// SubscriberByEmail finds a subscriber by email, ignoring case
+	SubscriberByEmail(email string) (NewsletterSubscriber, bool, error)
+
+	// SaveSubscriber adds the subscriber or replaces the one with its ID
+	SaveSubscriber(subscriber NewsletterSubscriber) error
+
+	// Subscribers returns every subscriber, oldest first
+	Subscribers() ([]NewsletterSubscriber, error)
+
+	// AddContactMessage stores a new contact message
+	AddContactMessage(message ContactMessage) error
+
+	// ContactMessages returns every contact message, oldest first
+	ContactMessages() ([]ContactMessage, error)
+
+	// DeleteSubscribers removes the subscribers with those IDs and returns
+	// how many there were
+	DeleteSubscribers(ids []string) (int, error)
+
+	// MarkContactMessagesRead flags the messages with those IDs as read and
+	// returns how many weren't already
+	MarkContactMessagesRead(ids []string) (int, error)
+
+	// DeleteContactMessages removes the messages with those IDs and returns
+	// them, so their attachments can go too
+	DeleteContactMessages(ids []string) ([]ContactMessage, error)
+}
+
+// MemoryFormsRepository keeps everything in memory; it's lost on restart
+type MemoryFormsRepository struct {
+	mu          sync.RWMutex
+	subscribers []NewsletterSubscriber
+	byID        map[string]int
+	byEmail     map[string]int
+	messages    []ContactMessage
+}
+
+func NewMemoryFormsRepository() *MemoryFormsRepository {
+	return &MemoryFormsRepository{
+		subscribers: []NewsletterSubscriber{},
+		byID:        map[string]int{},
+		byEmail:     map[string]int{},
+		messages:    []ContactMessage{},
+	}
+}
+
+func (r *MemoryFormsRepository) Subscriber(id string) (NewsletterSubscriber, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i, ok := r.byID[id]
+	if !ok {
+		return NewsletterSubscriber{}, false, nil
+	}
+	return r.subscribers[i], true, nil
+}
+
+func (r *MemoryFormsRepository) SubscriberByEmail(email string) (NewsletterSubscriber, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	i, ok := r.byEmail[strings.ToLower(email)]
+	if !ok {
+		return NewsletterSubscriber{}, false, nil
+	}
+	return r.subscribers[i], true, nil
+}
+
+func (r *MemoryFormsRepository) SaveSubscriber(subscriber NewsletterSubscriber) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.saveSubscriber(subscriber)
+	return nil
+}
+
+func (r *MemoryFormsRepository) saveSubscriber(subscriber NewsletterSubscriber) {
+	if i, ok := r.byID[subscriber.ID]; ok {
+		delete(r.byEmail, strings.ToLower(r.subscribers[i].Email))
+		r.subscribers[i] = subscriber
+		r.byEmail[strings.ToLower(subscriber.Email)] = i
+		return
+	}
+
+	r.subscribers = append(r.subscribers, subscriber)
+	r.byID[subscriber.ID] = len(r.subscribers) - 1
+	r.byEmail[strings.ToLower(subscriber.Email)] = len(r.subscribers) - 1
+}
+
+func (r *MemoryFormsRepository) Subscribers() ([]NewsletterSubscriber, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]NewsletterSubscriber{}, r.subscribers...), nil
+}
+
+func (r *MemoryFormsRepository) AddContactMessage(message ContactMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+func (r *MemoryFormsRepository) ContactMessages() ([]ContactMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]ContactMessage{}, r.messages...), nil
+}
+
+func (r *MemoryFormsRepository) DeleteSubscribers(ids []string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.deleteSubscribers(idSet(ids)), nil
+}
+
+func (r *MemoryFormsRepository) deleteSubscribers(ids map[string]bool) int {
+	kept := r.subscribers[:0]
+	for _, subscriber := range r.subscribers {
+		if !ids[subscriber.ID] {
+			kept = append(kept, subscriber)
+		}
+	}
+	deleted := len(r.subscribers) - len(kept)
+	if deleted == 0 {
+		return 0
+	}
+
+	// Positions moved, so the indexes are rebuilt
+	r.subscribers = kept
+	r.byID = make(map[string]int, len(kept))
+	r.byEmail = make(map[string]int, len(kept))
+	for i, subscriber := range kept {
+		r.byID[subscriber.ID] = i
+		r.byEmail[strings.ToLower(subscriber.Email)] = i
+	}
+	return deleted
+}
+
+func (r *MemoryFormsRepository) MarkContactMessagesRead(ids []string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.markRead(idSet(ids)), nil
+}
+
+func (r *MemoryFormsRepository) markRead(ids map[string]bool) int {
+	marked := 0
+	for i := range r.messages {
+		if ids[r.messages[i].ID] && !r.messages[i].Read {
+			r.messages[i].Read = true
+			marked++
+		}
+	}
+	return marked
+}
+
+func (r *MemoryFormsRepository) DeleteContactMessages(ids []string) ([]ContactMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.deleteMessages(idSet(ids)), nil
+}
+
+func (r *MemoryFormsRepository) deleteMessages(ids map[string]bool) []ContactMessage {
+	var deleted []ContactMessage
+	kept := make([]ContactMessage, 0, len(r.messages))
+	for _, message := range r.messages {
+		if ids[message.ID] {
+			deleted = append(deleted, message)
+			continue
+		}
+		kept = append(kept, message)
+	}
+	r.messages = kept
+	return deleted
+}
+
+// matchingSubscribers returns the IDs among ids of existing subscribers, so
+// FileFormsRepository logs only changes that happen
+func (r *MemoryFormsRepository) matchingSubscribers(ids []string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []string
+	for id := range idSet(ids) {
+		if _, ok := r.byID[id]; ok {
+			matched = append(matched, id)
+		}
+	}
+	return matched
+}
+
+// matchingMessages returns the IDs among ids of existing messages that pass
+// keep
+func (r *MemoryFormsRepository) matchingMessages(ids []string, keep func(ContactMessage) bool) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wanted := idSet(ids)
+	var matched []string
+	for _, message := range r.messages {
+		if wanted[message.ID] && keep(message) {
+			matched = append(matched, message.ID)
+		}
+	}
+	return matched
+}
+
+// idSet turns a list of IDs into a set
+func idSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// records is how many log records the current state takes
+func (r *MemoryFormsRepository) records() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.subscribers) + len(r.messages)
+}
+
+// Compaction thresholds of FileFormsRepository: the log is rewritten once it
+// has at least compactMinRecords records and more than compactRatio times
+// the records the current state needs
+const (
+	compactMinRecords = 1000
+	compactRatio      = 2
+)
+
+// formsLogRecord is one line of the log: a subscriber as saved, a new
+// contact message, messages marked as read or records deleted
+type formsLogRecord struct {
+	Subscriber *NewsletterSubscriber `json:"subscriber,omitempty"`
+	Contact    *ContactMessage       `json:"contact,omitempty"`
+
+	ReadContacts       []string `json:"readContacts,omitempty"`
+	DeletedContacts    []string `json:"deletedContacts,omitempty"`
+	DeletedSubscribers []string `json:"deletedSubscribers,omitempty"`
+}
+
+// FileFormsRepository persists to an append-only log of JSON lines, replayed
+// into memory on open. Every change is synced before it's acknowledged, and
+// the log is compacted when superseded or deleted records pile up.
+type FileFormsRepository struct {
+	path   string
+	memory *MemoryFormsRepository
+
+	// mu serializes writes so the log and the memory agree
+	mu sync.Mutex
+	// file is nil after Close, and after a compaction that couldn't reopen
+	// the log, in which case the next append tries again
+	file    *os.File
+	closed  bool
+	records int
+}
+
+// openFormsLog opens a log for appending; tests replace it to make it fail
+var openFormsLog = func(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+}
+
+// OpenFileFormsRepository replays the log at path, creating it if needed.
+// A torn last line, from a crash in the middle of a write, is dropped; any
+// other damage is an error.
+func OpenFileFormsRepository(path string) (*FileFormsRepository, error) {
+	r := &FileFormsRepository{
+		path:   path,
+		memory: NewMemoryFormsRepository(),
+	}
+
+	if err := r.replay(); err != nil {
+		return nil, err
+	}
+	if r.needsCompaction() {
+		if err := r.compact(); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+	if err := r.openLog(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *FileFormsRepository) replay() error {
+	file, err := os.Open(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(bytes.TrimSpace(data)) > 0 {
+				log.Printf("Registro incompleto no fim de %s descartado (%d bytes)", r.path, len(data))
+				return os.Truncate(r.path, offset)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		offset += int64(len(data))
+
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		var record formsLogRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return fmt.Errorf("%s:%d: %w", r.path, line, err)
+		}
+		r.apply(record)
+		r.records++
+	}
+}
+
+func (r *FileFormsRepository) apply(record formsLogRecord) {
+	if record.Subscriber != nil {
+		r.memory.saveSubscriber(*record.Subscriber)
+	}
+	if record.Contact != nil {
+		r.memory.messages = append(r.memory.messages, *record.Contact)
+	}
+	if len(record.ReadContacts) > 0 {
+		r.memory.markRead(idSet(record.ReadContacts))
+	}
+	if len(record.DeletedContacts) > 0 {
+		r.memory.deleteMessages(idSet(record.DeletedContacts))
+	}
+	if len(record.DeletedSubscribers) > 0 {
+		r.memory.deleteSubscribers(idSet(record.DeletedSubscribers))
+	}
+}
+
+func (r *FileFormsRepository) openLog() error {
+	file, err := openFormsLog(r.path)
+	if err != nil {
+		return err
+	}
+	r.file = file
+	return nil
+}
+
+func (r *FileFormsRepository) Subscriber(id string) (NewsletterSubscriber, bool, error) {
+	return r.memory.Subscriber(id)
+}
+
+func (r *FileFormsRepository) SubscriberByEmail(email string) (NewsletterSubscriber, bool, error) {
+	return r.memory.SubscriberByEmail(email)
+}
+
+func (r *FileFormsRepository) SaveSubscriber(subscriber NewsletterSubscriber) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.append(formsLogRecord{Subscriber: &subscriber}); err != nil {
+		return err
+	}
+	r.memory.SaveSubscriber(subscriber)
+	return r.maybeCompact()
+}
+
+func (r *FileFormsRepository) Subscribers() ([]NewsletterSubscriber, error) {
+	return r.memory.Subscribers()
+}
+
+func (r *FileFormsRepository) AddContactMessage(message ContactMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.append(formsLogRecord{Contact: &message}); err != nil {
+		return err
+	}
+	r.memory.AddContactMessage(message)
+	return nil
+}
+
+func (r *FileFormsRepository) ContactMessages() ([]ContactMessage, error) {
+	return r.memory.ContactMessages()
+}
+
+func (r *FileFormsRepository) DeleteSubscribers(ids []string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids = r.memory.matchingSubscribers(ids)
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := r.append(formsLogRecord{DeletedSubscribers: ids}); err != nil {
+		return 0, err
+	}
+	deleted, _ := r.memory.DeleteSubscribers(ids)
+	return deleted, r.maybeCompact()
+}
+
+func (r *FileFormsRepository) MarkContactMessagesRead(ids []string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids = r.memory.matchingMessages(ids, func(m ContactMessage) bool { return !m.Read })
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := r.append(formsLogRecord{ReadContacts: ids}); err != nil {
+		return 0, err
+	}
+	marked, _ := r.memory.MarkContactMessagesRead(ids)
+	return marked, r.maybeCompact()
+}
+
+func (r *FileFormsRepository) DeleteContactMessages(ids []string) ([]ContactMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids = r.memory.matchingMessages(ids, func(ContactMessage) bool { return true })
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if err := r.append(formsLogRecord{DeletedContacts: ids}); err != nil {
+		return nil, err
+	}
+	deleted, _ := r.memory.DeleteContactMessages(ids)
+	return deleted, r.maybeCompact()
+}
+
+// Compact rewrites the log with one record per subscriber and message
+func (r *FileFormsRepository) Compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.compact()
+}
+
+// Close closes the log; the repository can't be written afterwards
+func (r *FileFormsRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// append writes one record and syncs it; callers must hold r.mu
+func (r *FileFormsRepository) append(record formsLogRecord) error {
+	if r.closed {
+		return os.ErrClosed
+	}
+	if r.file == nil {
+		if err := r.openLog(); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := r.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err := r.file.Sync(); err != nil {
+		return err
+	}
+	r.records++
+	return nil
+}
+
+func (r *FileFormsRepository) needsCompaction() bool {
+	return r.records >= compactMinRecords && r.records > compactRatio*r.memory.records()
+}
+
+// maybeCompact compacts when the thresholds are crossed. The change that
+// triggered it is already durable, so a failure is only logged.
+func (r *FileFormsRepository) maybeCompact() error {
+	if !r.needsCompaction() {
+		return nil
+	}
+	if err := r.compact(); err != nil {
+		log.Printf("Erro ao compactar %s: %v", r.path, err)
+	}
+	return nil
+}
+
+// compact writes the current state to a new log and swaps it in; callers
+// must hold r.mu
+func (r *FileFormsRepository) compact() error {
+	subscribers, _ := r.memory.Subscribers()
+	messages, _ := r.memory.ContactMessages()
+
+	err := writeFileAtomic(r.path, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		for i := range subscribers {
+			if err := encoder.Encode(formsLogRecord{Subscriber: &subscribers[i]}); err != nil {
+				return err
+			}
+		}
+		for i := range messages {
+			if err := encoder.Encode(formsLogRecord{Contact: &messages[i]}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Appends must go to the new file, not the renamed-over one. If it
+	// can't be opened the old handle goes anyway, and append reopens.
+	r.records = len(subscribers) + len(messages)
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	return r.openLog()
+}
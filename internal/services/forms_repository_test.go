@@ -0,0 +1,367 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// discardNewsletterMailer accepts every confirmation without logging it
+type discardNewsletterMailer struct{}
+
+func (discardNewsletterMailer) SendNewsletterConfirmation(context.Context, NewsletterConfirmation) error {
+	return nil
+}
+
+func newTestFormsService(repository FormsRepository) *FormsService {
+	fs := NewFormsService(nil, nil)
+	fs.UseRepository(repository)
+	fs.UseNewsletterMailer(discardNewsletterMailer{}, "http://localhost:8080")
+	return fs
+}
+
+func openTestLog(t *testing.T, path string) *FileFormsRepository {
+	t.Helper()
+	repository, err := OpenFileFormsRepository(path)
+	if err != nil {
+		t.Fatalf("OpenFileFormsRepository: %v", err)
+	}
+	t.Cleanup(func() { repository.Close() })
+	return repository
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Count(data, []byte("\n"))
+}
+
+func TestFormsRepositoryConcurrentSubmissions(t *testing.T) {
+	const (
+		workers    = 16
+		perWorker  = 10
+		sharedMail = "compartilhado@example.com"
+	)
+
+	repositories := map[string]func(t *testing.T) FormsRepository{
+		"memory": func(*testing.T) FormsRepository { return NewMemoryFormsRepository() },
+		"file": func(t *testing.T) FormsRepository {
+			return openTestLog(t, filepath.Join(t.TempDir(), "forms.jsonl"))
+		},
+	}
+
+	for name, open := range repositories {
+		t.Run(name, func(t *testing.T) {
+			repository := open(t)
+			fs := newTestFormsService(repository)
+			ctx := context.Background()
+
+			var wg sync.WaitGroup
+			errs := make(chan error, workers*perWorker*3)
+			for w := range workers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := range perWorker {
+						email := fmt.Sprintf("pessoa%d-%d@example.com", w, i)
+						if result, _ := fs.SubmitContact(ctx, ContactForm{
+							Name:    "Pessoa",
+							Email:   email,
+							Subject: "duvida",
+							Message: "Mensagem de teste concorrente",
+						}); !result.Valid {
+							errs <- fmt.Errorf("contato %s: %s", email, result.Message)
+						}
+						if result, _ := fs.SubmitNewsletter(ctx, NewsletterForm{Name: "Pessoa", Email: email}); !result.Valid {
+							errs <- fmt.Errorf("newsletter %s: %s", email, result.Message)
+						}
+						// Everyone signing the same address up must leave one subscriber
+						if result, _ := fs.SubmitNewsletter(ctx, NewsletterForm{Email: sharedMail}); !result.Valid {
+							errs <- fmt.Errorf("newsletter %s: %s", sharedMail, result.Message)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				t.Error(err)
+			}
+
+			messages, err := fs.GetContactMessages()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(messages) != workers*perWorker {
+				t.Errorf("got %d contact messages, want %d", len(messages), workers*perWorker)
+			}
+			ids := map[string]bool{}
+			for _, message := range messages {
+				if ids[message.ID] {
+					t.Errorf("duplicate contact message ID %s", message.ID)
+				}
+				ids[message.ID] = true
+			}
+
+			subscribers, err := fs.GetNewsletterSubscribers()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(subscribers) != workers*perWorker+1 {
+				t.Errorf("got %d subscribers, want %d", len(subscribers), workers*perWorker+1)
+			}
+
+			file, ok := repository.(*FileFormsRepository)
+			if !ok {
+				return
+			}
+			if err := file.Close(); err != nil {
+				t.Fatal(err)
+			}
+			reopened := openTestLog(t, file.path)
+			if got, _ := reopened.ContactMessages(); len(got) != len(messages) {
+				t.Errorf("after reopening got %d contact messages, want %d", len(got), len(messages))
+			}
+			if got, _ := reopened.Subscribers(); len(got) != len(subscribers) {
+				t.Errorf("after reopening got %d subscribers, want %d", len(got), len(subscribers))
+			}
+		})
+	}
+}
+
+func TestFileFormsRepositoryReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forms.jsonl")
+	repository := openTestLog(t, path)
+
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ana := NewsletterSubscriber{ID: "news_1", Name: "Ana", Email: "ana@example.com", Status: SubscriberPending, CreatedAt: created}
+	bruno := NewsletterSubscriber{ID: "news_2", Name: "Bruno", Email: "bruno@example.com", Status: SubscriberPending, CreatedAt: created}
+	for _, subscriber := range []NewsletterSubscriber{ana, bruno} {
+		if err := repository.SaveSubscriber(subscriber); err != nil {
+			t.Fatal(err)
+		}
+	}
+	confirmed := created.Add(time.Hour)
+	ana.Status, ana.ConfirmedAt = SubscriberConfirmed, &confirmed
+	if err := repository.SaveSubscriber(ana); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repository.DeleteSubscribers([]string{bruno.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if err := repository.AddContactMessage(ContactMessage{
+			ID:        fmt.Sprintf("contact_%d", i),
+			Name:      "Carla",
+			Email:     "carla@example.com",
+			Subject:   "duvida",
+			Message:   "Mensagem número " + fmt.Sprint(i),
+			CreatedAt: created,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if marked, err := repository.MarkContactMessagesRead([]string{"contact_1", "contact_1", "missing"}); err != nil || marked != 1 {
+		t.Fatalf("MarkContactMessagesRead = %d, %v; want 1", marked, err)
+	}
+	if deleted, err := repository.DeleteContactMessages([]string{"contact_2"}); err != nil || len(deleted) != 1 {
+		t.Fatalf("DeleteContactMessages = %d, %v; want 1", len(deleted), err)
+	}
+	if err := repository.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := openTestLog(t, path)
+
+	subscribers, _ := reopened.Subscribers()
+	if len(subscribers) != 1 {
+		t.Fatalf("got %d subscribers, want 1", len(subscribers))
+	}
+	got := subscribers[0]
+	if got.ID != ana.ID || got.Status != SubscriberConfirmed || got.ConfirmedAt == nil || !got.ConfirmedAt.Equal(confirmed) {
+		t.Errorf("replayed subscriber = %+v, want %+v", got, ana)
+	}
+	if _, found, _ := reopened.SubscriberByEmail(bruno.Email); found {
+		t.Errorf("deleted subscriber %s came back", bruno.Email)
+	}
+
+	messages, _ := reopened.ContactMessages()
+	read := map[string]bool{}
+	for _, message := range messages {
+		read[message.ID] = message.Read
+	}
+	want := map[string]bool{"contact_1": true, "contact_3": false}
+	if fmt.Sprint(read) != fmt.Sprint(want) {
+		t.Errorf("replayed messages (ID: read) = %v, want %v", read, want)
+	}
+
+	// The reopened log keeps taking writes
+	if err := reopened.AddContactMessage(ContactMessage{ID: "contact_4", CreatedAt: created}); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if messages, _ := openTestLog(t, path).ContactMessages(); len(messages) != 3 {
+		t.Errorf("got %d messages after the second reopen, want 3", len(messages))
+	}
+}
+
+func TestFileFormsRepositoryTornLastLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forms.jsonl")
+	repository := openTestLog(t, path)
+	for i := 1; i <= 2; i++ {
+		if err := repository.AddContactMessage(ContactMessage{ID: fmt.Sprintf("contact_%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repository.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	intact, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString(`{"contact":{"id":"contact_3","name":"Ca`); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	reopened := openTestLog(t, path)
+	if messages, _ := reopened.ContactMessages(); len(messages) != 2 {
+		t.Errorf("got %d messages, want the 2 complete ones", len(messages))
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != intact.Size() {
+		t.Errorf("log is %d bytes after replay, want the torn line cut back to %d", info.Size(), intact.Size())
+	}
+
+	// New records must start on a line of their own
+	if err := reopened.AddContactMessage(ContactMessage{ID: "contact_3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if messages, _ := openTestLog(t, path).ContactMessages(); len(messages) != 3 {
+		t.Errorf("got %d messages after writing past the torn line, want 3", len(messages))
+	}
+}
+
+func TestFileFormsRepositoryDamagedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forms.jsonl")
+	log := `{"contact":{"id":"contact_1"}}` + "\n" + `{"contact":` + "\n" + `{"contact":{"id":"contact_2"}}` + "\n"
+	if err := os.WriteFile(path, []byte(log), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the last line can be torn by a crash; damage elsewhere is kept
+	// for someone to look at
+	if repository, err := OpenFileFormsRepository(path); err == nil {
+		repository.Close()
+		t.Fatal("OpenFileFormsRepository accepted a damaged line in the middle of the log")
+	}
+	if data, _ := os.ReadFile(path); string(data) != log {
+		t.Error("a damaged log was changed on open")
+	}
+}
+
+func TestFileFormsRepositoryCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forms.jsonl")
+	repository := openTestLog(t, path)
+
+	subscriber := NewsletterSubscriber{ID: "news_1", Email: "ana@example.com", Status: SubscriberPending}
+	if err := repository.AddContactMessage(ContactMessage{ID: "contact_1"}); err != nil {
+		t.Fatal(err)
+	}
+	// Saving the same subscriber over and over supersedes every earlier
+	// record, so the log crosses the thresholds on its own
+	for i := range compactMinRecords {
+		subscriber.Name = fmt.Sprintf("Ana %d", i)
+		if err := repository.SaveSubscriber(subscriber); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if lines := countLines(t, path); lines >= compactMinRecords {
+		t.Fatalf("log has %d lines, want it compacted below %d", lines, compactMinRecords)
+	}
+
+	// Writes after an automatic compaction go to the new file
+	if err := repository.AddContactMessage(ContactMessage{ID: "contact_2"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repository.MarkContactMessagesRead([]string{"contact_1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := countLines(t, path); lines != 3 {
+		t.Errorf("compacted log has %d lines, want one per subscriber and message (3)", lines)
+	}
+	if err := repository.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := openTestLog(t, path)
+	got, found, _ := reopened.Subscriber(subscriber.ID)
+	if !found || got.Name != subscriber.Name {
+		t.Errorf("subscriber after compaction = %+v, found %v; want name %q", got, found, subscriber.Name)
+	}
+	messages, _ := reopened.ContactMessages()
+	if len(messages) != 2 || !messages[0].Read || messages[1].Read {
+		t.Errorf("messages after compaction = %+v, want contact_1 read and contact_2 unread", messages)
+	}
+}
+
+func TestFileFormsRepositoryReopensAfterFailedCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forms.jsonl")
+	repository := openTestLog(t, path)
+	if err := repository.AddContactMessage(ContactMessage{ID: "contact_1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The new log can't be opened once it's been renamed into place
+	open := openFormsLog
+	t.Cleanup(func() { openFormsLog = open })
+	openFormsLog = func(string) (*os.File, error) { return nil, os.ErrPermission }
+	if err := repository.Compact(); !errors.Is(err, os.ErrPermission) {
+		t.Fatalf("Compact = %v, want the open error", err)
+	}
+
+	// Writes fail while the log can't be opened, and nothing is lost...
+	if err := repository.AddContactMessage(ContactMessage{ID: "contact_2"}); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("AddContactMessage with the log unavailable = %v, want the open error", err)
+	}
+	openFormsLog = open
+
+	// ...and go to the new log once it can be
+	if err := repository.AddContactMessage(ContactMessage{ID: "contact_3"}); err != nil {
+		t.Fatalf("AddContactMessage after the log came back: %v", err)
+	}
+	if err := repository.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.AddContactMessage(ContactMessage{ID: "contact_4"}); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("AddContactMessage after Close = %v, want os.ErrClosed", err)
+	}
+
+	messages, _ := openTestLog(t, path).ContactMessages()
+	if len(messages) != 2 || messages[0].ID != "contact_1" || messages[1].ID != "contact_3" {
+		t.Errorf("messages after reopening = %+v, want contact_1 and contact_3", messages)
+	}
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"showcase-datastar-go/internal/signing"
+)
+
+// SubscriberStatus is where a subscriber is in the double opt-in flow
+type SubscriberStatus string
+
+const (
+	// SubscriberPending signed up but hasn't confirmed the address yet
+	SubscriberPending SubscriberStatus = "pending"
+
+	// SubscriberConfirmed proved the address is theirs and gets the newsletter
+	SubscriberConfirmed SubscriberStatus = "confirmed"
+
+	// SubscriberUnsubscribed left; signing up again restarts the opt-in
+	SubscriberUnsubscribed SubscriberStatus = "unsubscribed"
+)
+
+// Token purposes and lifetimes of the newsletter links
+const (
+	newsletterConfirmPurpose     = "newsletter-confirm"
+	newsletterUnsubscribePurpose = "newsletter-unsubscribe"
+
+	NewsletterConfirmTTL     = 48 * time.Hour
+	NewsletterUnsubscribeTTL = 365 * 24 * time.Hour
+)
+
+// NewsletterConfirmation is the email asking a subscriber to confirm. The
+// address isn't verified yet, so the name typed with it stays out.
+type NewsletterConfirmation struct {
+	Email          string
+	ConfirmURL     string
+	UnsubscribeURL string
+	Expires        time.Time
+}
+
+// NewsletterMailer delivers the emails of the newsletter flow
+type NewsletterMailer interface {
+	SendNewsletterConfirmation(ctx context.Context, confirmation NewsletterConfirmation) error
+}
+
+// LogNewsletterMailer writes the confirmation links to the log instead of
+// sending mail, which is enough to try the flow locally
+type LogNewsletterMailer struct{}
+
+func (LogNewsletterMailer) SendNewsletterConfirmation(_ context.Context, confirmation NewsletterConfirmation) error {
+	log.Printf("📧 Confirmação de newsletter para %s: %s (cancelar: %s)",
+		confirmation.Email, confirmation.ConfirmURL, confirmation.UnsubscribeURL)
+	return nil
+}
+
+// UseNewsletterMailer sets how confirmation emails are sent; baseURL is the
+// public address of the site the links in them point to
+func (fs *FormsService) UseNewsletterMailer(mailer NewsletterMailer, baseURL string) {
+	fs.newsletterMailer = mailer
+	fs.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// UseTokenSigner sets the key newsletter links are signed with; without it
+// links stop working when the server restarts
+func (fs *FormsService) UseTokenSigner(signer *signing.Signer) {
+	fs.signer = signer
+}
+
+// sendNewsletterConfirmation issues fresh tokens for subscriber and mails them
+func (fs *FormsService) sendNewsletterConfirmation(ctx context.Context, subscriber NewsletterSubscriber) error {
+	return fs.newsletterMailer.SendNewsletterConfirmation(ctx, NewsletterConfirmation{
+		Email:          subscriber.Email,
+		ConfirmURL:     fs.newsletterURL("/newsletter/confirm", fs.signer.Sign(newsletterConfirmPurpose, subscriber.ID, NewsletterConfirmTTL)),
+		UnsubscribeURL: fs.NewsletterUnsubscribeURL(subscriber),
+		Expires:        time.Now().Add(NewsletterConfirmTTL),
+	})
+}
+
+// NewsletterUnsubscribeURL is the link that lets subscriber leave
+func (fs *FormsService) NewsletterUnsubscribeURL(subscriber NewsletterSubscriber) string {
+	return fs.newsletterURL("/newsletter/unsubscribe", fs.signer.Sign(newsletterUnsubscribePurpose, subscriber.ID, NewsletterUnsubscribeTTL))
+}
+
+func (fs *FormsService) newsletterURL(path, token string) string {
+	return fs.baseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// ConfirmNewsletter confirms the subscription a confirmation link points to
+func (fs *FormsService) ConfirmNewsletter(token string) *ValidationResult {
+	id, err := fs.signer.Verify(newsletterConfirmPurpose, token)
+	if errors.Is(err, signing.ErrExpiredToken) {
+		return &ValidationResult{
+			Valid:   false,
+			Message: "Link de confirmação expirado. Inscreva-se novamente para receber outro.",
+		}
+	}
+	if err != nil {
+		return &ValidationResult{
+			Valid:   false,
+			Message: "Link de confirmação inválido",
+		}
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	subscriber, found, err := fs.repository.Subscriber(id)
+	switch {
+	case err != nil:
+		log.Printf("Erro ao consultar inscrição %s: %v", id, err)
+		return storageFailure()
+	case !found:
+		return &ValidationResult{
+			Valid:   false,
+			Message: "Inscrição não encontrada",
+		}
+	case subscriber.Status == SubscriberConfirmed:
+		return &ValidationResult{
+			Valid:   true,
+			Message: "Sua inscrição já estava confirmada",
+			Data:    subscriber.Email,
+		}
+	case subscriber.Status == SubscriberUnsubscribed:
+		// An old link mustn't undo leaving
+		return &ValidationResult{
+			Valid:   false,
+			Message: "Esta inscrição foi cancelada. Inscreva-se novamente se quiser voltar.",
+		}
+	}
+
+	now := time.Now()
+	subscriber.Status = SubscriberConfirmed
+	subscriber.ConfirmedAt = &now
+	if err := fs.repository.SaveSubscriber(subscriber); err != nil {
+		log.Printf("Erro ao confirmar inscrição %s: %v", id, err)
+		return storageFailure()
+	}
+
+	return &ValidationResult{
+		Valid:   true,
+		Message: "Inscrição confirmada! Você passará a receber a newsletter.",
+		Data:    subscriber.Email,
+	}
+}
+
+// UnsubscribeNewsletter cancels the subscription an unsubscribe link points to
+func (fs *FormsService) UnsubscribeNewsletter(token string) *ValidationResult {
+	id, err := fs.signer.Verify(newsletterUnsubscribePurpose, token)
+	if errors.Is(err, signing.ErrExpiredToken) {
+		return &ValidationResult{
+			Valid:   false,
+			Message: "Link de cancelamento expirado. Use o link de um email mais recente.",
+		}
+	}
+	if err != nil {
+		return &ValidationResult{
+			Valid:   false,
+			Message: "Link de cancelamento inválido",
+		}
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	subscriber, found, err := fs.repository.Subscriber(id)
+	if err != nil {
+		log.Printf("Erro ao consultar inscrição %s: %v", id, err)
+		return storageFailure()
+	}
+	if !found {
+		return &ValidationResult{
+			Valid:   false,
+			Message: "Inscrição não encontrada",
+		}
+	}
+	if subscriber.Status == SubscriberUnsubscribed {
+		return &ValidationResult{
+			Valid:   true,
+			Message: "Sua inscrição já estava cancelada",
+			Data:    subscriber.Email,
+		}
+	}
+
+	now := time.Now()
+	subscriber.Status = SubscriberUnsubscribed
+	subscriber.UnsubscribedAt = &now
+	if err := fs.repository.SaveSubscriber(subscriber); err != nil {
+		log.Printf("Erro ao cancelar inscrição %s: %v", id, err)
+		return storageFailure()
+	}
+
+	return &ValidationResult{
+		Valid:   true,
+		Message: "Inscrição cancelada. Você não receberá mais a newsletter.",
+		Data:    subscriber.Email,
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"net/mail"
+
+	"showcase-datastar-go/internal/mailer"
+	"showcase-datastar-go/internal/templates/emails"
+)
+
+// ContactMailer acknowledges contact messages to their senders
+type ContactMailer interface {
+	SendContactAcknowledgement(ctx context.Context, message ContactMessage) error
+}
+
+// UseContactMailer turns on the acknowledgement of contact messages
+func (fs *FormsService) UseContactMailer(mailer ContactMailer) {
+	fs.contactMailer = mailer
+}
+
+// contactSubjectLabels are the subjects as the contact form shows them
+var contactSubjectLabels = map[string]string{
+	"duvida":   "Dúvida Técnica",
+	"feedback": "Feedback",
+	"parceria": "Proposta de Parceria",
+	"bug":      "Relatar Bug",
+	"outro":    "Outro",
+}
+
+// MailNotifier sends the emails of the forms through a mailer, rendered
+// from the templates in package emails
+type MailNotifier struct {
+	Mailer mailer.Mailer
+	From   string
+}
+
+func (n *MailNotifier) SendNewsletterConfirmation(ctx context.Context, confirmation NewsletterConfirmation) error {
+	msg := &mailer.Message{
+		From:    n.From,
+		To:      []string{recipient("", confirmation.Email)},
+		Subject: "Confirme sua inscrição na newsletter",
+		Headers: map[string]string{
+			// One-click unsubscribe (RFC 8058) posts to the link itself
+			"List-Unsubscribe":      "<" + confirmation.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}
+	html := emails.NewsletterConfirmation(confirmation.ConfirmURL, confirmation.UnsubscribeURL, confirmation.Expires)
+	text := emails.NewsletterConfirmationText(confirmation.ConfirmURL, confirmation.UnsubscribeURL, confirmation.Expires)
+	if err := mailer.Render(ctx, msg, html, text); err != nil {
+		return err
+	}
+	return n.Mailer.Send(ctx, msg)
+}
+
+// SendContactAcknowledgement sends a fixed receipt. The sender's address is
+// never verified, so the name and message stay out of it; otherwise the form
+// would relay any text to any inbox.
+func (n *MailNotifier) SendContactAcknowledgement(ctx context.Context, message ContactMessage) error {
+	subject := contactSubjectLabels[message.Subject]
+	if subject == "" {
+		subject = "Contato"
+	}
+
+	msg := &mailer.Message{
+		From:    n.From,
+		To:      []string{recipient("", message.Email)},
+		Subject: "Recebemos sua mensagem: " + subject,
+	}
+	if err := mailer.Render(ctx, msg, emails.ContactAcknowledgement(subject), emails.ContactAcknowledgementText(subject)); err != nil {
+		return err
+	}
+	return n.Mailer.Send(ctx, msg)
+}
+
+// recipient formats a name and address as "Name <address>"
+func recipient(name, email string) string {
+	return (&mail.Address{Name: name, Address: email}).String()
+}
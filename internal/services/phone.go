@@ -0,0 +1,178 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PhoneType tells mobile, landline and foreign numbers apart
+type PhoneType string
+
+const (
+	PhoneMobile        PhoneType = "mobile"
+	PhoneLandline      PhoneType = "landline"
+	PhoneInternational PhoneType = "international"
+)
+
+// brazilCountryCode is the +55 prefix of Brazilian numbers
+const brazilCountryCode = "55"
+
+// PhoneNumber is a validated phone number
+type PhoneNumber struct {
+	// E164 is the canonical form, e.g. +5511987654321
+	E164 string `json:"e164"`
+
+	// National is the display form: (11) 98765-4321 for Brazilian numbers,
+	// the E.164 form for foreign ones
+	National string    `json:"national"`
+	Type     PhoneType `json:"type"`
+
+	// DDD and UF are empty for foreign numbers
+	DDD string `json:"ddd,omitempty"`
+	UF  string `json:"uf,omitempty"`
+}
+
+// dddStates maps every area code (DDD) in use, per Anatel's numbering plan,
+// to its state
+var dddStates = map[string]string{
+	"11": "SP", "12": "SP", "13": "SP", "14": "SP", "15": "SP", "16": "SP", "17": "SP", "18": "SP", "19": "SP",
+	"21": "RJ", "22": "RJ", "24": "RJ",
+	"27": "ES", "28": "ES",
+	"31": "MG", "32": "MG", "33": "MG", "34": "MG", "35": "MG", "37": "MG", "38": "MG",
+	"41": "PR", "42": "PR", "43": "PR", "44": "PR", "45": "PR", "46": "PR",
+	"47": "SC", "48": "SC", "49": "SC",
+	"51": "RS", "53": "RS", "54": "RS", "55": "RS",
+	"61": "DF",
+	"62": "GO", "64": "GO",
+	"63": "TO",
+	"65": "MT", "66": "MT",
+	"67": "MS",
+	"68": "AC",
+	"69": "RO",
+	"71": "BA", "73": "BA", "74": "BA", "75": "BA", "77": "BA",
+	"79": "SE",
+	"81": "PE", "87": "PE",
+	"82": "AL",
+	"83": "PB",
+	"84": "RN",
+	"85": "CE", "88": "CE",
+	"86": "PI", "89": "PI",
+	"91": "PA", "93": "PA", "94": "PA",
+	"92": "AM", "97": "AM",
+	"95": "RR",
+	"96": "AP",
+	"98": "MA", "99": "MA",
+}
+
+// ValidatePhone validates a Brazilian phone number, with or without +55, or
+// a foreign one in +<country code> form. Data carries the number masked as
+// far as it was typed; the parsed number is nil unless it's valid.
+func (fs *FormsService) ValidatePhone(phone string) (*ValidationResult, *PhoneNumber) {
+	masked := fs.MaskPhone(phone)
+	invalid := func(message string) (*ValidationResult, *PhoneNumber) {
+		return &ValidationResult{
+			Valid:   false,
+			Message: message,
+			Data:    masked,
+		}, nil
+	}
+
+	phone = strings.TrimSpace(phone)
+	digits := onlyDigits(phone)
+	if digits == "" {
+		return invalid("Telefone é obrigatório")
+	}
+
+	international := strings.HasPrefix(phone, "+")
+	switch {
+	case international && strings.HasPrefix(digits, brazilCountryCode):
+		digits = digits[len(brazilCountryCode):]
+	case international:
+		// E.164 allows up to 15 digits, country code included; nothing shorter
+		// than 8 is a real number anywhere
+		if len(digits) < 8 {
+			return invalid("Telefone internacional incompleto")
+		}
+		if len(digits) > 15 {
+			return invalid("Telefone internacional deve ter no máximo 15 dígitos")
+		}
+		if digits[0] == '0' {
+			return invalid("Código de país inválido")
+		}
+		number := &PhoneNumber{
+			E164:     "+" + digits,
+			National: "+" + digits,
+			Type:     PhoneInternational,
+		}
+		return &ValidationResult{
+			Valid:   true,
+			Message: "Telefone internacional válido",
+			Data:    number.National,
+		}, number
+	case len(digits) >= 12 && strings.HasPrefix(digits, brazilCountryCode):
+		// 55 typed without the plus sign
+		digits = digits[len(brazilCountryCode):]
+	case len(digits) >= 11 && digits[0] == '0' && dddStates[digits[1:3]] != "":
+		// Long-distance trunk prefix, as in 011 98765-4321
+		digits = digits[1:]
+	}
+
+	if len(digits) < 10 {
+		return invalid("Telefone incompleto: informe DDD e número")
+	}
+	if len(digits) > 11 {
+		return invalid("Telefone com dígitos demais")
+	}
+
+	ddd, subscriber := digits[:2], digits[2:]
+	uf, ok := dddStates[ddd]
+	if !ok {
+		return invalid(fmt.Sprintf("DDD %s não existe", ddd))
+	}
+
+	number := &PhoneNumber{
+		E164: "+" + brazilCountryCode + digits,
+		DDD:  ddd,
+		UF:   uf,
+	}
+
+	if len(subscriber) == 9 {
+		if subscriber[0] != '9' {
+			return invalid("Celular deve começar com 9")
+		}
+		number.Type = PhoneMobile
+		number.National = fmt.Sprintf("(%s) %s-%s", ddd, subscriber[:5], subscriber[5:])
+	} else {
+		switch subscriber[0] {
+		case '2', '3', '4', '5':
+		case '6', '7', '8', '9':
+			// A mobile number from before the extra 9 was added
+			return invalid("Celular deve ter 9 dígitos após o DDD")
+		default:
+			return invalid("Telefone fixo deve começar com 2, 3, 4 ou 5")
+		}
+		number.Type = PhoneLandline
+		number.National = fmt.Sprintf("(%s) %s-%s", ddd, subscriber[:4], subscriber[4:])
+	}
+
+	message := "Celular válido"
+	if number.Type == PhoneLandline {
+		message = "Telefone fixo válido"
+	}
+	return &ValidationResult{
+		Valid:   true,
+		Message: fmt.Sprintf("%s (%s)", message, uf),
+		Data:    number.National,
+	}, number
+}
+
+// onlyDigits drops everything but ASCII digits
+func onlyDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
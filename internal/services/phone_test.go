@@ -0,0 +1,56 @@
+package services
+
+import "testing"
+
+func TestValidatePhone(t *testing.T) {
+	tests := []struct {
+		name     string
+		phone    string
+		valid    bool
+		message  string
+		e164     string
+		national string
+		kind     PhoneType
+	}{
+		{"mobile", "11987654321", true, "Celular válido (SP)", "+5511987654321", "(11) 98765-4321", PhoneMobile},
+		{"mobile, masked", "(21) 99876-5432", true, "Celular válido (RJ)", "+5521998765432", "(21) 99876-5432", PhoneMobile},
+		{"mobile, +55", "+55 11 98765-4321", true, "Celular válido (SP)", "+5511987654321", "(11) 98765-4321", PhoneMobile},
+		{"mobile, 55 without the plus", "5511987654321", true, "Celular válido (SP)", "+5511987654321", "(11) 98765-4321", PhoneMobile},
+		{"mobile, trunk prefix", "011 98765-4321", true, "Celular válido (SP)", "+5511987654321", "(11) 98765-4321", PhoneMobile},
+		{"mobile without the 9", "11 8765-4321", false, "Celular deve ter 9 dígitos após o DDD", "", "", ""},
+		{"nine digits not starting with 9", "11 88765-4321", false, "Celular deve começar com 9", "", "", ""},
+		{"landline", "1133224455", true, "Telefone fixo válido (SP)", "+551133224455", "(11) 3322-4455", PhoneLandline},
+		{"landline, masked", "(61) 2345-6789", true, "Telefone fixo válido (DF)", "+556123456789", "(61) 2345-6789", PhoneLandline},
+		{"landline, +55", "+55 (51) 5432-1098", true, "Telefone fixo válido (RS)", "+555154321098", "(51) 5432-1098", PhoneLandline},
+		{"landline starting with 1", "1113224455", false, "Telefone fixo deve começar com 2, 3, 4 ou 5", "", "", ""},
+		{"unknown DDD 20", "(20) 98765-4321", false, "DDD 20 não existe", "", "", ""},
+		{"unknown DDD 23", "(23) 3322-4455", false, "DDD 23 não existe", "", "", ""},
+		{"too short", "11 98765", false, "Telefone incompleto: informe DDD e número", "", "", ""},
+		{"number without DDD", "98765-4321", false, "Telefone incompleto: informe DDD e número", "", "", ""},
+		{"too long", "119876543210", false, "Telefone com dígitos demais", "", "", ""},
+		{"+55 too long", "+55 11 98765-43210", false, "Telefone com dígitos demais", "", "", ""},
+		{"empty", "  ", false, "Telefone é obrigatório", "", "", ""},
+		{"international", "+1 (415) 555-2671", true, "Telefone internacional válido", "+14155552671", "+14155552671", PhoneInternational},
+		{"international too short", "+351 123", false, "Telefone internacional incompleto", "", "", ""},
+		{"international too long", "+1234567890123456", false, "Telefone internacional deve ter no máximo 15 dígitos", "", "", ""},
+	}
+
+	fs := NewFormsService(nil, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, number := fs.ValidatePhone(tt.phone)
+			if result.Valid != tt.valid || result.Message != tt.message {
+				t.Fatalf("ValidatePhone(%q) = %v %q, want %v %q", tt.phone, result.Valid, result.Message, tt.valid, tt.message)
+			}
+			if !tt.valid {
+				if number != nil {
+					t.Errorf("invalid number parsed as %+v", number)
+				}
+				return
+			}
+			if number.E164 != tt.e164 || number.National != tt.national || number.Type != tt.kind || result.Data != tt.national {
+				t.Errorf("ValidatePhone(%q) = %+v (data %q), want %s %q %s", tt.phone, number, result.Data, tt.e164, tt.national, tt.kind)
+			}
+		})
+	}
+}
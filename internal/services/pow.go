@@ -0,0 +1,242 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"math/bits"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"showcase-datastar-go/internal/signing"
+)
+
+var (
+	// ErrPowMissing means the submission carries no solution
+	ErrPowMissing = errors.New("proof of work missing")
+
+	// ErrPowInvalid means the challenge is forged, expired, already used,
+	// issued to another network or the solution doesn't meet its difficulty
+	ErrPowInvalid = errors.New("proof of work invalid")
+)
+
+const powPurpose = "pow"
+
+// maxPowNonce bounds the nonce, a decimal counter
+const maxPowNonce = 20
+
+// PowChallenge is what the page solves: find a nonce so that
+// SHA-256(token + ":" + nonce) starts with Difficulty zero bits
+type PowChallenge struct {
+	Token      string `json:"token"`
+	Difficulty int    `json:"difficulty"`
+	Expires    int64  `json:"expires"`
+}
+
+// PowSolution is a solved challenge as the page sends it
+type PowSolution struct {
+	Token string `json:"token"`
+	Nonce string `json:"nonce"`
+}
+
+// PowOptions tune a PowGuard; zero values pick the defaults
+type PowOptions struct {
+	// Difficulty is the zero bits asked of a quiet network (default 16,
+	// about a second in the browser; negative turns the work off)
+	Difficulty int
+
+	// MaxDifficulty caps the scaling (default 22)
+	MaxDifficulty int
+
+	// Step submissions from a network within Window add one bit, doubling
+	// the work (defaults 10 and 10m)
+	Step   int
+	Window time.Duration
+
+	// TTL is how long a challenge can be used (default 30m)
+	TTL time.Duration
+}
+
+// PowGuard issues and verifies proof-of-work challenges. Difficulty grows
+// with the recent submissions from the client's network, a /24 for IPv4
+// and a /48 for IPv6, so a burst from one place gets slower and slower.
+type PowGuard struct {
+	signer  *signing.Signer
+	options PowOptions
+
+	mu        sync.Mutex
+	recent    map[string][]time.Time
+	used      map[string]time.Time
+	lastPrune time.Time
+}
+
+func NewPowGuard(signer *signing.Signer, options PowOptions) *PowGuard {
+	switch {
+	case options.Difficulty < 0:
+		// No work at all, the challenge just can't be reused
+		options.Difficulty, options.MaxDifficulty = 0, 0
+	case options.Difficulty == 0:
+		options.Difficulty = 16
+		fallthrough
+	default:
+		if options.MaxDifficulty <= 0 {
+			options.MaxDifficulty = 22
+		}
+		options.MaxDifficulty = max(options.MaxDifficulty, options.Difficulty)
+	}
+	if options.Step <= 0 {
+		options.Step = 10
+	}
+	if options.Window <= 0 {
+		options.Window = 10 * time.Minute
+	}
+	if options.TTL <= 0 {
+		options.TTL = 30 * time.Minute
+	}
+
+	return &PowGuard{
+		signer:    signer,
+		options:   options,
+		recent:    map[string][]time.Time{},
+		used:      map[string]time.Time{},
+		lastPrune: time.Now(),
+	}
+}
+
+// Challenge issues a challenge for a client, bound to its network
+func (g *PowGuard) Challenge(ip string) PowChallenge {
+	network := ipNetwork(ip)
+
+	g.mu.Lock()
+	difficulty := g.difficulty(network, time.Now())
+	g.mu.Unlock()
+
+	salt := make([]byte, 12)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+	subject := base64.RawURLEncoding.EncodeToString(salt) + "|" + strconv.Itoa(difficulty) + "|" + network
+
+	return PowChallenge{
+		Token:      g.signer.Sign(powPurpose, subject, g.options.TTL),
+		Difficulty: difficulty,
+		Expires:    time.Now().Add(g.options.TTL).UnixMilli(),
+	}
+}
+
+// Verify checks a solution sent from ip. Every attempt counts towards the
+// network's rate, and a challenge can only be used once.
+func (g *PowGuard) Verify(ip string, solution PowSolution) error {
+	network := ipNetwork(ip)
+	now := time.Now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.prune(now)
+	// Past this many the difficulty is at its cap anyway
+	times := append(g.recent[network], now)
+	if limit := g.options.Step * (g.options.MaxDifficulty - g.options.Difficulty + 1); len(times) > limit {
+		times = times[len(times)-limit:]
+	}
+	g.recent[network] = times
+
+	if solution.Token == "" || solution.Nonce == "" {
+		return ErrPowMissing
+	}
+	if len(solution.Nonce) > maxPowNonce {
+		return ErrPowInvalid
+	}
+
+	subject, err := g.signer.Verify(powPurpose, solution.Token)
+	if err != nil {
+		return ErrPowInvalid
+	}
+	parts := strings.Split(subject, "|")
+	if len(parts) != 3 || parts[2] != network {
+		return ErrPowInvalid
+	}
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return ErrPowInvalid
+	}
+	if _, replayed := g.used[solution.Token]; replayed {
+		return ErrPowInvalid
+	}
+	if leadingZeroBits(sha256.Sum256([]byte(solution.Token+":"+solution.Nonce))) < difficulty {
+		return ErrPowInvalid
+	}
+
+	g.used[solution.Token] = now.Add(g.options.TTL)
+	return nil
+}
+
+// difficulty is the base plus one bit per Step recent submissions; callers
+// must hold g.mu
+func (g *PowGuard) difficulty(network string, now time.Time) int {
+	count := 0
+	for _, at := range g.recent[network] {
+		if now.Sub(at) < g.options.Window {
+			count++
+		}
+	}
+	return min(g.options.Difficulty+count/g.options.Step, g.options.MaxDifficulty)
+}
+
+// prune forgets old submissions and expired challenges; callers must hold
+// g.mu
+func (g *PowGuard) prune(now time.Time) {
+	if now.Sub(g.lastPrune) < time.Minute {
+		return
+	}
+	g.lastPrune = now
+
+	for network, times := range g.recent {
+		kept := times[:0]
+		for _, at := range times {
+			if now.Sub(at) < g.options.Window {
+				kept = append(kept, at)
+			}
+		}
+		if len(kept) == 0 {
+			delete(g.recent, network)
+		} else {
+			g.recent[network] = kept
+		}
+	}
+	for token, expires := range g.used {
+		if now.After(expires) {
+			delete(g.used, token)
+		}
+	}
+}
+
+// ipNetwork is the /24 (IPv4) or /48 (IPv6) an address belongs to
+func ipNetwork(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap()
+	size := 48
+	if addr.Is4() {
+		size = 24
+	}
+	prefix, _ := addr.Prefix(size)
+	return prefix.String()
+}
+
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	count := 0
+	for _, b := range sum {
+		if b != 0 {
+			return count + bits.LeadingZeros8(b)
+		}
+		count += 8
+	}
+	return count
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"crypto/sha256"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"showcase-datastar-go/internal/signing"
+)
+
+// solve finds a nonce for a challenge the way the page does; tests keep
+// the difficulty low so this takes a few dozen hashes
+func solve(t *testing.T, challenge PowChallenge) PowSolution {
+	t.Helper()
+	for nonce := 0; ; nonce++ {
+		candidate := strconv.Itoa(nonce)
+		if leadingZeroBits(sha256.Sum256([]byte(challenge.Token+":"+candidate))) >= challenge.Difficulty {
+			return PowSolution{Token: challenge.Token, Nonce: candidate}
+		}
+	}
+}
+
+// unsolved finds a nonce that doesn't meet a challenge's difficulty
+func unsolved(t *testing.T, challenge PowChallenge) PowSolution {
+	t.Helper()
+	for nonce := 0; ; nonce++ {
+		candidate := strconv.Itoa(nonce)
+		if leadingZeroBits(sha256.Sum256([]byte(challenge.Token+":"+candidate))) < challenge.Difficulty {
+			return PowSolution{Token: challenge.Token, Nonce: candidate}
+		}
+	}
+}
+
+func TestLeadingZeroBits(t *testing.T) {
+	tests := []struct {
+		prefix []byte
+		want   int
+	}{
+		{[]byte{0x80}, 0},
+		{[]byte{0x40}, 1},
+		{[]byte{0x01}, 7},
+		{[]byte{0x00, 0xFF}, 8},
+		{[]byte{0x00, 0x00, 0x1F}, 19},
+		{[]byte{0x00, 0x00, 0x00, 0x00, 0x08}, 36},
+	}
+	for _, tt := range tests {
+		var sum [sha256.Size]byte
+		copy(sum[:], tt.prefix)
+		if got := leadingZeroBits(sum); got != tt.want {
+			t.Errorf("leadingZeroBits(%x...) = %d, want %d", tt.prefix, got, tt.want)
+		}
+	}
+	if got := leadingZeroBits([sha256.Size]byte{}); got != 256 {
+		t.Errorf("leadingZeroBits(all zeros) = %d, want 256", got)
+	}
+}
+
+func TestIPNetwork(t *testing.T) {
+	tests := map[string]string{
+		"203.0.113.7":          "203.0.113.0/24",
+		"203.0.113.250":        "203.0.113.0/24",
+		"::ffff:203.0.113.7":   "203.0.113.0/24",
+		"2001:db8:1:2::1":      "2001:db8:1::/48",
+		"2001:db8:1:ffff::abc": "2001:db8:1::/48",
+		"2001:db8:2::1":        "2001:db8:2::/48",
+		"not an address":       "not an address",
+	}
+	for ip, want := range tests {
+		if got := ipNetwork(ip); got != want {
+			t.Errorf("ipNetwork(%q) = %q, want %q", ip, got, want)
+		}
+	}
+}
+
+func TestPowVerify(t *testing.T) {
+	signer := signing.NewRandom()
+	g := NewPowGuard(signer, PowOptions{Difficulty: 4, MaxDifficulty: 4})
+
+	challenge := g.Challenge("203.0.113.7")
+	if challenge.Difficulty != 4 {
+		t.Fatalf("difficulty = %d, want 4", challenge.Difficulty)
+	}
+
+	if err := g.Verify("203.0.113.7", unsolved(t, challenge)); !errors.Is(err, ErrPowInvalid) {
+		t.Errorf("nonce short of the difficulty = %v, want ErrPowInvalid", err)
+	}
+	solution := solve(t, challenge)
+	// Another host of the same /24 may send it
+	if err := g.Verify("203.0.113.99", solution); err != nil {
+		t.Fatalf("Verify(solved) = %v", err)
+	}
+	if err := g.Verify("203.0.113.7", solution); !errors.Is(err, ErrPowInvalid) {
+		t.Errorf("replayed solution = %v, want ErrPowInvalid", err)
+	}
+
+	other := g.Challenge("203.0.113.7")
+	if err := g.Verify("198.51.100.7", solve(t, other)); !errors.Is(err, ErrPowInvalid) {
+		t.Errorf("solution sent from another network = %v, want ErrPowInvalid", err)
+	}
+
+	// An expired challenge, signed as Challenge would have done it
+	expired := PowChallenge{
+		Token:      signer.Sign(powPurpose, "c2FsdA|4|203.0.113.0/24", -time.Second),
+		Difficulty: 4,
+	}
+	if err := g.Verify("203.0.113.7", solve(t, expired)); !errors.Is(err, ErrPowInvalid) {
+		t.Errorf("expired challenge = %v, want ErrPowInvalid", err)
+	}
+
+	forged := NewPowGuard(signing.NewRandom(), PowOptions{Difficulty: 4}).Challenge("203.0.113.7")
+	if err := g.Verify("203.0.113.7", solve(t, forged)); !errors.Is(err, ErrPowInvalid) {
+		t.Errorf("challenge signed with another key = %v, want ErrPowInvalid", err)
+	}
+
+	fresh := g.Challenge("203.0.113.7")
+	if err := g.Verify("203.0.113.7", PowSolution{Token: fresh.Token}); !errors.Is(err, ErrPowMissing) {
+		t.Errorf("no nonce = %v, want ErrPowMissing", err)
+	}
+	if err := g.Verify("203.0.113.7", PowSolution{Token: fresh.Token, Nonce: strings.Repeat("1", maxPowNonce+1)}); !errors.Is(err, ErrPowInvalid) {
+		t.Errorf("overlong nonce = %v, want ErrPowInvalid", err)
+	}
+}
+
+func TestPowDifficultyScalesPerNetwork(t *testing.T) {
+	g := NewPowGuard(signing.NewRandom(), PowOptions{Difficulty: 2, MaxDifficulty: 4, Step: 3})
+
+	submit := func(ip string, n int) {
+		for range n {
+			g.Verify(ip, PowSolution{})
+		}
+	}
+	difficulty := func(ip string, want int) {
+		t.Helper()
+		if got := g.Challenge(ip).Difficulty; got != want {
+			t.Errorf("difficulty for %s = %d, want %d", ip, got, want)
+		}
+	}
+
+	difficulty("203.0.113.7", 2)
+	// Every attempt counts, solved or not, from any host of the /24
+	submit("203.0.113.7", 2)
+	submit("203.0.113.8", 1)
+	difficulty("203.0.113.200", 3)
+	submit("203.0.113.7", 20)
+	difficulty("203.0.113.7", 4)
+	difficulty("203.0.114.7", 2)
+
+	// IPv6 hosts share their /48
+	submit("2001:db8:1:1::1", 3)
+	submit("2001:db8:1:2::1", 3)
+	difficulty("2001:db8:1:ffff::1", 4)
+	difficulty("2001:db8:2::1", 2)
+
+	// The history kept is capped once the cap is reached
+	if got, limit := len(g.recent["203.0.113.0/24"]), 3*(4-2+1); got != limit {
+		t.Errorf("%d submissions kept, want %d", got, limit)
+	}
+}
+
+func TestPowDisabled(t *testing.T) {
+	g := NewPowGuard(signing.NewRandom(), PowOptions{Difficulty: -1})
+
+	challenge := g.Challenge("203.0.113.7")
+	if challenge.Difficulty != 0 {
+		t.Fatalf("difficulty = %d, want 0", challenge.Difficulty)
+	}
+	solution := PowSolution{Token: challenge.Token, Nonce: "0"}
+	if err := g.Verify("203.0.113.7", solution); err != nil {
+		t.Errorf("Verify = %v, want any nonce accepted", err)
+	}
+	if err := g.Verify("203.0.113.7", solution); !errors.Is(err, ErrPowInvalid) {
+		t.Errorf("replay with the work off = %v, want ErrPowInvalid", err)
+	}
+}
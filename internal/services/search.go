@@ -1,20 +1,81 @@
 package services
 
 import (
+	"context"
 	"sort"
-	"strings"
+	"sync"
 	"time"
 
+	"showcase-datastar-go/internal/search"
 	"showcase-datastar-go/internal/templates/fragments"
 )
 
+// Search modes selectable through SearchParams.Mode
+const (
+	SearchModeLexical = search.ModeLexical
+	SearchModeVector  = search.ModeVector
+	SearchModeHybrid  = search.ModeHybrid
+)
+
 type SearchService struct {
-	mockData []fragments.SearchResult
+	engine   *search.Engine[fragments.SearchResult]
+	taxonomy *Taxonomy
+
+	// mu serializes imports and guards catalogPath and snapshotPath
+	mu sync.Mutex
+
+	// catalogPath is where imported catalogs are persisted, if set
+	catalogPath string
+
+	// snapshotPath is where the built index is persisted, if set
+	snapshotPath string
 }
 
 func NewSearchService() *SearchService {
 	return &SearchService{
-		mockData: getMockSearchData(),
+		engine:   search.New(catalogMapping(), getMockSearchData()),
+		taxonomy: NewTaxonomy(getMockTaxonomy()),
+	}
+}
+
+// catalogMapping describes how the generic engine reads catalog items
+func catalogMapping() search.Mapping[fragments.SearchResult] {
+	return search.Mapping[fragments.SearchResult]{
+		ID: func(item fragments.SearchResult) string { return item.ID },
+		Fields: []search.Field[fragments.SearchResult]{
+			{
+				Name:   "title",
+				Text:   func(item fragments.SearchResult) string { return item.Title },
+				Phrase: 1.0,
+				Word:   0.6,
+				Fuzzy:  0.5,
+				Embed:  2,
+			},
+			{
+				Name:   "description",
+				Text:   func(item fragments.SearchResult) string { return item.Description },
+				Phrase: 0.7,
+				Word:   0.4,
+				Fuzzy:  0.3,
+			},
+			{
+				Name:  "tags",
+				Terms: func(item fragments.SearchResult) []string { return item.Tags },
+				Word:  0.3,
+			},
+			{
+				Name: "category",
+				Text: func(item fragments.SearchResult) string { return item.Category },
+			},
+		},
+		// Boost by popularity (normalize to 0-0.2 range)
+		Boost: func(item fragments.SearchResult) float64 {
+			popularityBoost := float64(item.Popularity) / 10000000.0
+			if popularityBoost > 0.2 {
+				popularityBoost = 0.2
+			}
+			return popularityBoost
+		},
 	}
 }
 
@@ -25,6 +86,15 @@ type SearchParams struct {
 	Sort     string
 	Offset   int
 	Limit    int
+
+	// Mode selects lexical (default), vector or hybrid ranking
+	Mode string
+
+	// Tag keeps only items carrying this tag
+	Tag string
+
+	// CategoryBoosts slightly favors categories the visitor clicked before
+	CategoryBoosts map[string]float64
 }
 
 // SearchResponse represents search response
@@ -33,57 +103,52 @@ type SearchResponse struct {
 	TotalResults int
 	Query        string
 	Duration     time.Duration
+
+	// Facets holds per-category counts for the query, rolled up the taxonomy
+	Facets []fragments.CategoryFacet
+
+	// Partial is set when ctx ended before the whole index was scanned
+	Partial bool
 }
 
-// Search performs the search with fuzzy matching and filtering
-func (s *SearchService) Search(params SearchParams) *SearchResponse {
+// Search performs the search with fuzzy matching and filtering. It stops
+// scanning when ctx is done and returns what it found so far as Partial.
+func (s *SearchService) Search(ctx context.Context, params SearchParams) *SearchResponse {
 	startTime := time.Now()
 
 	if params.Limit == 0 {
 		params.Limit = 10
 	}
 
-	var results []fragments.SearchResult
+	result := s.engine.Search(ctx, search.Query[fragments.SearchResult]{
+		Text: params.Query,
+		Mode: params.Mode,
+		Filter: func(item fragments.SearchResult) bool {
+			return params.Tag == "" || hasTag(item.Tags, params.Tag)
+		},
+		Boost: func(item fragments.SearchResult) float64 {
+			return params.CategoryBoosts[item.Category]
+		},
+	})
+
+	results := make([]fragments.SearchResult, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		hit.Doc.Score = hit.Score
+		results = append(results, hit.Doc)
+	}
 
-	// Filter by category
-	candidates := s.mockData
+	// Count matches per category before narrowing to the selected one
+	facets := s.taxonomy.Facets(s.taxonomy.RollUpCounts(results), true)
+
+	// Filter by category, including its descendants
 	if params.Category != "" && params.Category != "all" {
 		var filtered []fragments.SearchResult
-		for _, item := range candidates {
-			if item.Category == params.Category {
+		for _, item := range results {
+			if s.taxonomy.Contains(params.Category, item.Category) {
 				filtered = append(filtered, item)
 			}
 		}
-		candidates = filtered
-	}
-
-	// Search and score
-	if params.Query != "" {
-		var scored []scoredResult
-		query := strings.ToLower(params.Query)
-
-		for _, item := range candidates {
-			score := s.calculateScore(item, query)
-			if score > 0.1 { // Minimum relevance threshold
-				scored = append(scored, scoredResult{
-					result: item,
-					score:  score,
-				})
-			}
-		}
-
-		// Sort by score
-		sort.Slice(scored, func(i, j int) bool {
-			return scored[i].score > scored[j].score
-		})
-
-		// Extract results
-		for _, sr := range scored {
-			sr.result.Score = sr.score
-			results = append(results, sr.result)
-		}
-	} else {
-		results = candidates
+		results = filtered
 	}
 
 	// Apply sorting
@@ -92,14 +157,11 @@ func (s *SearchService) Search(params SearchParams) *SearchResponse {
 	totalResults := len(results)
 
 	// Apply pagination
-	if params.Offset >= len(results) {
-		results = []fragments.SearchResult{}
-	} else {
-		end := params.Offset + params.Limit
-		if end > len(results) {
-			end = len(results)
-		}
-		results = results[params.Offset:end]
+	results = search.Paginate(results, params.Offset, params.Limit)
+
+	// Attach breadcrumbs to the returned page only
+	for i := range results {
+		results[i].CategoryPath = s.taxonomy.Path(results[i].Category)
 	}
 
 	return &SearchResponse{
@@ -107,80 +169,24 @@ func (s *SearchService) Search(params SearchParams) *SearchResponse {
 		TotalResults: totalResults,
 		Query:        params.Query,
 		Duration:     time.Since(startTime),
+		Facets:       facets,
+		Partial:      result.Partial,
 	}
 }
 
-type scoredResult struct {
-	result fragments.SearchResult
-	score  float64
+// Taxonomy returns the category tree loaded with the catalog
+func (s *SearchService) Taxonomy() *Taxonomy {
+	return s.taxonomy
 }
 
-// calculateScore calculates relevance score for fuzzy matching
-func (s *SearchService) calculateScore(item fragments.SearchResult, query string) float64 {
-	score := 0.0
-
-	title := strings.ToLower(item.Title)
-	description := strings.ToLower(item.Description)
-
-	// Exact match gets highest score
-	if strings.Contains(title, query) {
-		score += 1.0
-	} else if strings.Contains(description, query) {
-		score += 0.7
-	}
-
-	// Partial word matches
-	queryWords := strings.Fields(query)
-	for _, word := range queryWords {
-		if strings.Contains(title, word) {
-			score += 0.6
-		}
-		if strings.Contains(description, word) {
-			score += 0.4
-		}
-
-		// Check tags
-		for _, tag := range item.Tags {
-			if strings.Contains(strings.ToLower(tag), word) {
-				score += 0.3
-			}
-		}
-	}
-
-	// Fuzzy matching (simple implementation)
-	score += s.fuzzyMatch(title, query) * 0.5
-	score += s.fuzzyMatch(description, query) * 0.3
-
-	// Boost by popularity (normalize to 0-0.2 range)
-	popularityBoost := float64(item.Popularity) / 10000000.0
-	if popularityBoost > 0.2 {
-		popularityBoost = 0.2
-	}
-	score += popularityBoost
-
-	return score
+// Categories returns the whole taxonomy with catalog counts per node
+func (s *SearchService) Categories() []fragments.CategoryFacet {
+	return s.taxonomy.Facets(s.taxonomy.RollUpCounts(s.engine.Docs()), false)
 }
 
-// Simple fuzzy matching implementation
-func (s *SearchService) fuzzyMatch(text, query string) float64 {
-	if len(query) == 0 {
-		return 0
-	}
-
-	text = strings.ToLower(text)
-	query = strings.ToLower(query)
-
-	matches := 0
-	queryIndex := 0
-
-	for i := 0; i < len(text) && queryIndex < len(query); i++ {
-		if text[i] == query[queryIndex] {
-			matches++
-			queryIndex++
-		}
-	}
-
-	return float64(matches) / float64(len(query))
+// GetByID returns a single item by its ID
+func (s *SearchService) GetByID(id string) (fragments.SearchResult, bool) {
+	return s.engine.Get(id)
 }
 
 // applySorting applies sorting to results
@@ -212,7 +218,7 @@ func getMockSearchData() []fragments.SearchResult {
 			ID:          "go",
 			Title:       "Go (Golang)",
 			Description: "Linguagem de programação open source desenvolvida pelo Google. Simples, rápida e confiável para construir software eficiente.",
-			Category:    "languages",
+			Category:    "systems-languages",
 			Tags:        []string{"google", "compiled", "concurrent", "backend", "microservices", "cloud"},
 			URL:         "https://golang.org",
 			Icon:        "trending-up",
@@ -223,7 +229,7 @@ func getMockSearchData() []fragments.SearchResult {
 			ID:          "javascript",
 			Title:       "JavaScript",
 			Description: "Linguagem de programação dinâmica essencial para desenvolvimento web, tanto frontend quanto backend com Node.js.",
-			Category:    "languages",
+			Category:    "web-languages",
 			Tags:        []string{"web", "frontend", "backend", "nodejs", "es6", "typescript"},
 			URL:         "https://developer.mozilla.org/en-US/docs/Web/JavaScript",
 			Icon:        "trending-up",
@@ -234,7 +240,7 @@ func getMockSearchData() []fragments.SearchResult {
 			ID:          "python",
 			Title:       "Python",
 			Description: "Linguagem de programação de alto nível, interpretada e de propósito geral. Ideal para IA, ciência de dados e desenvolvimento web.",
-			Category:    "languages",
+			Category:    "scripting-languages",
 			Tags:        []string{"ai", "data-science", "django", "flask", "machine-learning"},
 			URL:         "https://python.org",
 			Icon:        "trending-up",
@@ -245,7 +251,7 @@ func getMockSearchData() []fragments.SearchResult {
 			ID:          "react",
 			Title:       "React",
 			Description: "Biblioteca JavaScript para construir interfaces de usuário, especialmente single-page applications. Desenvolvida pelo Facebook.",
-			Category:    "frameworks",
+			Category:    "react",
 			Tags:        []string{"facebook", "frontend", "jsx", "virtual-dom", "spa", "hooks"},
 			URL:         "https://reactjs.org",
 			Icon:        "trending-up",
@@ -256,7 +262,7 @@ func getMockSearchData() []fragments.SearchResult {
 			ID:          "gin",
 			Title:       "Gin Framework",
 			Description: "Framework web HTTP de alta performance para Go. Oferece API rápida e minimalista com suporte a middleware.",
-			Category:    "frameworks",
+			Category:    "backend",
 			Tags:        []string{"go", "web", "http", "api", "performance", "middleware"},
 			URL:         "https://gin-gonic.com",
 			Icon:        "trending-up",
@@ -267,7 +273,7 @@ func getMockSearchData() []fragments.SearchResult {
 			ID:          "docker",
 			Title:       "Docker",
 			Description: "Plataforma de containerização que permite embalar aplicações em containers leves e portáveis.",
-			Category:    "tools",
+			Category:    "containers",
 			Tags:        []string{"containers", "devops", "deployment", "microservices", "kubernetes"},
 			URL:         "https://docker.com",
 			Icon:        "trending-up",
@@ -278,7 +284,7 @@ func getMockSearchData() []fragments.SearchResult {
 			ID:          "postgresql",
 			Title:       "PostgreSQL",
 			Description: "Sistema de gerenciamento de banco de dados relacional open source avançado e confiável.",
-			Category:    "databases",
+			Category:    "relational",
 			Tags:        []string{"sql", "relational", "acid", "json", "performance", "enterprise"},
 			URL:         "https://postgresql.org",
 			Icon:        "trending-up",
@@ -289,7 +295,7 @@ func getMockSearchData() []fragments.SearchResult {
 			ID:          "mongodb",
 			Title:       "MongoDB",
 			Description: "Banco de dados NoSQL orientado a documentos, oferece alta performance, disponibilidade e escalabilidade.",
-			Category:    "databases",
+			Category:    "document",
 			Tags:        []string{"nosql", "document", "json", "scalability", "big-data"},
 			URL:         "https://mongodb.com",
 			Icon:        "trending-up",
@@ -300,7 +306,7 @@ func getMockSearchData() []fragments.SearchResult {
 			ID:          "vue",
 			Title:       "Vue.js",
 			Description: "Framework JavaScript progressivo para construir interfaces de usuário. Fácil de aprender e altamente performático.",
-			Category:    "frameworks",
+			Category:    "vue",
 			Tags:        []string{"frontend", "spa", "progressive", "components", "reactivity"},
 			URL:         "https://vuejs.org",
 			Icon:        "trending-up",
@@ -311,7 +317,7 @@ func getMockSearchData() []fragments.SearchResult {
 			ID:          "rust",
 			Title:       "Rust",
 			Description: "Linguagem de programação de sistemas que oferece memory safety sem garbage collection e performance excepcional.",
-			Category:    "languages",
+			Category:    "systems-languages",
 			Tags:        []string{"systems", "memory-safe", "performance", "mozilla", "webassembly"},
 			URL:         "https://rust-lang.org",
 			Icon:        "trending-up",
@@ -322,7 +328,7 @@ func getMockSearchData() []fragments.SearchResult {
 			ID:          "kubernetes",
 			Title:       "Kubernetes",
 			Description: "Sistema open source para automatizar deployment, escalonamento e gerenciamento de aplicações containerizadas.",
-			Category:    "tools",
+			Category:    "orchestration",
 			Tags:        []string{"orchestration", "containers", "devops", "microservices", "google"},
 			URL:         "https://kubernetes.io",
 			Icon:        "trending-up",
@@ -333,7 +339,7 @@ func getMockSearchData() []fragments.SearchResult {
 			ID:          "tailwindcss",
 			Title:       "Tailwind CSS",
 			Description: "Framework CSS utility-first que permite construir designs customizados rapidamente sem deixar o HTML.",
-			Category:    "frameworks",
+			Category:    "css",
 			Tags:        []string{"css", "utility", "responsive", "design", "frontend"},
 			URL:         "https://tailwindcss.com",
 			Icon:        "trending-up",
@@ -344,7 +350,7 @@ func getMockSearchData() []fragments.SearchResult {
 			ID:          "redis",
 			Title:       "Redis",
 			Description: "Estrutura de dados in-memory open source usada como database, cache e message broker.",
-			Category:    "databases",
+			Category:    "key-value",
 			Tags:        []string{"in-memory", "cache", "performance", "key-value", "pub-sub"},
 			URL:         "https://redis.io",
 			Icon:        "trending-up",
@@ -355,7 +361,7 @@ func getMockSearchData() []fragments.SearchResult {
 			ID:          "nextjs",
 			Title:       "Next.js",
 			Description: "Framework React para produção que oferece server-side rendering, static generation e muitas outras funcionalidades.",
-			Category:    "frameworks",
+			Category:    "react",
 			Tags:        []string{"react", "ssr", "static", "vercel", "full-stack"},
 			URL:         "https://nextjs.org",
 			Icon:        "trending-up",
@@ -366,7 +372,7 @@ func getMockSearchData() []fragments.SearchResult {
 			ID:          "typescript",
 			Title:       "TypeScript",
 			Description: "Superset tipado do JavaScript que adiciona definições de tipos estáticos opcionais ao JavaScript.",
-			Category:    "languages",
+			Category:    "web-languages",
 			Tags:        []string{"microsoft", "javascript", "types", "frontend", "backend"},
 			URL:         "https://typescriptlang.org",
 			Icon:        "trending-up",
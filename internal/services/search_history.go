@@ -0,0 +1,274 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"showcase-datastar-go/internal/templates/fragments"
+)
+
+const (
+	// DefaultRecentSearches is how many queries are remembered per session
+	DefaultRecentSearches = 8
+
+	// searchSessionTTL is how long an idle session is kept in memory
+	searchSessionTTL = 24 * time.Hour
+
+	// maxSearchSessions bounds the sessions kept in memory; past it the one
+	// idle the longest is dropped
+	maxSearchSessions = 10000
+
+	// maxCategoryBoost caps the personalization boost (same range as popularity)
+	maxCategoryBoost = 0.2
+
+	// trendWindow is how far back activity counts towards trending tags
+	trendWindow = 24 * time.Hour
+
+	// trendHalfLife is how quickly past activity loses weight
+	trendHalfLife = time.Hour
+
+	// maxTrendEvents bounds the global activity log
+	maxTrendEvents = 1000
+
+	// clickTrendWeight makes a click count more than a search
+	clickTrendWeight = 2.0
+)
+
+// SearchHistoryService keeps per-session search history and category clicks,
+// plus a global log of recent activity used for trending tags
+type SearchHistoryService struct {
+	sessions  map[string]*searchSession
+	activity  []trendEvent
+	mu        sync.RWMutex
+	maxRecent int
+}
+
+// trendEvent is a search (query text and tag filter) or a click (item tags)
+type trendEvent struct {
+	at     time.Time
+	query  string
+	tags   []string
+	weight float64
+}
+
+type searchSession struct {
+	recent         []fragments.RecentSearch
+	categoryClicks map[string]int
+	lastSeen       time.Time
+}
+
+func NewSearchHistoryService(maxRecent int) *SearchHistoryService {
+	if maxRecent <= 0 {
+		maxRecent = DefaultRecentSearches
+	}
+
+	return &SearchHistoryService{
+		sessions:  make(map[string]*searchSession),
+		maxRecent: maxRecent,
+	}
+}
+
+// Record stores a search in the session history, most recent first
+func (hs *SearchHistoryService) Record(sessionID string, params SearchParams) {
+	if sessionID == "" || params.Query == "" {
+		return
+	}
+
+	entry := fragments.RecentSearch{
+		Query:    params.Query,
+		Category: params.Category,
+		Sort:     params.Sort,
+		Mode:     params.Mode,
+	}
+	if entry.Category == "" {
+		entry.Category = "all"
+	}
+	if entry.Sort == "" {
+		entry.Sort = "relevance"
+	}
+	if entry.Mode == "" {
+		entry.Mode = SearchModeLexical
+	}
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	session := hs.session(sessionID)
+
+	// Move duplicates to the top instead of repeating them
+	recent := []fragments.RecentSearch{entry}
+	for _, existing := range session.recent {
+		if existing == entry {
+			continue
+		}
+		recent = append(recent, existing)
+	}
+	if len(recent) > hs.maxRecent {
+		recent = recent[:hs.maxRecent]
+	}
+	session.recent = recent
+
+	event := trendEvent{query: normalizeTagText(params.Query), weight: 1}
+	if params.Tag != "" {
+		event.tags = []string{strings.ToLower(params.Tag)}
+	}
+	hs.addActivity(event)
+}
+
+// RecordClick registers a click on a result, counting towards its category
+// for personalization and its tags for trending
+func (hs *SearchHistoryService) RecordClick(sessionID string, item fragments.SearchResult) {
+	if sessionID == "" || item.Category == "" {
+		return
+	}
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	hs.session(sessionID).categoryClicks[item.Category]++
+	hs.addActivity(trendEvent{tags: uniqueTags(item.Tags), weight: clickTrendWeight})
+}
+
+// TrendingTags scores each tag by recent activity across all sessions:
+// searches mentioning the tag and clicks on items carrying it, with older
+// events decaying exponentially
+func (hs *SearchHistoryService) TrendingTags(tags []string) map[string]float64 {
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+
+	now := time.Now()
+	trends := make(map[string]float64)
+
+	for _, event := range hs.activity {
+		age := now.Sub(event.at)
+		if age > trendWindow {
+			continue
+		}
+		weight := event.weight * math.Exp2(-age.Hours()/trendHalfLife.Hours())
+
+		for _, tag := range tags {
+			if hasTag(event.tags, tag) || queryMentionsTag(event.query, tag) {
+				trends[tag] += weight
+			}
+		}
+	}
+	return trends
+}
+
+// addActivity appends to the activity log; caller must hold the lock
+func (hs *SearchHistoryService) addActivity(event trendEvent) {
+	event.at = time.Now()
+
+	cutoff := 0
+	for cutoff < len(hs.activity) && event.at.Sub(hs.activity[cutoff].at) > trendWindow {
+		cutoff++
+	}
+	cutoff = max(cutoff, len(hs.activity)+1-maxTrendEvents)
+
+	hs.activity = append(hs.activity[cutoff:], event)
+}
+
+// normalizeTagText lowercases text and treats hyphens as spaces, so that
+// "big data" and "big-data" compare equal
+func normalizeTagText(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(strings.ReplaceAll(text, "-", " "))), " ")
+}
+
+// queryMentionsTag reports whether the tag appears as whole words in query,
+// which must already be normalized
+func queryMentionsTag(query, tag string) bool {
+	if query == "" {
+		return false
+	}
+	return strings.Contains(" "+query+" ", " "+normalizeTagText(tag)+" ")
+}
+
+// Recent returns the session's recent searches, most recent first
+func (hs *SearchHistoryService) Recent(sessionID string) []fragments.RecentSearch {
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+
+	session, exists := hs.sessions[sessionID]
+	if !exists {
+		return []fragments.RecentSearch{}
+	}
+
+	recent := make([]fragments.RecentSearch, len(session.recent))
+	copy(recent, session.recent)
+	return recent
+}
+
+// CategoryBoosts returns a score boost per category proportional to the
+// share of clicks the session gave to that category
+func (hs *SearchHistoryService) CategoryBoosts(sessionID string) map[string]float64 {
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+
+	session, exists := hs.sessions[sessionID]
+	if !exists || len(session.categoryClicks) == 0 {
+		return nil
+	}
+
+	total := 0
+	for _, clicks := range session.categoryClicks {
+		total += clicks
+	}
+
+	boosts := make(map[string]float64, len(session.categoryClicks))
+	for category, clicks := range session.categoryClicks {
+		boosts[category] = float64(clicks) / float64(total) * maxCategoryBoost
+	}
+	return boosts
+}
+
+// Clear removes all history for a session
+func (hs *SearchHistoryService) Clear(sessionID string) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	delete(hs.sessions, sessionID)
+}
+
+// session returns (creating if needed) a session; caller must hold the lock
+func (hs *SearchHistoryService) session(sessionID string) *searchSession {
+	now := time.Now()
+	hs.pruneExpired(now)
+
+	session, exists := hs.sessions[sessionID]
+	if !exists {
+		if len(hs.sessions) >= maxSearchSessions {
+			hs.dropIdlest()
+		}
+		session = &searchSession{
+			recent:         []fragments.RecentSearch{},
+			categoryClicks: make(map[string]int),
+		}
+		hs.sessions[sessionID] = session
+	}
+	session.lastSeen = now
+
+	return session
+}
+
+// dropIdlest drops the session seen the longest ago; caller must hold the lock
+func (hs *SearchHistoryService) dropIdlest() {
+	idlest := ""
+	var seen time.Time
+	for id, session := range hs.sessions {
+		if idlest == "" || session.lastSeen.Before(seen) {
+			idlest, seen = id, session.lastSeen
+		}
+	}
+	delete(hs.sessions, idlest)
+}
+
+// pruneExpired drops idle sessions; caller must hold the lock
+func (hs *SearchHistoryService) pruneExpired(now time.Time) {
+	for id, session := range hs.sessions {
+		if now.Sub(session.lastSeen) > searchSessionTTL {
+			delete(hs.sessions, id)
+		}
+	}
+}
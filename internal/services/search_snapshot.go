@@ -0,0 +1,107 @@
+package services
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+
+	"showcase-datastar-go/internal/search"
+)
+
+// catalogIndexVersion is part of the snapshot fingerprint; bump it whenever
+// catalogMapping changes what goes into the vector index
+const catalogIndexVersion = "catalog-index-1"
+
+// SnapshotStatus tells how the index was obtained at startup
+type SnapshotStatus string
+
+const (
+	SnapshotRestored SnapshotStatus = "restored"
+	SnapshotMissing  SnapshotStatus = "missing"
+	SnapshotStale    SnapshotStatus = "stale"
+	SnapshotCorrupt  SnapshotStatus = "corrupt"
+)
+
+// UseIndexSnapshot restores the search index from the snapshot at path when
+// it was built from the current catalog; otherwise (missing, stale or
+// corrupt) it rebuilds the index and rewrites the snapshot. Call it after
+// LoadCatalogFile. Later imports keep the snapshot up to date.
+func (s *SearchService) UseIndexSnapshot(path string) (SnapshotStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshotPath = path
+
+	status, err := s.readIndexSnapshot()
+	if status == SnapshotRestored {
+		return status, nil
+	}
+	if status == "" {
+		return status, err
+	}
+
+	return status, s.writeIndexSnapshot()
+}
+
+// readIndexSnapshot loads the snapshot into the engine. An empty status
+// means the file could not be read at all; caller must hold s.mu.
+func (s *SearchService) readIndexSnapshot() (SnapshotStatus, error) {
+	file, err := os.Open(s.snapshotPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return SnapshotMissing, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	source, err := s.catalogFingerprint()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.engine.ReadSnapshot(bufio.NewReader(file), source)
+	switch {
+	case err == nil:
+		return SnapshotRestored, nil
+	case errors.Is(err, search.ErrSnapshotStale):
+		return SnapshotStale, err
+	case errors.Is(err, search.ErrSnapshotCorrupt):
+		return SnapshotCorrupt, err
+	default:
+		return "", err
+	}
+}
+
+// writeIndexSnapshot builds the index and persists it; caller must hold s.mu
+func (s *SearchService) writeIndexSnapshot() error {
+	source, err := s.catalogFingerprint()
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.snapshotPath, func(w io.Writer) error {
+		return s.engine.WriteSnapshot(w, source)
+	})
+}
+
+// catalogFingerprint hashes the indexed catalog together with the index
+// version, so any change to the catalog source invalidates the snapshot
+func (s *SearchService) catalogFingerprint() ([sha256.Size]byte, error) {
+	docs := s.engine.Docs()
+
+	records := make([]CatalogRecord, 0, len(docs))
+	for _, item := range docs {
+		records = append(records, catalogRecordFrom(item))
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+
+	return sha256.Sum256(append([]byte(catalogIndexVersion+"\n"), data...)), nil
+}
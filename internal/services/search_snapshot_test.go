@@ -0,0 +1,47 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUseIndexSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.snap")
+
+	useSnapshot := func(s *SearchService, want SnapshotStatus) {
+		t.Helper()
+		status, err := s.UseIndexSnapshot(path)
+		if err != nil {
+			t.Fatalf("UseIndexSnapshot: %v", err)
+		}
+		if status != want {
+			t.Fatalf("UseIndexSnapshot = %s, want %s", status, want)
+		}
+	}
+
+	// Missing: built and written
+	useSnapshot(NewSearchService(), SnapshotMissing)
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("snapshot not written: %v", err)
+	}
+
+	// Same catalog: restored as is
+	useSnapshot(NewSearchService(), SnapshotRestored)
+
+	// Another catalog: rebuilt, and the rewritten snapshot matches it
+	changed := NewSearchService()
+	docs := changed.engine.Docs()
+	changed.engine.Replace(docs[:len(docs)-1])
+	useSnapshot(changed, SnapshotStale)
+	restored := NewSearchService()
+	restored.engine.Replace(docs[:len(docs)-1])
+	useSnapshot(restored, SnapshotRestored)
+
+	// Corrupt: rebuilt and rewritten
+	if err := os.WriteFile(path, []byte("SRCHSNAP not really a snapshot"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	useSnapshot(NewSearchService(), SnapshotCorrupt)
+	useSnapshot(NewSearchService(), SnapshotRestored)
+}
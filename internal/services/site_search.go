@@ -0,0 +1,240 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/a-h/templ"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"showcase-datastar-go/internal/search"
+	"showcase-datastar-go/internal/templates/fragments"
+)
+
+const (
+	// DefaultPageResults is how many page hits are shown above catalog results
+	DefaultPageResults = 4
+
+	// snippetLength is the approximate size in runes of a page hit excerpt
+	snippetLength = 160
+)
+
+// SitePage is a showcase page indexed by the site search
+type SitePage struct {
+	Path      string
+	Title     string
+	Component templ.Component
+}
+
+// pageSection is a heading of a rendered page with the text under it
+type pageSection struct {
+	ID        string
+	Path      string
+	PageTitle string
+	Heading   string
+	Text      string
+}
+
+// SiteSearchService searches the content of the showcase pages themselves
+type SiteSearchService struct {
+	engine *search.Engine[pageSection]
+}
+
+// NewSiteSearchService renders every page once and indexes its sections
+func NewSiteSearchService(pages []SitePage) (*SiteSearchService, error) {
+	var sections []pageSection
+
+	for _, page := range pages {
+		var buf bytes.Buffer
+		if err := page.Component.Render(context.Background(), &buf); err != nil {
+			return nil, fmt.Errorf("render %s: %w", page.Path, err)
+		}
+
+		pageSections, err := extractSections(page, &buf)
+		if err != nil {
+			return nil, fmt.Errorf("index %s: %w", page.Path, err)
+		}
+		sections = append(sections, pageSections...)
+	}
+
+	return &SiteSearchService{
+		engine: search.New(pageSectionMapping(), sections),
+	}, nil
+}
+
+// pageSectionMapping weighs headings well above body text, which is long
+// and would otherwise match almost any word
+func pageSectionMapping() search.Mapping[pageSection] {
+	return search.Mapping[pageSection]{
+		ID: func(s pageSection) string { return s.ID },
+		Fields: []search.Field[pageSection]{
+			{
+				Name:   "heading",
+				Text:   func(s pageSection) string { return s.Heading },
+				Phrase: 1.0,
+				Word:   0.6,
+				Fuzzy:  0.3,
+				Embed:  2,
+			},
+			{
+				Name: "page",
+				Text: func(s pageSection) string { return s.PageTitle },
+				Word: 0.2,
+			},
+			{
+				Name:   "text",
+				Text:   func(s pageSection) string { return s.Text },
+				Phrase: 0.5,
+				Word:   0.2,
+			},
+		},
+		MinScore: 0.5,
+	}
+}
+
+// Search returns the best page sections for query; the flag reports
+// whether ctx ended before every section was scanned
+func (s *SiteSearchService) Search(ctx context.Context, query, mode string, limit int) ([]fragments.PageResult, bool) {
+	if strings.TrimSpace(query) == "" {
+		return []fragments.PageResult{}, false
+	}
+
+	result := s.engine.Search(ctx, search.Query[pageSection]{
+		Text: query,
+		Mode: mode,
+	})
+
+	hits := search.Paginate(result.Hits, 0, limit)
+	pages := make([]fragments.PageResult, 0, len(hits))
+	for _, hit := range hits {
+		section := hit.Doc
+		pages = append(pages, fragments.PageResult{
+			Page:    section.PageTitle,
+			Heading: section.Heading,
+			Snippet: snippet(section.Text, query),
+			URL:     sectionURL(section),
+			Score:   hit.Score,
+		})
+	}
+	return pages, result.Partial
+}
+
+// skippedElements carry no readable content
+var skippedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Template: true,
+	atom.Select:   true,
+	atom.Textarea: true,
+}
+
+// extractSections splits rendered HTML into one section per h1–h3 heading;
+// text before the first heading belongs to a section titled after the page
+func extractSections(page SitePage, r io.Reader) ([]pageSection, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		sections []pageSection
+		heading  = page.Title
+		text     []string
+	)
+
+	flush := func() {
+		body := strings.Join(strings.Fields(strings.Join(text, " ")), " ")
+		if body != "" || (heading != "" && heading != page.Title) {
+			sections = append(sections, pageSection{
+				ID:        fmt.Sprintf("%s#%d", page.Path, len(sections)),
+				Path:      page.Path,
+				PageTitle: page.Title,
+				Heading:   heading,
+				Text:      body,
+			})
+		}
+		text = nil
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.ElementNode && skippedElements[n.DataAtom]:
+			return
+		case n.Type == html.ElementNode && (n.DataAtom == atom.H1 || n.DataAtom == atom.H2 || n.DataAtom == atom.H3):
+			flush()
+			heading = strings.Join(strings.Fields(nodeText(n)), " ")
+			return
+		case n.Type == html.TextNode:
+			text = append(text, n.Data)
+		}
+
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+	flush()
+
+	return sections, nil
+}
+
+// nodeText concatenates every text node under n
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+
+	var parts []string
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && skippedElements[child.DataAtom] {
+			continue
+		}
+		parts = append(parts, nodeText(child))
+	}
+	return strings.Join(parts, " ")
+}
+
+// sectionURL links to the page, scrolling to the heading in browsers that
+// support text fragments
+func sectionURL(section pageSection) string {
+	if section.Heading == "" || section.Heading == section.PageTitle {
+		return section.Path
+	}
+	return section.Path + "#:~:text=" + url.PathEscape(section.Heading)
+}
+
+// snippet returns an excerpt of text around the first query word it contains
+func snippet(text, query string) string {
+	if utf8.RuneCountInString(text) <= snippetLength {
+		return text
+	}
+
+	start := 0
+	lower := strings.ToLower(text)
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if i := strings.Index(lower, word); i >= 0 {
+			start = utf8.RuneCountInString(lower[:i]) - snippetLength/4
+			break
+		}
+	}
+
+	runes := []rune(text)
+	start = max(0, min(start, len(runes)-snippetLength))
+	excerpt := strings.TrimSpace(string(runes[start : start+snippetLength]))
+
+	if start > 0 {
+		excerpt = "… " + excerpt
+	}
+	if start+snippetLength < len(runes) {
+		excerpt += " …"
+	}
+	return excerpt
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"showcase-datastar-go/internal/signing"
+)
+
+// SpamReason tells why a submission was taken for spam
+type SpamReason string
+
+const (
+	// SpamHoneypot means the hidden field, invisible to people, was filled
+	SpamHoneypot SpamReason = "honeypot"
+
+	// SpamNoToken means the render token is missing or forged
+	SpamNoToken SpamReason = "token"
+
+	// SpamTooFast means the form was sent quicker than a person can type
+	SpamTooFast SpamReason = "too_fast"
+
+	// SpamIPRate means too many submissions from the same IP
+	SpamIPRate SpamReason = "ip_rate"
+
+	// SpamEmailRate means too many submissions with the same email
+	SpamEmailRate SpamReason = "email_rate"
+)
+
+// ErrFormExpired means the page was rendered too long ago; unlike spam,
+// the visitor is told to reload
+var ErrFormExpired = errors.New("form expired")
+
+const formRenderPurpose = "form-render"
+
+// Forms screened by the SpamGuard
+const (
+	FormContact    = "contact"
+	FormNewsletter = "newsletter"
+)
+
+// FormGuard is what the page sends along with a form: the signed render
+// token and the honeypot field
+type FormGuard struct {
+	Token   string `json:"token"`
+	Website string `json:"website"`
+}
+
+// Submission is a form submission to screen
+type Submission struct {
+	Form  string
+	IP    string
+	Email string
+	Guard FormGuard
+}
+
+// SpamOptions tune a SpamGuard; zero values pick the defaults
+type SpamOptions struct {
+	// MinDelay is the least time between rendering and submitting (default 3s)
+	MinDelay time.Duration
+
+	// TokenTTL is how long a rendered form can be submitted (default 24h)
+	TokenTTL time.Duration
+
+	// IPBurst submissions per IP and form are allowed at once, then one
+	// every IPRefill (defaults 5 and 1m)
+	IPBurst  int
+	IPRefill time.Duration
+
+	// EmailBurst submissions per email and form are allowed at once, then
+	// one every EmailRefill (defaults 3 and 10m)
+	EmailBurst  int
+	EmailRefill time.Duration
+}
+
+// SpamStats counts the submissions dropped as spam
+type SpamStats struct {
+	Blocked     int64                `json:"blocked"`
+	Reasons     map[SpamReason]int64 `json:"reasons"`
+	Forms       map[string]int64     `json:"forms"`
+	LastBlocked *time.Time           `json:"lastBlocked,omitempty"`
+}
+
+// SpamGuard screens public form submissions. Spam is meant to be accepted
+// silently and not stored, so bots get no signal to adapt to.
+type SpamGuard struct {
+	signer  *signing.Signer
+	options SpamOptions
+
+	ipLimiter    *rateLimiter
+	emailLimiter *rateLimiter
+
+	mu    sync.Mutex
+	stats SpamStats
+}
+
+func NewSpamGuard(signer *signing.Signer, options SpamOptions) *SpamGuard {
+	if options.MinDelay <= 0 {
+		options.MinDelay = 3 * time.Second
+	}
+	if options.TokenTTL <= 0 {
+		options.TokenTTL = 24 * time.Hour
+	}
+	if options.IPBurst <= 0 {
+		options.IPBurst = 5
+	}
+	if options.IPRefill <= 0 {
+		options.IPRefill = time.Minute
+	}
+	if options.EmailBurst <= 0 {
+		options.EmailBurst = 3
+	}
+	if options.EmailRefill <= 0 {
+		options.EmailRefill = 10 * time.Minute
+	}
+
+	return &SpamGuard{
+		signer:       signer,
+		options:      options,
+		ipLimiter:    newRateLimiter(options.IPBurst, options.IPRefill),
+		emailLimiter: newRateLimiter(options.EmailBurst, options.EmailRefill),
+		stats: SpamStats{
+			Reasons: map[SpamReason]int64{},
+			Forms:   map[string]int64{},
+		},
+	}
+}
+
+// RenderToken signs the time a form is rendered
+func (g *SpamGuard) RenderToken() string {
+	return g.signer.Sign(formRenderPurpose, strconv.FormatInt(time.Now().UnixMilli(), 10), g.options.TokenTTL)
+}
+
+// Check screens a submission. It returns the reason it's spam, or "" to
+// let it through; ErrFormExpired asks for a reload instead. Cheap checks go
+// first, so bots caught by them don't use up the rate limits of real people.
+func (g *SpamGuard) Check(submission Submission) (SpamReason, error) {
+	reason, err := g.check(submission)
+	if reason != "" {
+		g.record(submission, reason)
+	}
+	return reason, err
+}
+
+func (g *SpamGuard) check(submission Submission) (SpamReason, error) {
+	if strings.TrimSpace(submission.Guard.Website) != "" {
+		return SpamHoneypot, nil
+	}
+
+	rendered, err := g.signer.Verify(formRenderPurpose, submission.Guard.Token)
+	if errors.Is(err, signing.ErrExpiredToken) {
+		return "", ErrFormExpired
+	}
+	if err != nil {
+		return SpamNoToken, nil
+	}
+	millis, err := strconv.ParseInt(rendered, 10, 64)
+	if err != nil {
+		return SpamNoToken, nil
+	}
+	if time.Since(time.UnixMilli(millis)) < g.options.MinDelay {
+		return SpamTooFast, nil
+	}
+
+	if !g.ipLimiter.allow(submission.Form + "|" + submission.IP) {
+		return SpamIPRate, nil
+	}
+	if email := strings.ToLower(strings.TrimSpace(submission.Email)); email != "" {
+		if !g.emailLimiter.allow(submission.Form + "|" + email) {
+			return SpamEmailRate, nil
+		}
+	}
+	return "", nil
+}
+
+func (g *SpamGuard) record(submission Submission, reason SpamReason) {
+	now := time.Now()
+
+	g.mu.Lock()
+	g.stats.Blocked++
+	g.stats.Reasons[reason]++
+	g.stats.Forms[submission.Form]++
+	g.stats.LastBlocked = &now
+	g.mu.Unlock()
+
+	log.Printf("🛡️  Spam descartado no formulário %s (%s) de %s", submission.Form, reason, submission.IP)
+}
+
+// DecoyResult is the answer to a dropped submission: the same a real one
+// gets, so bots can't tell
+func DecoyResult(form, email string) *ValidationResult {
+	if form == FormNewsletter {
+		return &ValidationResult{
+			Valid:   true,
+			Message: newsletterPendingMessage + strings.TrimSpace(email),
+			Data:    newRecordID("news"),
+		}
+	}
+	return &ValidationResult{
+		Valid:   true,
+		Message: contactSentMessage,
+		Data:    newRecordID("contact"),
+	}
+}
+
+// Stats returns a copy of the spam counters
+func (g *SpamGuard) Stats() SpamStats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stats := SpamStats{
+		Blocked:     g.stats.Blocked,
+		Reasons:     make(map[SpamReason]int64, len(g.stats.Reasons)),
+		Forms:       make(map[string]int64, len(g.stats.Forms)),
+		LastBlocked: g.stats.LastBlocked,
+	}
+	for reason, count := range g.stats.Reasons {
+		stats.Reasons[reason] = count
+	}
+	for form, count := range g.stats.Forms {
+		stats.Forms[form] = count
+	}
+	return stats
+}
+
+// rateLimiter keeps a token bucket per key: burst tokens at most, one more
+// every refill
+type rateLimiter struct {
+	burst  float64
+	refill time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(burst int, refill time.Duration) *rateLimiter {
+	return &rateLimiter{
+		burst:     float64(burst),
+		refill:    refill,
+		buckets:   map[string]*tokenBucket{},
+		lastPrune: time.Now(),
+	}
+}
+
+// allow takes a token from the bucket of key, if there's one
+func (l *rateLimiter) allow(key string) bool {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = l.level(bucket, now)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+func (l *rateLimiter) level(bucket *tokenBucket, now time.Time) float64 {
+	return min(l.burst, bucket.tokens+float64(now.Sub(bucket.last))/float64(l.refill))
+}
+
+// prune forgets buckets that have refilled, which behave like new ones;
+// callers must hold l.mu
+func (l *rateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+	for key, bucket := range l.buckets {
+		if l.level(bucket, now) >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
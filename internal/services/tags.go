@@ -0,0 +1,162 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"showcase-datastar-go/internal/templates/fragments"
+)
+
+const (
+	// maxCoOccurringTags is how many related tags are reported per tag
+	maxCoOccurringTags = 5
+
+	// tagCloudLevels is the number of font sizes used by the tag cloud
+	tagCloudLevels = 5
+
+	// maxTrendingTags is how many tags the cloud highlights as trending
+	maxTrendingTags = 3
+)
+
+// TagCount is a tag with the number of documents it appears in
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// TagStat summarizes how a tag is used across the catalog
+type TagStat struct {
+	Tag         string     `json:"tag"`
+	Count       int        `json:"count"`
+	CoOccurring []TagCount `json:"coOccurring"`
+	Trending    float64    `json:"trending"`
+}
+
+// TagStats returns every catalog tag with its document count and the tags it
+// most often appears with, most used first
+func (s *SearchService) TagStats() []TagStat {
+	counts := make(map[string]int)
+	pairs := make(map[string]map[string]int)
+
+	for _, item := range s.engine.Docs() {
+		tags := uniqueTags(item.Tags)
+		for _, tag := range tags {
+			counts[tag]++
+			if pairs[tag] == nil {
+				pairs[tag] = make(map[string]int)
+			}
+			for _, other := range tags {
+				if other != tag {
+					pairs[tag][other]++
+				}
+			}
+		}
+	}
+
+	stats := make([]TagStat, 0, len(counts))
+	for tag, count := range counts {
+		related := make([]TagCount, 0, len(pairs[tag]))
+		for other, n := range pairs[tag] {
+			related = append(related, TagCount{Tag: other, Count: n})
+		}
+		sortTagCounts(related)
+		if len(related) > maxCoOccurringTags {
+			related = related[:maxCoOccurringTags]
+		}
+
+		stats = append(stats, TagStat{Tag: tag, Count: count, CoOccurring: related})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Tag < stats[j].Tag
+	})
+	return stats
+}
+
+// ApplyTrends fills the trending score of each stat
+func ApplyTrends(stats []TagStat, trends map[string]float64) {
+	for i := range stats {
+		stats[i].Trending = math.Round(trends[stats[i].Tag]*100) / 100
+	}
+}
+
+// TagCloud turns tag stats into cloud entries weighted by document count on
+// a log scale, flagging the most trending tags, in alphabetical order
+func TagCloud(stats []TagStat) []fragments.TagCloudItem {
+	maxCount := 1
+	for _, stat := range stats {
+		maxCount = max(maxCount, stat.Count)
+	}
+
+	trending := make([]TagStat, 0, len(stats))
+	for _, stat := range stats {
+		if stat.Trending > 0 {
+			trending = append(trending, stat)
+		}
+	}
+	sort.SliceStable(trending, func(i, j int) bool {
+		return trending[i].Trending > trending[j].Trending
+	})
+	hot := make(map[string]bool, maxTrendingTags)
+	for _, stat := range trending[:min(len(trending), maxTrendingTags)] {
+		hot[stat.Tag] = true
+	}
+
+	items := make([]fragments.TagCloudItem, 0, len(stats))
+	for _, stat := range stats {
+		weight := 1
+		if maxCount > 1 {
+			weight += int(math.Round(math.Log(float64(stat.Count)) / math.Log(float64(maxCount)) * (tagCloudLevels - 1)))
+		}
+
+		items = append(items, fragments.TagCloudItem{
+			Tag:      stat.Tag,
+			Count:    stat.Count,
+			Weight:   weight,
+			Trending: hot[stat.Tag],
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Tag < items[j].Tag
+	})
+	return items
+}
+
+// hasTag reports whether tags contains tag, ignoring case
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueTags lowercases tags and drops duplicates and blanks
+func uniqueTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	unique := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		unique = append(unique, tag)
+	}
+	return unique
+}
+
+func sortTagCounts(counts []TagCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Tag < counts[j].Tag
+	})
+}
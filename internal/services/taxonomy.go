@@ -0,0 +1,218 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"showcase-datastar-go/internal/templates/fragments"
+)
+
+// TaxonomyNode is a category in the catalog taxonomy tree
+type TaxonomyNode struct {
+	ID       string          `json:"id"`
+	Label    string          `json:"label"`
+	Parent   string          `json:"parent,omitempty"`
+	Children []*TaxonomyNode `json:"children,omitempty"`
+}
+
+// Taxonomy is a category tree indexed by node ID
+type Taxonomy struct {
+	nodes map[string]*TaxonomyNode
+	roots []*TaxonomyNode
+}
+
+// NewTaxonomy builds the tree from a flat node list; parents must be listed
+// before their children and unknown parents make the node a root
+func NewTaxonomy(nodes []TaxonomyNode) *Taxonomy {
+	t := &Taxonomy{
+		nodes: make(map[string]*TaxonomyNode, len(nodes)),
+	}
+
+	for _, n := range nodes {
+		node := &TaxonomyNode{
+			ID:     n.ID,
+			Label:  n.Label,
+			Parent: n.Parent,
+		}
+		t.nodes[node.ID] = node
+
+		if parent, exists := t.nodes[node.Parent]; exists && node.Parent != "" {
+			parent.Children = append(parent.Children, node)
+		} else {
+			node.Parent = ""
+			t.roots = append(t.roots, node)
+		}
+	}
+
+	return t
+}
+
+// LoadTaxonomyFile reads a taxonomy written by WriteTaxonomyFile: a JSON
+// array of nodes with their parent, parents listed before their children.
+// Unlike NewTaxonomy it rejects unknown parents and duplicate IDs.
+func LoadTaxonomyFile(path string) (*Taxonomy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []TaxonomyNode
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := validateTaxonomy(nodes); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return NewTaxonomy(nodes), nil
+}
+
+// WriteTaxonomyFile writes t in the format LoadTaxonomyFile reads
+func WriteTaxonomyFile(path string, t *Taxonomy) error {
+	data, err := json.MarshalIndent(t.Nodes(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// validateTaxonomy checks a flat node list before it becomes a tree
+func validateTaxonomy(nodes []TaxonomyNode) error {
+	if len(nodes) == 0 {
+		return errors.New("taxonomy has no categories")
+	}
+
+	seen := make(map[string]bool, len(nodes))
+	for i, node := range nodes {
+		switch {
+		case !catalogIDRegex.MatchString(node.ID):
+			return fmt.Errorf("category %d: invalid id %q", i+1, node.ID)
+		case seen[node.ID]:
+			return fmt.Errorf("category %q: duplicate id", node.ID)
+		case strings.TrimSpace(node.Label) == "":
+			return fmt.Errorf("category %q: label is required", node.ID)
+		case node.Parent != "" && !seen[node.Parent]:
+			return fmt.Errorf("category %q: parent %q must be listed before it", node.ID, node.Parent)
+		}
+		seen[node.ID] = true
+	}
+	return nil
+}
+
+// Nodes flattens the tree depth-first, parents before their children and
+// without the Children links, as NewTaxonomy takes it
+func (t *Taxonomy) Nodes() []TaxonomyNode {
+	nodes := make([]TaxonomyNode, 0, len(t.nodes))
+
+	var walk func(level []*TaxonomyNode)
+	walk = func(level []*TaxonomyNode) {
+		for _, node := range level {
+			nodes = append(nodes, TaxonomyNode{ID: node.ID, Label: node.Label, Parent: node.Parent})
+			walk(node.Children)
+		}
+	}
+	walk(t.roots)
+
+	return nodes
+}
+
+// Roots returns the top-level categories
+func (t *Taxonomy) Roots() []*TaxonomyNode {
+	return t.roots
+}
+
+// Node returns a node by ID
+func (t *Taxonomy) Node(id string) (*TaxonomyNode, bool) {
+	node, exists := t.nodes[id]
+	return node, exists
+}
+
+// Contains reports whether id is ancestor itself or one of its descendants
+func (t *Taxonomy) Contains(ancestor, id string) bool {
+	for node, exists := t.nodes[id]; exists; node, exists = t.nodes[node.Parent] {
+		if node.ID == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// Path returns the breadcrumb trail from the root down to id
+func (t *Taxonomy) Path(id string) []fragments.CategoryCrumb {
+	var path []fragments.CategoryCrumb
+	for node, exists := t.nodes[id]; exists; node, exists = t.nodes[node.Parent] {
+		path = append([]fragments.CategoryCrumb{{ID: node.ID, Label: node.Label}}, path...)
+	}
+	return path
+}
+
+// RollUpCounts counts items per category, adding every item to all of
+// its ancestors as well
+func (t *Taxonomy) RollUpCounts(items []fragments.SearchResult) map[string]int {
+	counts := make(map[string]int)
+	for _, item := range items {
+		for node, exists := t.nodes[item.Category]; exists; node, exists = t.nodes[node.Parent] {
+			counts[node.ID]++
+		}
+	}
+	return counts
+}
+
+// Facets flattens the tree depth-first with the given counts, skipping
+// nodes with no items when skipEmpty is set
+func (t *Taxonomy) Facets(counts map[string]int, skipEmpty bool) []fragments.CategoryFacet {
+	var facets []fragments.CategoryFacet
+
+	var walk func(nodes []*TaxonomyNode, depth int)
+	walk = func(nodes []*TaxonomyNode, depth int) {
+		for _, node := range nodes {
+			if skipEmpty && counts[node.ID] == 0 {
+				continue
+			}
+			facets = append(facets, fragments.CategoryFacet{
+				ID:    node.ID,
+				Label: node.Label,
+				Depth: depth,
+				Count: counts[node.ID],
+			})
+			walk(node.Children, depth+1)
+		}
+	}
+	walk(t.roots, 0)
+
+	return facets
+}
+
+// getMockTaxonomy returns the category tree for the mock catalog, used until
+// a catalog file comes with its own taxonomy
+func getMockTaxonomy() []TaxonomyNode {
+	return []TaxonomyNode{
+		{ID: "languages", Label: "Linguagens"},
+		{ID: "systems-languages", Label: "Sistemas", Parent: "languages"},
+		{ID: "web-languages", Label: "Web", Parent: "languages"},
+		{ID: "scripting-languages", Label: "Scripting", Parent: "languages"},
+
+		{ID: "frameworks", Label: "Frameworks"},
+		{ID: "frontend", Label: "Frontend", Parent: "frameworks"},
+		{ID: "react", Label: "React", Parent: "frontend"},
+		{ID: "vue", Label: "Vue", Parent: "frontend"},
+		{ID: "css", Label: "CSS", Parent: "frontend"},
+		{ID: "backend", Label: "Backend", Parent: "frameworks"},
+
+		{ID: "tools", Label: "Ferramentas"},
+		{ID: "containers", Label: "Containers", Parent: "tools"},
+		{ID: "orchestration", Label: "Orquestração", Parent: "tools"},
+
+		{ID: "databases", Label: "Databases"},
+		{ID: "relational", Label: "Relacional", Parent: "databases"},
+		{ID: "nosql", Label: "NoSQL", Parent: "databases"},
+		{ID: "document", Label: "Documentos", Parent: "nosql"},
+		{ID: "key-value", Label: "Chave-valor", Parent: "nosql"},
+	}
+}
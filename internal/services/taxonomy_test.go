@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const guidesCatalog = `[
+	{"id": "go-tour", "title": "Tour of Go", "description": "Introdução interativa", "category": "go-guides", "url": "https://go.dev/tour"},
+	{"id": "templ-docs", "title": "templ", "description": "Componentes HTML em Go", "category": "guides", "url": "https://templ.guide"}
+]`
+
+const guidesTaxonomy = `[
+	{"id": "guides", "label": "Guias"},
+	{"id": "go-guides", "label": "Go", "parent": "guides"}
+]`
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadCatalogFileWithTaxonomy(t *testing.T) {
+	dir := t.TempDir()
+	catalogPath := filepath.Join(dir, "catalog.json")
+	writeTestFile(t, catalogPath, guidesCatalog)
+	writeTestFile(t, filepath.Join(dir, "catalog.taxonomy.json"), guidesTaxonomy)
+
+	s := NewSearchService()
+	if err := s.LoadCatalogFile(catalogPath); err != nil {
+		t.Fatalf("LoadCatalogFile: %v", err)
+	}
+
+	facets := s.Categories()
+	counts := map[string]int{}
+	for _, facet := range facets {
+		counts[facet.ID] = facet.Count
+	}
+	if len(facets) != 2 || counts["guides"] != 2 || counts["go-guides"] != 1 {
+		t.Errorf("facets = %+v, want guides 2 rolled up from go-guides 1", facets)
+	}
+
+	// Imports are validated against the loaded tree, not the built-in one
+	report, err := s.ImportCatalog(strings.NewReader(`[{"id": "react", "title": "React", "description": "UI", "category": "react", "url": "https://react.dev"}]`),
+		ImportOptions{Format: ImportFormatJSON, Merge: true})
+	if !errors.Is(err, ErrImportRejected) {
+		t.Fatalf("import into a category missing from the taxonomy = %v, want ErrImportRejected", err)
+	}
+	if errs := report.Rows[0].Errors; len(errs) != 1 || errs[0] != `unknown category "react"` {
+		t.Errorf("row errors = %q", errs)
+	}
+}
+
+func TestLoadCatalogFileUnknownCategories(t *testing.T) {
+	dir := t.TempDir()
+	catalogPath := filepath.Join(dir, "catalog.json")
+	writeTestFile(t, catalogPath, guidesCatalog)
+
+	// Without its taxonomy the catalog's categories aren't in the built-in tree
+	if err := NewSearchService().LoadCatalogFile(catalogPath); err == nil || !strings.Contains(err.Error(), "unknown category") {
+		t.Errorf("LoadCatalogFile without the taxonomy = %v, want an unknown category error", err)
+	}
+
+	// A new taxonomy must still fit the built-in catalog kept when the
+	// catalog file doesn't exist yet
+	missing := filepath.Join(dir, "new.json")
+	writeTestFile(t, TaxonomyFileFor(missing), guidesTaxonomy)
+	if err := NewSearchService().LoadCatalogFile(missing); err == nil || !strings.Contains(err.Error(), "not in the taxonomy") {
+		t.Errorf("LoadCatalogFile with a taxonomy the current catalog doesn't fit = %v", err)
+	}
+}
+
+func TestLoadTaxonomyFileRejectsInvalidTrees(t *testing.T) {
+	tests := map[string]string{
+		"empty":               `[]`,
+		"duplicate id":        `[{"id": "guides", "label": "Guias"}, {"id": "guides", "label": "Outra"}]`,
+		"parent after child":  `[{"id": "go-guides", "label": "Go", "parent": "guides"}, {"id": "guides", "label": "Guias"}]`,
+		"unknown parent":      `[{"id": "go-guides", "label": "Go", "parent": "guides"}]`,
+		"missing label":       `[{"id": "guides"}]`,
+		"invalid id":          `[{"id": "Guias de Go", "label": "Guias"}]`,
+		"not a list of nodes": `{"id": "guides"}`,
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "taxonomy.json")
+			writeTestFile(t, path, content)
+			if _, err := LoadTaxonomyFile(path); err == nil {
+				t.Error("LoadTaxonomyFile accepted an invalid taxonomy")
+			}
+		})
+	}
+}
+
+func TestImportWritesTaxonomyNextToNewCatalog(t *testing.T) {
+	catalogPath := filepath.Join(t.TempDir(), "catalog.json")
+	s := NewSearchService()
+	if err := s.LoadCatalogFile(catalogPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ImportCatalog(strings.NewReader(`[{"id": "react", "title": "React", "description": "UI", "category": "react", "url": "https://react.dev"}]`),
+		ImportOptions{Format: ImportFormatJSON}); err != nil {
+		t.Fatal(err)
+	}
+
+	taxonomy, err := LoadTaxonomyFile(TaxonomyFileFor(catalogPath))
+	if err != nil {
+		t.Fatalf("taxonomy not written with the catalog: %v", err)
+	}
+	if len(taxonomy.Nodes()) != len(getMockTaxonomy()) || !taxonomy.Contains("frameworks", "react") {
+		t.Errorf("written taxonomy has %d nodes, want the built-in tree", len(taxonomy.Nodes()))
+	}
+
+	// And the pair loads back together
+	if err := NewSearchService().LoadCatalogFile(catalogPath); err != nil {
+		t.Errorf("reloading the written catalog: %v", err)
+	}
+}
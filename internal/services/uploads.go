@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ErrUploadLimit means too many uploads are being tracked at once
+var ErrUploadLimit = errors.New("too many uploads in progress")
+
+// uploadIDRegex is the shape of the IDs pages make up for their uploads
+var uploadIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{8,64}$`)
+
+const (
+	// maxTrackedUploads bounds the uploads tracked, watched or not
+	maxTrackedUploads = 1000
+
+	// uploadLinger is how long a finished upload, or one watched but never
+	// started, is kept
+	uploadLinger = time.Minute
+)
+
+// UploadProgress is how far an upload has come; Total is 0 until the
+// request starts
+type UploadProgress struct {
+	Received int64 `json:"received"`
+	Total    int64 `json:"total"`
+	Percent  int   `json:"percent"`
+	Done     bool  `json:"done"`
+}
+
+// UploadTracker follows request bodies as they're read, so the page can
+// watch its upload over a second connection
+type UploadTracker struct {
+	mu      sync.Mutex
+	uploads map[string]*trackedUpload
+}
+
+type trackedUpload struct {
+	progress UploadProgress
+	updated  time.Time
+
+	// reading is set while the request body is being read
+	reading bool
+
+	// changed is closed and replaced on every update
+	changed chan struct{}
+}
+
+func NewUploadTracker() *UploadTracker {
+	return &UploadTracker{uploads: map[string]*trackedUpload{}}
+}
+
+// ValidUploadID tells whether id can name an upload
+func ValidUploadID(id string) bool {
+	return uploadIDRegex.MatchString(id)
+}
+
+// Track counts what is read from body, of total bytes, as the progress of
+// upload id. Call Finish once the body is consumed.
+func (t *UploadTracker) Track(id string, total int64, body io.Reader) (io.Reader, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	upload, err := t.upload(id)
+	if err != nil {
+		return nil, err
+	}
+	upload.progress = UploadProgress{Total: max(total, 0)}
+	upload.reading = true
+	t.notify(upload)
+	return &countingReader{reader: body, tracker: t, upload: upload}, nil
+}
+
+// Finish marks upload id as done
+func (t *UploadTracker) Finish(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if upload, ok := t.uploads[id]; ok {
+		upload.reading = false
+		upload.progress.Done = true
+		upload.progress.Percent = 100
+		t.notify(upload)
+	}
+}
+
+// Watch calls send with the progress of upload id, now and on every
+// change, until the upload is done or ctx ends. The upload may start after
+// the watch.
+func (t *UploadTracker) Watch(ctx context.Context, id string, send func(UploadProgress)) error {
+	for {
+		t.mu.Lock()
+		upload, err := t.upload(id)
+		if err != nil {
+			t.mu.Unlock()
+			return err
+		}
+		progress, changed := upload.progress, upload.changed
+		t.mu.Unlock()
+
+		send(progress)
+		if progress.Done {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// upload finds or adds upload id; callers must hold t.mu
+func (t *UploadTracker) upload(id string) (*trackedUpload, error) {
+	if upload, ok := t.uploads[id]; ok {
+		return upload, nil
+	}
+
+	t.prune(time.Now())
+	if len(t.uploads) >= maxTrackedUploads {
+		return nil, ErrUploadLimit
+	}
+	upload := &trackedUpload{updated: time.Now(), changed: make(chan struct{})}
+	t.uploads[id] = upload
+	return upload, nil
+}
+
+// notify wakes the watchers of upload; callers must hold t.mu
+func (t *UploadTracker) notify(upload *trackedUpload) {
+	upload.updated = time.Now()
+	close(upload.changed)
+	upload.changed = make(chan struct{})
+}
+
+// prune forgets uploads finished, or watched but not started, for
+// uploadLinger; callers must hold t.mu
+func (t *UploadTracker) prune(now time.Time) {
+	for id, upload := range t.uploads {
+		if !upload.reading && now.Sub(upload.updated) > uploadLinger {
+			delete(t.uploads, id)
+		}
+	}
+}
+
+// countingReader reports progress whenever the percentage moves
+type countingReader struct {
+	reader  io.Reader
+	tracker *UploadTracker
+	upload  *trackedUpload
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n == 0 {
+		return n, err
+	}
+
+	r.tracker.mu.Lock()
+	progress := &r.upload.progress
+	progress.Received += int64(n)
+	if progress.Total > 0 {
+		percent := int(min(progress.Received*100/progress.Total, 99))
+		if percent != progress.Percent {
+			progress.Percent = percent
+			r.tracker.notify(r.upload)
+		}
+	}
+	r.tracker.mu.Unlock()
+	return n, err
+}
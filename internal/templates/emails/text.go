@@ -0,0 +1,26 @@
+package emails
+
+import (
+	"context"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/a-h/templ"
+)
+
+// textEmail lays out a plain-text email as layout does the HTML one: the
+// title underlined, paragraphs separated by blank lines and the signature.
+// Plain text isn't HTML, so nothing in it is escaped.
+func textEmail(title string, paragraphs ...string) templ.Component {
+	return templ.ComponentFunc(func(_ context.Context, w io.Writer) error {
+		var body strings.Builder
+		body.WriteString(title + "\n" + strings.Repeat("=", utf8.RuneCountInString(title)) + "\n\n")
+		for _, paragraph := range paragraphs {
+			body.WriteString(paragraph + "\n\n")
+		}
+		body.WriteString("-- \nCEAR Showcase Go\n")
+		_, err := io.WriteString(w, body.String())
+		return err
+	})
+}
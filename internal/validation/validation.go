@@ -0,0 +1,326 @@
+// Package validation validates form structs against declarative rules read
+// from struct tags:
+//
+//	Name string `json:"name" validate:"required,min=2,max=100" label:"Nome" msg:"max=Nome muito longo"`
+//
+// An empty value fails only the required rule; otherwise rules run in order
+// and the first failure is the field's message. Built-in rules are required,
+// min and max (in runes), pattern (regular expression, must come last),
+// oneof (space-separated values) and eqfield/nefield (compare with another
+// field, by JSON name). Any other rule name refers to a custom Func.
+//
+// Messages default to Portuguese templates built from the label; the msg tag
+// overrides them per rule as "rule=message|rule=message", where {param} is
+// replaced by the rule parameter. The "valid" key sets the success message.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Func is a custom validator; param is the text after "=" in the rule
+type Func func(value, param string) bool
+
+// Errors maps a field's JSON name to its message
+type Errors map[string]string
+
+// rule is a single parsed rule of a field
+type rule struct {
+	name    string
+	param   string
+	message string
+
+	pattern *regexp.Regexp
+	custom  Func
+}
+
+// field holds the rules of one struct field
+type field struct {
+	name    string
+	label   string
+	index   int
+	rules   []rule
+	valid   string
+	maxRune int
+
+	// required is the message for an empty value, "" if the field is optional
+	required string
+}
+
+// Schema is the rule set of a form type T, which must be a struct whose
+// validated fields are strings
+type Schema[T any] struct {
+	fields []field
+	byName map[string]int
+}
+
+// defaultMessages are used when the msg tag doesn't override a rule
+var defaultMessages = map[string]string{
+	"required": "{label} é obrigatório",
+	"min":      "{label} deve ter pelo menos {param} caracteres",
+	"max":      "{label} deve ter no máximo {param} caracteres",
+	"pattern":  "{label} inválido",
+	"oneof":    "{label} inválido",
+	"eqfield":  "{label} não confere",
+	"nefield":  "{label} deve ser diferente",
+}
+
+// Parse reads the rules of T from its struct tags; funcs holds the custom
+// validators the tags may refer to
+func Parse[T any](funcs map[string]Func) (*Schema[T], error) {
+	var zero T
+	typ := reflect.TypeOf(zero)
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("validation: %T is not a struct", zero)
+	}
+
+	schema := &Schema[T]{byName: make(map[string]int)}
+
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		tag, ok := sf.Tag.Lookup("validate")
+		if !ok {
+			continue
+		}
+		if sf.Type.Kind() != reflect.String {
+			return nil, fmt.Errorf("validation: %s.%s must be a string", typ.Name(), sf.Name)
+		}
+
+		f, err := parseField(sf, i, tag, funcs)
+		if err != nil {
+			return nil, fmt.Errorf("validation: %s.%s: %w", typ.Name(), sf.Name, err)
+		}
+		schema.byName[f.name] = len(schema.fields)
+		schema.fields = append(schema.fields, f)
+	}
+
+	// Cross-field rules must point at a known field
+	for _, f := range schema.fields {
+		for _, r := range f.rules {
+			if r.name != "eqfield" && r.name != "nefield" {
+				continue
+			}
+			if _, ok := schema.byName[r.param]; !ok {
+				return nil, fmt.Errorf("validation: %s: unknown field %q in %s", f.name, r.param, r.name)
+			}
+		}
+	}
+
+	return schema, nil
+}
+
+// MustParse is like Parse but panics on invalid tags
+func MustParse[T any](funcs map[string]Func) *Schema[T] {
+	schema, err := Parse[T](funcs)
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// Validate checks every field of form and returns the failing ones
+func (s *Schema[T]) Validate(form T) Errors {
+	value := reflect.ValueOf(form)
+	values := make(map[string]string, len(s.fields))
+	for _, f := range s.fields {
+		values[f.name] = value.Field(f.index).String()
+	}
+
+	errs := Errors{}
+	for _, f := range s.fields {
+		if message := f.check(values[f.name], values); message != "" {
+			errs[f.name] = message
+		}
+	}
+	return errs
+}
+
+// First returns the message of the first failing field in declaration order
+func (s *Schema[T]) First(errs Errors) string {
+	for _, f := range s.fields {
+		if message, ok := errs[f.name]; ok {
+			return message
+		}
+	}
+	return ""
+}
+
+// ValidateField checks a single value; cross-field rules are skipped since
+// the other fields are unknown. ok is false when the field doesn't exist.
+func (s *Schema[T]) ValidateField(name, value string) (message string, ok bool) {
+	i, ok := s.byName[name]
+	if !ok {
+		return "", false
+	}
+	return s.fields[i].check(value, nil), true
+}
+
+// ValidMessage is the success message of a field
+func (s *Schema[T]) ValidMessage(name string) string {
+	if i, ok := s.byName[name]; ok {
+		return s.fields[i].valid
+	}
+	return ""
+}
+
+// MaxLength returns the max rule of a field in runes, or 0 if it has none
+func (s *Schema[T]) MaxLength(name string) int {
+	if i, ok := s.byName[name]; ok {
+		return s.fields[i].maxRune
+	}
+	return 0
+}
+
+// check returns the message of the first failing rule, or "" if value
+// passes; values is nil when other fields are unknown
+func (f field) check(value string, values map[string]string) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		// Optional and empty: nothing else applies
+		return f.required
+	}
+
+	for _, r := range f.rules {
+		var ok bool
+		switch r.name {
+		case "min":
+			ok = utf8.RuneCountInString(value) >= atoi(r.param)
+		case "max":
+			ok = utf8.RuneCountInString(value) <= atoi(r.param)
+		case "pattern":
+			ok = r.pattern.MatchString(value)
+		case "oneof":
+			ok = false
+			for _, option := range strings.Fields(r.param) {
+				if value == option {
+					ok = true
+					break
+				}
+			}
+		case "eqfield", "nefield":
+			if values == nil {
+				continue
+			}
+			ok = (value == strings.TrimSpace(values[r.param])) == (r.name == "eqfield")
+		default:
+			ok = r.custom(value, r.param)
+		}
+
+		if !ok {
+			return r.message
+		}
+	}
+	return ""
+}
+
+func parseField(sf reflect.StructField, index int, tag string, funcs map[string]Func) (field, error) {
+	f := field{
+		name:  strings.Split(sf.Tag.Get("json"), ",")[0],
+		label: sf.Tag.Get("label"),
+		index: index,
+	}
+	if f.name == "" || f.name == "-" {
+		f.name = sf.Name
+	}
+	if f.label == "" {
+		f.label = sf.Name
+	}
+
+	overrides := make(map[string]string)
+	if msg := sf.Tag.Get("msg"); msg != "" {
+		for _, pair := range strings.Split(msg, "|") {
+			key, message, found := strings.Cut(pair, "=")
+			if !found {
+				return f, fmt.Errorf("malformed msg %q", pair)
+			}
+			overrides[strings.TrimSpace(key)] = message
+		}
+	}
+
+	f.valid = overrides["valid"]
+	if f.valid == "" {
+		f.valid = f.label + " válido"
+	}
+
+	for _, spec := range splitRules(tag) {
+		name, param, _ := strings.Cut(spec, "=")
+		r := rule{name: name, param: param}
+
+		switch name {
+		case "required":
+		case "min", "max":
+			if atoi(param) <= 0 {
+				return f, fmt.Errorf("%s needs a positive number, got %q", name, param)
+			}
+			if name == "max" {
+				f.maxRune = atoi(param)
+			}
+		case "pattern":
+			pattern, err := regexp.Compile(param)
+			if err != nil {
+				return f, err
+			}
+			r.pattern = pattern
+		case "oneof", "eqfield", "nefield":
+			if param == "" {
+				return f, fmt.Errorf("%s needs a parameter", name)
+			}
+		default:
+			r.custom = funcs[name]
+			if r.custom == nil {
+				return f, fmt.Errorf("unknown rule %q", name)
+			}
+		}
+
+		message, ok := overrides[name]
+		if !ok {
+			message, ok = defaultMessages[name]
+		}
+		if !ok {
+			message = defaultMessages["pattern"]
+		}
+		r.message = strings.NewReplacer("{label}", f.label, "{param}", param).Replace(message)
+
+		if name == "required" {
+			f.required = r.message
+			continue
+		}
+		f.rules = append(f.rules, r)
+	}
+
+	return f, nil
+}
+
+// splitRules splits the validate tag on commas, except inside a pattern
+// rule, which must then come last
+func splitRules(tag string) []string {
+	var specs []string
+	for tag != "" {
+		if strings.HasPrefix(tag, "pattern=") {
+			specs = append(specs, tag)
+			break
+		}
+		spec, rest, _ := strings.Cut(tag, ",")
+		if spec = strings.TrimSpace(spec); spec != "" {
+			specs = append(specs, spec)
+		}
+		tag = strings.TrimSpace(rest)
+	}
+	return specs
+}
+
+// atoi parses a rule parameter, returning 0 when it isn't a number
+func atoi(s string) int {
+	n := 0
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return 0
+		}
+		n = n*10 + int(r-'0')
+	}
+	return n
+}
@@ -0,0 +1,91 @@
+// Sends forms with files attached. $$post only posts the store as JSON, so
+// when a [data-attachments] form has files selected its submit is taken
+// over here: the form goes as multipart to data-attachments, while the
+// server reports how much of it has arrived over the SSE stream at
+// data-attachments-progress. The Datastar-Merge-Store of the answer is
+// applied through the inputs bound (data-model) to each signal, the same
+// way pow.js writes to the store.
+(() => {
+  function uploadID() {
+    const bytes = crypto.getRandomValues(new Uint8Array(12));
+    return Array.from(bytes, (b) => b.toString(16).padStart(2, "0")).join("");
+  }
+
+  function setModel(form, path, value) {
+    const selector = `[data-model="${path}"]`;
+    const input = form.querySelector(selector) || document.querySelector(selector);
+    if (!input || input.type === "file") return;
+    // Boolean signals read any non-empty value as true
+    input.value = value === true ? "true" : value === false || value == null ? "" : String(value);
+    input.dispatchEvent(new Event("input", { bubbles: true }));
+  }
+
+  // applyStore writes a store update; signals without an input are skipped
+  function applyStore(form, update, prefix = "") {
+    for (const [key, value] of Object.entries(update)) {
+      if (value && typeof value === "object" && !Array.isArray(value)) {
+        applyStore(form, value, `${prefix}${key}.`);
+      } else {
+        setModel(form, `${prefix}${key}`, value);
+      }
+    }
+  }
+
+  function failure(status) {
+    if (status === 413) return "Os anexos passam do limite de tamanho";
+    if (status === 403) return "Sessão expirada. Recarregue a página e tente novamente.";
+    return "Não foi possível enviar a mensagem, tente novamente em instantes";
+  }
+
+  async function upload(form, files) {
+    const id = uploadID();
+    const data = new FormData(form);
+    applyStore(form, { contactLoading: true, contactUpload: { percent: 0, message: "" } });
+
+    const progress = new EventSource(`${form.dataset.attachmentsProgress}?upload=${id}`);
+    progress.addEventListener("upload-progress", (event) => {
+      const update = JSON.parse(event.data);
+      setModel(form, "contactUpload.percent", update.contactUpload.percent);
+      if (update.contactUpload.done) progress.close();
+    });
+    progress.onerror = () => progress.close();
+
+    try {
+      const response = await fetch(`${form.dataset.attachments}?upload=${id}`, {
+        method: "POST",
+        body: data,
+        headers: { "X-CSRF-Token": data.get("csrf") || "" },
+      });
+      const store = JSON.parse(response.headers.get("Datastar-Merge-Store") || "{}");
+      const result = await response.json().catch(() => ({}));
+      if ("contactUpload" in store) {
+        applyStore(form, store);
+      } else {
+        applyStore(form, { contactLoading: false, contactUpload: { percent: 0, message: failure(response.status) } });
+      }
+      if (result.valid) files.value = "";
+    } catch (error) {
+      console.error("attachments: upload failed", error);
+      applyStore(form, { contactLoading: false, contactUpload: { percent: 0, message: failure(0) } });
+    } finally {
+      progress.close();
+    }
+  }
+
+  // Capturing on the document runs before Datastar's submit handler on the
+  // form, which never sees the event
+  document.addEventListener(
+    "submit",
+    (event) => {
+      const form = event.target;
+      if (!(form instanceof HTMLFormElement) || !form.dataset.attachments) return;
+      const files = form.querySelector("[data-attachments-files]");
+      if (!files || files.files.length === 0) return;
+
+      event.preventDefault();
+      event.stopPropagation();
+      upload(form, files);
+    },
+    true,
+  );
+})();
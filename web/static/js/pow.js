@@ -0,0 +1,63 @@
+// Solves the proof-of-work challenges of the public forms in the background.
+// Each [data-pow] element holds hidden inputs bound to the Datastar store:
+// token, difficulty, expires and nonce. When a new token shows up (on load or
+// merged in after a submission) a worker finds the nonce, which is written
+// back through the input so the store, and the next $$post, carry it.
+// Challenges about to expire are replaced from data-pow (the challenge URL).
+(() => {
+  const REFRESH_MARGIN = 60 * 1000;
+
+  function field(el, name) {
+    return el.querySelector(`[data-pow-${name}]`);
+  }
+
+  function setField(input, value) {
+    input.value = value;
+    input.dispatchEvent(new Event("input", { bubbles: true }));
+  }
+
+  function watch(el) {
+    let solving = "";
+    let worker = null;
+
+    async function refresh() {
+      try {
+        const response = await fetch(el.dataset.pow, { headers: { Accept: "application/json" } });
+        if (!response.ok) return;
+        const challenge = await response.json();
+        setField(field(el, "difficulty"), challenge.difficulty);
+        setField(field(el, "expires"), challenge.expires);
+        setField(field(el, "nonce"), "");
+        setField(field(el, "token"), challenge.token);
+      } catch (error) {
+        console.error("pow: could not refresh the challenge", error);
+      }
+    }
+
+    setInterval(() => {
+      const token = field(el, "token").value;
+      const expires = Number(field(el, "expires").value);
+      if (expires && Date.now() > expires - REFRESH_MARGIN) {
+        refresh();
+        return;
+      }
+      if (!token || token === solving) return;
+
+      solving = token;
+      if (worker) worker.terminate();
+      worker = new Worker("/static/js/pow-worker.js");
+      worker.onmessage = (event) => {
+        if (event.data.token === field(el, "token").value) {
+          setField(field(el, "nonce"), event.data.nonce);
+        }
+        worker.terminate();
+        worker = null;
+      };
+      worker.postMessage({ token, difficulty: Number(field(el, "difficulty").value) });
+    }, 250);
+  }
+
+  document.addEventListener("DOMContentLoaded", () => {
+    document.querySelectorAll("[data-pow]").forEach(watch);
+  });
+})();